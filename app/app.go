@@ -4,7 +4,9 @@ package app
 import (
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/ktr0731/evans/assertion"
 	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/cui"
 	"github.com/ktr0731/evans/meta"
@@ -13,6 +15,10 @@ import (
 	"github.com/spf13/pflag"
 )
 
+// exitCodeAssertionFailed is the exit code when the result of "cli call" doesn't satisfy assertions.
+// It is distinguished from other errors.
+const exitCodeAssertionFailed = 2
+
 // App is the root component for running the application.
 type App struct {
 	cui cui.UI
@@ -36,13 +42,16 @@ func (a *App) Run(args []string) int {
 	// So, there are both of old-style and new-style command-line interfaces in this version.
 
 	a.cmd.SetArgs(args)
+	// Hack.
+	switch firstArg(a.cmd, args) {
+	case "cli", "repl", "serve", "proxy": // Sub commands for new-style interface.
+		// If the first non-flag arg is "cli", "repl", "serve" or "proxy", it is regarded as a sub-command of new-style.
+		// Flag values such as "proxy" of "--host proxy" are not regarded as sub-commands.
+		a.cmd.registerNewCommands()
+		a.cmd.RunE = nil
+	}
 	for _, r := range args {
-		// Hack.
 		switch r {
-		case "cli", "repl": // Sub commands for new-style interface.
-			// If an arg named "cli" or "repl" is passed, it is regarded as a sub-command of new-style.
-			a.cmd.registerNewCommands()
-			a.cmd.RunE = nil
 		case "-h", "--help":
 			// If the help flags is passed, call registerNewCommands for display sub-command helps.
 			a.cmd.registerNewCommands()
@@ -53,6 +62,12 @@ func (a *App) Run(args []string) int {
 		return 0
 	}
 
+	var ae *assertion.Error
+	if errors.As(err, &ae) {
+		a.cui.Error(fmt.Sprintf("evans: %s", ae))
+		return exitCodeAssertionFailed
+	}
+
 	var e interface {
 		Code() usecase.ErrorCode
 		Message() string
@@ -68,6 +83,47 @@ func (a *App) Run(args []string) int {
 	return 1
 }
 
+// firstArg returns the first non-flag argument in args. Values of the flags of cmd are skipped.
+// If args has no such arguments, it returns an empty string.
+func firstArg(cmd *command, args []string) string {
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.AddFlagSet(cmd.PersistentFlags())
+	fs.AddFlagSet(cmd.Flags())
+	// requireValue reports whether the flag requires a value as the next argument.
+	requireValue := func(f *pflag.Flag) bool {
+		return f != nil && f.NoOptDefVal == ""
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+			return ""
+		case strings.HasPrefix(arg, "--"):
+			if !strings.Contains(arg, "=") && requireValue(fs.Lookup(arg[2:])) {
+				i++
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// Shorthands can be combined like "-rp 50051". The rest of the first shorthand which requires a value
+			// is its value, so the next argument is its value only if it is the last one.
+			for j := 1; j < len(arg); j++ {
+				if requireValue(fs.ShorthandLookup(arg[j : j+1])) {
+					if j == len(arg)-1 {
+						i++
+					}
+					break
+				}
+			}
+		default:
+			return arg
+		}
+	}
+	return ""
+}
+
 // printUsage shows the command usage text to cui.Writer and exit. Do not call it before calling parseFlags.
 func printUsage(cmd interface{ Help() error }) {
 	_ = cmd.Help() // Help never return errors.
@@ -99,6 +155,7 @@ func mergeConfig(fs *pflag.FlagSet, flags *flags, protos []string) (*mergedConfi
 		return nil, errors.Wrap(err, "failed to get config")
 	}
 	cfg.Default.ProtoFile = append(cfg.Default.ProtoFile, protos...)
+	cfg.Server.RefreshReflection = flags.common.refreshReflection
 
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/evans/cui"
+)
+
+func Test_firstArg(t *testing.T) {
+	cmd := New(cui.New()).cmd
+	cases := map[string]struct {
+		args     string
+		expected string
+	}{
+		"sub-command":                        {args: "cli call api.Example.Unary", expected: "cli"},
+		"sub-command after flags":            {args: "-r --host localhost proxy", expected: "proxy"},
+		"value of a flag":                    {args: "--host proxy -r", expected: ""},
+		"value of a shorthand flag":          {args: "-p serve repl", expected: "repl"},
+		"value of combined shorthand flags":  {args: "-rp serve", expected: ""},
+		"value in the same argument":         {args: "--host=proxy -rp50051 serve", expected: "serve"},
+		"boolean flag followed by an arg":    {args: "--reflection serve", expected: "serve"},
+		"argument after the flag terminator": {args: "-r -- proxy", expected: "proxy"},
+		"no arguments":                       {args: "", expected: ""},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if actual := firstArg(cmd, strings.Fields(c.args)); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
@@ -1,19 +1,34 @@
 package app
 
 import (
+	"os"
+	"sort"
 	"strings"
 
+	"github.com/ktr0731/evans/assertion"
+	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/interpolate"
+	"github.com/ktr0731/evans/logger"
 	"github.com/ktr0731/evans/mode"
+	"github.com/ktr0731/evans/present/json"
+	"github.com/ktr0731/evans/usecase"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
 )
 
 func newCLICallCommand(flags *flags, ui cui.UI) *cobra.Command {
 	var (
 		out          string
+		in           string
 		enrich       bool
 		emitDefaults bool
+		expectCode   string
+		expectJSON   string
+		golden       string
+		update       bool
+		ignoreFields []string
 	)
 	cmd := &cobra.Command{
 		Use:     "call [options ...] <method>",
@@ -23,8 +38,14 @@ func newCLICallCommand(flags *flags, ui cui.UI) *cobra.Command {
 		Example: strings.Join([]string{
 			"        $ echo '{}' | evans -r cli call api.Service.Unary # call Unary method with an empty message",
 			"        $ evans -r cli call -f in.json api.Service.Unary  # call Unary method with an input file",
+			"        $ evans -r cli call -f in.yaml api.Service.Unary  # call Unary method with a YAML input file",
 			"",
 			"        $ evans -r cli call -f in.json --enrich --output json api.Service.Unary # enrich output with JSON format",
+			"        $ evans -r cli call -f in.json --enrich -o ndjson api.Service.ServerStreaming # stream each event as a JSON line",
+			"",
+			`        $ evans -r cli call -f in.json --expect-code NotFound api.Service.Unary              # fail if the status is not NotFound`,
+			`        $ evans -r cli call -f in.json --expect-json '{"name": "foo"}' api.Service.Unary      # fail if the response doesn't have the name`,
+			"        $ evans -r cli call -f in.json --golden out.json --update api.Service.Unary          # update the golden file",
 		}, "\n"),
 		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
 			if cfg.REPL.ColoredOutput {
@@ -35,17 +56,24 @@ func newCLICallCommand(flags *flags, ui cui.UI) *cobra.Command {
 			if len(args) == 0 {
 				return errors.New("method is required")
 			}
+			a, err := newAssertion(expectCode, expectJSON, golden, update, ignoreFields)
+			if err != nil {
+				return err
+			}
 			invoker, err := mode.NewCallCLIInvoker(ui, args[0], &mode.CallCLIInvokerOption{
 				Headers:      cfg.Config.Request.Header,
 				Enrich:       enrich,
 				EmitDefaults: emitDefaults,
 				FilePath:     cfg.file,
 				FormatType:   out,
+				InputFormat:  in,
+				Timeout:      cfg.Config.Request.Timeout,
+				Assertion:    a,
 			})
 			if err != nil {
 				return err
 			}
-			if err := mode.RunAsCLIMode(cfg.Config, invoker); err != nil {
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
 				return errors.Wrap(err, "failed to run CLI mode")
 			}
 			return nil
@@ -58,12 +86,178 @@ func newCLICallCommand(flags *flags, ui cui.UI) *cobra.Command {
 	initFlagSet(f, ui.Writer())
 	f.BoolVar(&enrich, "enrich", false, `enrich response output includes header, message, trailer and status`)
 	f.BoolVar(&emitDefaults, "emit-defaults", false, `render fields with default values`)
-	f.StringVarP(&out, "output", "o", "curl", `output format. one of "json" or "curl". "curl" is a curl-like format.`)
+	f.Duration("timeout", 0, `the deadline of the RPC (e.g. 500ms, 3s). zero means no deadline`)
+	f.StringVar(&in, "input-format", "", `input format. one of "json", "textproto" or "yaml". if empty, it is detected from the extension of --file, or "json" is used`)
+	f.StringVarP(&out, "output", "o", "curl", `output format. one of "json", "ndjson" (alias "jsonl"), "textproto", "yaml", "binary" or "curl". "curl" is a curl-like format. "binary" writes the message in the wire format that "protoc --decode" can read, and prefixes each message by its length as a varint for server streaming RPCs.`)
+	f.StringVar(&expectCode, "expect-code", "", `fail if the status code is not the specified one (e.g. NotFound or 5). OK is expected if omitted and other assertions are specified`)
+	f.StringVar(&expectJSON, "expect-json", "", `fail if the response doesn't contain the fields of the JSON document. "@<file>" reads the document from the file. an array is compared with all responses of streaming RPCs`)
+	f.StringVar(&golden, "golden", "", `fail if the status and responses differ from the golden file`)
+	f.BoolVar(&update, "update", false, `update the golden file with the result instead of comparing them`)
+	f.StringSliceVar(&ignoreFields, "ignore-fields", nil, `dot-separated paths of response fields that are ignored by --expect-json and --golden (e.g. createdAt,user.updatedAt)`)
+
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), []string{"file"}))
+	return cmd
+}
+
+// newAssertion returns an assertion for call flags. If no assertion flags are specified, it returns nil.
+func newAssertion(expectCode, expectJSON, golden string, update bool, ignoreFields []string) (*assertion.Assertion, error) {
+	if update && golden == "" {
+		return nil, errors.New("--update requires --golden")
+	}
+	if expectCode == "" && expectJSON == "" && golden == "" {
+		return nil, nil
+	}
+
+	a := &assertion.Assertion{
+		GoldenFile:   golden,
+		Update:       update,
+		IgnoreFields: ignoreFields,
+	}
+	switch {
+	case expectCode != "":
+		code, err := assertion.ParseCode(expectCode)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid --expect-code")
+		}
+		a.Code = &code
+	case expectJSON != "":
+		// If --expect-code is omitted, a non-OK status must fail even if the responses match.
+		// The golden file has its own status, so --golden alone doesn't need it.
+		code := codes.OK
+		a.Code = &code
+	}
+	if expectJSON != "" {
+		b := []byte(expectJSON)
+		if strings.HasPrefix(expectJSON, "@") {
+			var err error
+			b, err = os.ReadFile(strings.TrimPrefix(expectJSON, "@"))
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read the file of --expect-json")
+			}
+		}
+		v, err := assertion.DecodeJSON(b)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid --expect-json")
+		}
+		a.JSON = v
+	}
+	return a, nil
+}
+
+func newCLIBenchCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		in  string
+		out string
+		opt usecase.BenchOption
+	)
+	cmd := &cobra.Command{
+		Use:   "bench [options ...] <method>",
+		Short: "benchmark a method",
+		Long: `bench sends the same request to the method repeatedly, and reports the throughput, latency and status codes.
+The request is read from stdin or --file in the same way as call. Unary and server streaming methods are supported.
+For server streaming methods, the latency is the time to the first message.`,
+		Example: strings.Join([]string{
+			"        $ echo '{}' | evans -r cli bench api.Service.Unary      # send 200 requests with 10 workers",
+			"        $ evans -r cli bench -f in.json -n 1000 -c 50 api.Service.Unary",
+			"        $ evans -r cli bench -f in.json -d 30s --rate 100 api.Service.Unary # send 100 requests/sec for 30 seconds",
+			"        $ evans -r cli bench -f in.json -o json api.Service.ServerStreaming",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("method is required")
+			}
+			opt.Timeout = cfg.Config.Request.Timeout
+			invoker, err := mode.NewBenchCLIInvoker(ui, args[0], &mode.BenchCLIInvokerOption{
+				Headers:      cfg.Config.Request.Header,
+				FilePath:     cfg.file,
+				InputFormat:  in,
+				OutputFormat: out,
+				BenchOption:  opt,
+			})
+			if err != nil {
+				return err
+			}
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
+				return errors.Wrap(err, "failed to run CLI mode")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.IntVarP(&opt.Requests, "requests", "n", 200, "the total number of requests. ignored if --duration is specified")
+	f.DurationVarP(&opt.Duration, "duration", "d", 0, "the duration to keep sending requests (e.g. 10s)")
+	f.IntVarP(&opt.Concurrency, "concurrency", "c", 10, "the number of workers that send requests concurrently")
+	f.Float64Var(&opt.Rate, "rate", 0, "the max number of requests per second. zero means unlimited")
+	f.Duration("timeout", 0, "the deadline of each request (e.g. 500ms, 3s). zero means no deadline")
+	f.StringVar(&in, "input-format", "", `input format. one of "json", "textproto" or "yaml". if empty, it is detected from the extension of --file, or "json" is used`)
+	f.StringVarP(&out, "output", "o", "text", `output format. one of "text" or "json". durations in JSON are in nanoseconds`)
 
 	cmd.SetHelpFunc(usageFunc(ui.Writer(), []string{"file"}))
 	return cmd
 }
 
+func newCLIRunCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		out          string
+		enrich       bool
+		emitDefaults bool
+	)
+	cmd := &cobra.Command{
+		Use:   "run [options ...] <script>",
+		Short: "run a script that calls methods in sequence",
+		Long: `run calls methods described in the script file in order. The script is written in YAML or TOML (if the extension is ".toml").
+Each step has a name, a fully-qualified method name, headers and a request (or requests for streaming methods).
+Strings in headers and requests are rendered as Go templates, so later steps can refer to the results of earlier steps
+like {{ .steps.<name>.response.<field> }}. Other available keys are responses, header, trailer and status.
+run stops as soon as a step fails, and exits with a non-zero code.`,
+		Example: strings.Join([]string{
+			"        $ evans -r cli run script.yaml                    # run steps in script.yaml",
+			"        $ evans -r cli run --enrich -o json script.toml   # run steps in script.toml with enriched JSON output",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			if cfg.REPL.ColoredOutput {
+				ui = cui.NewColored(ui)
+			}
+
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("script is required")
+			}
+			invoker, err := mode.NewRunCLIInvoker(ui, args[0], &mode.RunCLIInvokerOption{
+				Headers:      cfg.Config.Request.Header,
+				Enrich:       enrich,
+				EmitDefaults: emitDefaults,
+				FormatType:   out,
+				Timeout:      cfg.Config.Request.Timeout,
+			})
+			if err != nil {
+				return err
+			}
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
+				return errors.Wrap(err, "failed to run CLI mode")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.BoolVar(&enrich, "enrich", false, `enrich response output includes header, message, trailer and status`)
+	f.BoolVar(&emitDefaults, "emit-defaults", false, `render fields with default values`)
+	f.Duration("timeout", 0, `the deadline of each step (e.g. 500ms, 3s). zero means no deadline`)
+	f.StringVarP(&out, "output", "o", "curl", `output format. one of "json", "ndjson" (alias "jsonl"), "textproto", "yaml" or "curl". "curl" is a curl-like format.`)
+
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
 func newCLIListCommand(flags *flags, ui cui.UI) *cobra.Command {
 	var (
 		out string
@@ -91,7 +285,7 @@ list lists method names belong to the service. If not, list lists all services.`
 				dsn = args[0]
 			}
 			invoker := mode.NewListCLIInvoker(ui, dsn, out)
-			if err := mode.RunAsCLIMode(cfg.Config, invoker); err != nil {
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
 				return errors.Wrap(err, "failed to run CLI mode")
 			}
 			return nil
@@ -131,7 +325,80 @@ The symbol should be a fully-qualified name. If no symbol is passed, desc shows
 				fqn = args[0]
 			}
 			invoker := mode.NewDescribeCLIInvoker(ui, fqn)
-			if err := mode.RunAsCLIMode(cfg.Config, invoker); err != nil {
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
+				return errors.Wrap(err, "failed to run CLI mode")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newCLISkeletonCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		out string
+	)
+	cmd := &cobra.Command{
+		Use:   "skeleton [options ...] <method|message>",
+		Short: "generate an example request",
+		Long: `skeleton writes an example request of the given method or message. The symbol should be a fully-qualified name.
+Every field has a placeholder value. In YAML and textproto, enum values and the other fields of each oneof are listed in comments.
+The output can be passed to "cli call --file" after editing it.`,
+		Example: strings.Join([]string{
+			"        $ evans -r cli skeleton api.Service.Unary > req.json   # write an example request of the method",
+			"        $ evans -r cli skeleton -o yaml api.Request > req.yaml # write an example of the message in YAML",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("method or message is required")
+			}
+			invoker := mode.NewSkeletonCLIInvoker(ui, args[0], out)
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
+				return errors.Wrap(err, "failed to run CLI mode")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVarP(&out, "output", "o", "json", `output format. one of "json", "yaml" or "textproto".`)
+
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newCLIExportCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		out string
+	)
+	cmd := &cobra.Command{
+		Use:   "export [options ...] <output dir>",
+		Short: "export the descriptors of loaded services",
+		Long: `export writes files that define all loaded services and their dependencies to the output directory.
+By default, export reconstructs .proto files from the descriptors, so they can be used with --path and --proto.
+If "-o protoset" is specified, export writes a FileDescriptorSet named "descriptors.protoset" instead, so it can be used with --protoset.`,
+		Example: strings.Join([]string{
+			"        $ evans -r cli export out             # write .proto files to out",
+			"        $ evans -r cli export -o protoset out # write out/descriptors.protoset",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("output directory is required")
+			}
+			invoker := mode.NewExportCLIInvoker(ui, args[0], out)
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
 				return errors.Wrap(err, "failed to run CLI mode")
 			}
 			return nil
@@ -142,6 +409,66 @@ The symbol should be a fully-qualified name. If no symbol is passed, desc shows
 
 	f := cmd.Flags()
 	initFlagSet(f, ui.Writer())
+	f.StringVarP(&out, "output", "o", "proto", `output format. one of "proto" or "protoset".`)
+
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newCLIProfilesCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		out string
+	)
+	cmd := &cobra.Command{
+		Use:   "profiles [options ...]",
+		Short: "list profiles defined in the config files",
+		Long: `profiles lists profiles defined in the global and local config files.
+A profile is defined as [profiles.<name>] and overrides server, request and default. It is selected by --profile.`,
+		Example: strings.Join([]string{
+			"        $ evans cli profiles         # list profile names",
+			"        $ evans cli profiles -o json # list profiles with their config",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.meta.verbose {
+				logger.SetOutput(interpolate.NewMaskWriter(os.Stderr))
+			}
+			profiles, err := config.Profiles()
+			if err != nil {
+				return errors.Wrap(err, "failed to load profiles")
+			}
+			switch out {
+			case "name":
+				names := make([]string, 0, len(profiles))
+				for name := range profiles {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					ui.Output(name)
+				}
+			case "json":
+				// The output may be pasted to others, so secrets in profiles are masked.
+				masked := make(map[string]map[string]interface{}, len(profiles))
+				for name, p := range profiles {
+					masked[name] = config.MaskProfile(p)
+				}
+				s, err := json.NewPresenter("  ").Format(masked)
+				if err != nil {
+					return errors.Wrap(err, "failed to format profiles")
+				}
+				ui.Output(s)
+			default:
+				return errors.Errorf("unknown output format '%s'", out)
+			}
+			return nil
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVarP(&out, "output", "o", "name", `output format. one of "json" or "name".`)
 
 	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
 	return cmd
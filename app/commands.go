@@ -12,6 +12,7 @@ import (
 	"github.com/ktr0731/evans/cache"
 	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/interpolate"
 	"github.com/ktr0731/evans/logger"
 	"github.com/ktr0731/evans/mode"
 	"github.com/ktr0731/evans/prompt"
@@ -33,6 +34,8 @@ func (c *command) registerNewCommands() {
 	c.AddCommand(
 		newCLICommand(c.flags, c.ui),
 		newREPLCommand(c.flags, c.ui),
+		newServeCommand(c.flags, c.ui),
+		newProxyCommand(c.flags, c.ui),
 	)
 }
 
@@ -47,7 +50,7 @@ func runFunc(
 		}
 
 		if flags.meta.verbose {
-			logger.SetOutput(os.Stderr)
+			logger.SetOutput(interpolate.NewMaskWriter(os.Stderr))
 		}
 
 		switch {
@@ -120,7 +123,7 @@ func newOldCommand(flags *flags, ui cui.UI) *command {
 			if err != nil {
 				return err
 			}
-			if err := mode.RunAsCLIMode(cfg.Config, invoker); err != nil {
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
 				return errors.Wrap(err, "failed to run CLI mode")
 			}
 
@@ -152,6 +155,7 @@ func bindFlags(f *pflag.FlagSet, flags *flags, w io.Writer) {
 	f.StringVar(&flags.common.service, "service", "", "default service")
 	f.StringSliceVar(&flags.common.path, "path", nil, "comma-separated proto file paths")
 	f.StringSliceVar(&flags.common.proto, "proto", nil, "comma-separated proto file names")
+	f.StringSliceVar(&flags.common.protoset, "protoset", nil, "comma-separated protoset file names")
 	f.StringVar(&flags.common.host, "host", "", "gRPC server host")
 	f.StringVarP(&flags.common.port, "port", "p", "50051", "gRPC server port")
 	f.Var(
@@ -159,6 +163,7 @@ func bindFlags(f *pflag.FlagSet, flags *flags, w io.Writer) {
 		"header", "default headers that set to each requests (example: foo=bar)")
 	f.BoolVar(&flags.common.web, "web", false, "use gRPC-Web protocol")
 	f.BoolVarP(&flags.common.reflection, "reflection", "r", false, "use gRPC reflection")
+	f.BoolVar(&flags.common.refreshReflection, "refresh-reflection", false, "ignore the reflection descriptor cache")
 	f.BoolVarP(&flags.common.tls, "tls", "t", false, "use a secure TLS connection")
 	f.StringVar(&flags.common.cacert, "cacert", "", "the CA certificate file for verifying the server")
 	f.StringVar(
@@ -170,6 +175,18 @@ func bindFlags(f *pflag.FlagSet, flags *flags, w io.Writer) {
 	f.StringVar(
 		&flags.common.serverName,
 		"servername", "", "override the server name used to verify the hostname (ignored if --tls is disabled)")
+	f.BoolVar(&flags.common.insecure, "insecure", false, "skip the verification of the server certificate. it requires --tls")
+	f.StringVar(&flags.common.tlsMinVersion, "tls-min-version", "", "the minimum TLS version (1.0, 1.1, 1.2 or 1.3). it requires --tls")
+	f.StringSliceVar(&flags.common.tlsCipherSuites, "tls-cipher-suites", nil, "comma-separated cipher suites used in TLS 1.0-1.2. it requires --tls")
+	f.StringVar(&flags.common.profile, "profile", "", "the profile defined in the config files (see 'evans cli profiles')")
+	f.StringVar(&flags.common.authTokenFile, "auth-token-file", "", "the file that has a bearer token. it is re-read when the file is changed")
+	f.StringVar(&flags.common.authTokenURL, "auth-token-url", "", "the token endpoint of the OAuth2 client credentials flow")
+	f.StringVar(&flags.common.authClientID, "auth-client-id", "", "the client ID of the OAuth2 client credentials flow")
+	f.StringVar(&flags.common.authClientSecret, "auth-client-secret", "", "the client secret of the OAuth2 client credentials flow")
+	f.StringSliceVar(&flags.common.authScopes, "auth-scopes", nil, "comma-separated scopes of the OAuth2 client credentials flow")
+	f.StringVar(&flags.common.authCommand, "auth-command", "", "the command that prints a bearer token. it requires --allow-command-interpolation")
+	f.BoolVar(&flags.common.authAllowPlain, "auth-allow-plaintext", false, "allow sending the bearer token to servers without TLS")
+	f.BoolVar(&flags.common.allowCommand, "allow-command-interpolation", false, "enable $(command) in header values and TLS file paths")
 
 	f.BoolVarP(&flags.meta.edit, "edit", "e", false, "edit the project config file by using $EDITOR")
 	f.BoolVar(&flags.meta.editGlobal, "edit-global", false, "edit the global config file by using $EDITOR")
@@ -218,7 +235,7 @@ func newCLICommand(flags *flags, ui cui.UI) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if err := mode.RunAsCLIMode(cfg.Config, invoker); err != nil {
+			if err := mode.RunAsCLIMode(cfg.Config, ui, invoker); err != nil {
 				return errors.Wrap(err, "failed to run CLI mode")
 			}
 			return nil
@@ -231,8 +248,13 @@ func newCLICommand(flags *flags, ui cui.UI) *cobra.Command {
 	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
 	cmd.AddCommand(
 		newCLICallCommand(flags, ui),
+		newCLIBenchCommand(flags, ui),
+		newCLIRunCommand(flags, ui),
 		newCLIListCommand(flags, ui),
 		newCLIDescribeCommand(flags, ui),
+		newCLISkeletonCommand(flags, ui),
+		newCLIExportCommand(flags, ui),
+		newCLIProfilesCommand(flags, ui),
 	)
 	return cmd
 }
@@ -255,6 +277,75 @@ func newREPLCommand(flags *flags, ui cui.UI) *cobra.Command {
 	return cmd
 }
 
+func newServeCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var opt mode.ServeOption
+	cmd := &cobra.Command{
+		Use:   "serve [options ...]",
+		Short: "mock server mode",
+		Long: `serve starts a mock gRPC server that serves all services of proto or protoset files on --host and --port.
+Each method answers with a canned response of the first matched stub in --stub, or a generated placeholder message.
+A stub file is written in YAML (or JSON), and each stub has these keys:
+
+  method:    the fully-qualified method name
+  match:     a part of the request message. if omitted, the stub matches any requests
+  header:    response headers
+  response:  the response message (or "responses" for streaming methods)
+  status:    the status which has "code" and "message"
+  trailer:   response trailers
+
+If --reflection is specified, the server also serves gRPC reflection.`,
+		Example: strings.Join([]string{
+			"        $ evans --proto api.proto serve                           # answer with placeholder messages",
+			"        $ evans --proto api.proto -r serve --stub stubs.yaml      # answer with stubs and serve gRPC reflection",
+			"        $ evans --protoset api.protoset --port 8080 serve",
+		}, "\n"),
+		RunE: runFunc(flags, func(_ *cobra.Command, cfg *mergedConfig) error {
+			return mode.RunAsServeMode(cfg.Config, ui, &opt)
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVar(&opt.StubFile, "stub", "", "the stub file. if empty, all methods answer with placeholder messages")
+	f.BoolVar(&opt.AccessLog, "access-log", false, "print a line for each RPC")
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newProxyCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var opt mode.ProxyOption
+	cmd := &cobra.Command{
+		Use:   "proxy [options ...]",
+		Short: "record/replay proxy mode",
+		Long: `proxy listens on --listen and forwards all RPCs to --upstream (or --host and --port).
+Messages are decoded by descriptors from gRPC reflection of the upstream, proto files or protoset files.
+Each RPC is written to stdout as a line of JSON which has the method, headers, request and response messages,
+the status and timings. If --record is specified, the lines are also written to the session file.
+
+If --replay is specified, the proxy answers RPCs with the records of the session file instead of forwarding them.
+The record which has the same method and request messages is used. Replay requires proto files or protoset files.`,
+		Example: strings.Join([]string{
+			"        $ evans -r proxy --listen :50052 --upstream localhost:50051                           # log RPCs",
+			"        $ evans -r proxy --listen :50052 --upstream localhost:50051 --record session.jsonl    # log and save RPCs",
+			"        $ evans --proto api.proto proxy --listen :50052 --replay session.jsonl                # replay without the upstream",
+		}, "\n"),
+		RunE: runFunc(flags, func(_ *cobra.Command, cfg *mergedConfig) error {
+			return mode.RunAsProxyMode(cfg.Config, ui, &opt)
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVar(&opt.Listen, "listen", "127.0.0.1:50052", "the address that the proxy listens on")
+	f.StringVar(&opt.Upstream, "upstream", "", "the upstream address. if empty, --host and --port are used")
+	f.StringVar(&opt.RecordFile, "record", "", "the session file that records are written to")
+	f.StringVar(&opt.ReplayFile, "replay", "", "the session file to replay instead of forwarding RPCs")
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
 func runREPLCommand(cfg *mergedConfig, ui cui.UI) error {
 	cache, err := cache.Get()
 	if err != nil {
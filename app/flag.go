@@ -28,20 +28,34 @@ type flags struct {
 	}
 
 	common struct {
-		pkg        string
-		service    string
-		path       []string
-		proto      []string
-		host       string
-		port       string
-		header     map[string][]string
-		web        bool
-		reflection bool
-		tls        bool
-		cacert     string
-		cert       string
-		certKey    string
-		serverName string
+		pkg               string
+		service           string
+		path              []string
+		proto             []string
+		protoset          []string
+		host              string
+		port              string
+		header            map[string][]string
+		web               bool
+		reflection        bool
+		refreshReflection bool
+		tls               bool
+		cacert            string
+		cert              string
+		certKey           string
+		serverName        string
+		insecure          bool
+		tlsMinVersion     string
+		tlsCipherSuites   []string
+		profile           string
+		authTokenFile     string
+		authTokenURL      string
+		authClientID      string
+		authClientSecret  string
+		authScopes        []string
+		authCommand       string
+		authAllowPlain    bool
+		allowCommand      bool
 	}
 
 	meta struct {
@@ -0,0 +1,300 @@
+// Package assertion provides assertions against results of RPC calls.
+package assertion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Error is returned by Assert if the result doesn't satisfy the assertion.
+type Error struct {
+	Failures []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("assertion failed:\n  %s", strings.Join(e.Failures, "\n  "))
+}
+
+// Assertion represents expectations for the result of an RPC call.
+// Response messages are compared in the JSON mapping of Protocol Buffers, so field order is ignored.
+type Assertion struct {
+	// Code is the expected status code. If nil, the status code is not checked.
+	Code *codes.Code
+	// JSON is the expected response document. Fields which are not in JSON are ignored.
+	// If JSON is an array, it is compared with all response messages. Otherwise, it is compared with
+	// the only response message.
+	JSON interface{}
+	// GoldenFile is the path to the golden file that has the status and all response messages.
+	// If empty, the golden file is not compared.
+	GoldenFile string
+	// If Update is true, Assert updates the golden file with the result instead of comparing them.
+	Update bool
+	// IgnoreFields are dot-separated paths to fields of response messages which are ignored.
+	IgnoreFields []string
+}
+
+// golden represents the content of golden files.
+type golden struct {
+	Status    goldenStatus  `json:"status"`
+	Responses []interface{} `json:"responses"`
+}
+
+type goldenStatus struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Assert checks the result of an RPC call, which consists of the status and responses.
+// Each response is a message in the JSON mapping decoded with json.Decoder.UseNumber.
+// If the result doesn't satisfy a, Assert returns *Error.
+func (a *Assertion) Assert(code codes.Code, message string, responses []interface{}) error {
+	for _, p := range a.IgnoreFields {
+		for _, res := range responses {
+			removeField(res, strings.Split(p, "."))
+		}
+	}
+
+	var failures []string
+	if a.Code != nil && *a.Code != code {
+		failures = append(failures, fmt.Sprintf("code: expected %s, but got %s", a.Code, code))
+	}
+
+	if a.JSON != nil {
+		expected := a.JSON
+		for _, p := range a.IgnoreFields {
+			removeField(expected, strings.Split(p, "."))
+		}
+		if _, ok := expected.([]interface{}); ok {
+			failures = append(failures, compare("$", expected, toSlice(responses), true)...)
+		} else if len(responses) != 1 {
+			failures = append(failures, fmt.Sprintf("$: expected a response, but got %d responses", len(responses)))
+		} else {
+			failures = append(failures, compare("$", expected, responses[0], true)...)
+		}
+	}
+
+	if a.GoldenFile != "" {
+		actual := golden{
+			Status:    goldenStatus{Code: code.String(), Message: message},
+			Responses: toSlice(responses),
+		}
+		if a.Update {
+			if err := writeGolden(a.GoldenFile, &actual); err != nil {
+				return err
+			}
+		} else {
+			expected, err := readGolden(a.GoldenFile)
+			if err != nil {
+				return err
+			}
+			for _, p := range a.IgnoreFields {
+				for _, res := range expected.Responses {
+					removeField(res, strings.Split(p, "."))
+				}
+			}
+			failures = append(failures, compare("$.status.code", expected.Status.Code, actual.Status.Code, false)...)
+			failures = append(failures, compare("$.status.message", expected.Status.Message, actual.Status.Message, false)...)
+			failures = append(failures, compare("$.responses", expected.Responses, actual.Responses, false)...)
+		}
+	}
+
+	if len(failures) != 0 {
+		return &Error{Failures: failures}
+	}
+	return nil
+}
+
+// ParseCode parses s as a status code. s is a code name like "NotFound" or "NOT_FOUND", or its number.
+// Numbers of undefined codes are rejected.
+func ParseCode(s string) (codes.Code, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		if n > uint64(codes.Unauthenticated) {
+			return 0, errors.Errorf("unknown status code '%s'", s)
+		}
+		return codes.Code(n), nil
+	}
+	normalize := func(s string) string { return strings.ToLower(strings.ReplaceAll(s, "_", "")) }
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if normalize(c.String()) == normalize(s) {
+			return c, nil
+		}
+	}
+	return 0, errors.Errorf("unknown status code '%s'", s)
+}
+
+// DecodeJSON decodes b as a JSON document in the same way as response messages.
+func DecodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Match reports whether actual has all fields of expected. Object fields which are not in expected are ignored.
+// Both of them are JSON documents decoded in the same way as DecodeJSON.
+func Match(expected, actual interface{}) bool {
+	return len(compare("", expected, actual, true)) == 0
+}
+
+func readGolden(path string) (*golden, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the golden file")
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var g golden
+	if err := dec.Decode(&g); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the golden file")
+	}
+	if g.Responses == nil {
+		g.Responses = []interface{}{}
+	}
+	return &g, nil
+}
+
+func writeGolden(path string, g *golden) error {
+	b, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the golden file")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "failed to create the dir of the golden file")
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return errors.Wrap(err, "failed to write the golden file")
+	}
+	return nil
+}
+
+// compare compares expected with actual, and returns differences. If partial is true,
+// object fields which are not in expected are ignored.
+func compare(path string, expected, actual interface{}, partial bool) []string {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, but got %s", path, format(actual))}
+		}
+		var failures []string
+		for _, k := range sortedKeys(e) {
+			av, found := a[k]
+			if !found {
+				failures = append(failures, fmt.Sprintf("%s.%s: expected %s, but not found", path, k, format(e[k])))
+				continue
+			}
+			failures = append(failures, compare(path+"."+k, e[k], av, partial)...)
+		}
+		if !partial {
+			for _, k := range sortedKeys(a) {
+				if _, found := e[k]; !found {
+					failures = append(failures, fmt.Sprintf("%s.%s: unexpected field %s", path, k, format(a[k])))
+				}
+			}
+		}
+		return failures
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, but got %s", path, format(actual))}
+		}
+		if len(e) != len(a) {
+			return []string{fmt.Sprintf("%s: expected %d elements, but got %d", path, len(e), len(a))}
+		}
+		var failures []string
+		for i := range e {
+			failures = append(failures, compare(fmt.Sprintf("%s[%d]", path, i), e[i], a[i], partial)...)
+		}
+		return failures
+	default:
+		if !equalScalar(expected, actual) {
+			return []string{fmt.Sprintf("%s: expected %s, but got %s", path, format(expected), format(actual))}
+		}
+		return nil
+	}
+}
+
+// equalScalar compares JSON scalar values. Numbers are compared by their values.
+// A number and a string are regarded as equal if they represent the same value
+// because 64-bit integers are encoded as strings in the JSON mapping.
+func equalScalar(expected, actual interface{}) bool {
+	en, eok := toNumber(expected)
+	an, aok := toNumber(actual)
+	if eok && aok {
+		return en == an
+	}
+	return expected == actual
+}
+
+func toNumber(v interface{}) (string, bool) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return "", false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return "", false
+	}
+	// Integers are compared as they are to avoid the precision loss.
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return s, true
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), true
+}
+
+// removeField removes the field specified by path from v. Arrays on the path are traversed.
+func removeField(v interface{}, path []string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(v, path[0])
+			return
+		}
+		removeField(v[path[0]], path[1:])
+	case []interface{}:
+		for _, e := range v {
+			removeField(e, path)
+		}
+	}
+}
+
+func toSlice(responses []interface{}) []interface{} {
+	if responses == nil {
+		return []interface{}{}
+	}
+	return responses
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func format(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,209 @@
+package assertion_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/assertion"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+func mustDecode(t *testing.T, s string) interface{} {
+	t.Helper()
+	v, err := assertion.DecodeJSON([]byte(s))
+	if err != nil {
+		t.Fatalf("failed to decode %s: %s", s, err)
+	}
+	return v
+}
+
+func TestAssertion_Assert(t *testing.T) {
+	notFound := codes.NotFound
+
+	cases := map[string]struct {
+		assertion *assertion.Assertion
+		code      codes.Code
+		responses []string
+
+		expectedFailures []string
+	}{
+		"no assertions": {
+			assertion: &assertion.Assertion{},
+			responses: []string{`{"name": "foo"}`},
+		},
+		"code": {
+			assertion: &assertion.Assertion{Code: &notFound},
+			code:      codes.NotFound,
+		},
+		"code mismatch": {
+			assertion:        &assertion.Assertion{Code: &notFound},
+			code:             codes.OK,
+			expectedFailures: []string{"code: expected NotFound, but got OK"},
+		},
+		"partial match": {
+			assertion: &assertion.Assertion{JSON: `{"user": {"name": "foo"}, "ids": [1, "2"]}`},
+			responses: []string{`{"ids": ["1", 2], "user": {"age": 10, "name": "foo"}, "ok": true}`},
+		},
+		"mismatch": {
+			assertion: &assertion.Assertion{JSON: `{"user": {"name": "foo", "age": 20}, "ids": [1], "ok": false}`},
+			responses: []string{`{"ids": ["1", 2], "user": {"name": "bar"}, "ok": true}`},
+			expectedFailures: []string{
+				"$.ids: expected 1 elements, but got 2",
+				`$.ok: expected false, but got true`,
+				`$.user.age: expected 20, but not found`,
+				`$.user.name: expected "foo", but got "bar"`,
+			},
+		},
+		"ignore fields": {
+			assertion: &assertion.Assertion{
+				JSON:         `{"user": {"name": "foo", "createdAt": "2020-01-01T00:00:00Z"}}`,
+				IgnoreFields: []string{"user.createdAt"},
+			},
+			responses: []string{`{"user": {"name": "foo", "createdAt": "2021-01-01T00:00:00Z"}}`},
+		},
+		"streaming": {
+			assertion: &assertion.Assertion{JSON: `[{"name": "foo"}, {}]`},
+			responses: []string{`{"name": "foo"}`, `{"name": "bar"}`},
+		},
+		"object against several responses": {
+			assertion:        &assertion.Assertion{JSON: `{"name": "foo"}`},
+			responses:        []string{`{"name": "foo"}`, `{"name": "bar"}`},
+			expectedFailures: []string{"$: expected a response, but got 2 responses"},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if s, ok := c.assertion.JSON.(string); ok {
+				c.assertion.JSON = mustDecode(t, s)
+			}
+			var responses []interface{}
+			for _, r := range c.responses {
+				responses = append(responses, mustDecode(t, r))
+			}
+
+			err := c.assertion.Assert(c.code, "", responses)
+			if len(c.expectedFailures) == 0 {
+				if err != nil {
+					t.Fatalf("Assert must not return an error, but got '%s'", err)
+				}
+				return
+			}
+			var aerr *assertion.Error
+			if !errors.As(err, &aerr) {
+				t.Fatalf("Assert must return *assertion.Error, but got '%v'", err)
+			}
+			if diff := cmp.Diff(c.expectedFailures, aerr.Failures); diff != "" {
+				t.Errorf("unexpected failures (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAssertion_Assert_golden(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden", "out.json")
+	responses := func(s string) []interface{} { return []interface{}{mustDecode(t, s)} }
+
+	a := &assertion.Assertion{GoldenFile: golden, Update: true, IgnoreFields: []string{"id"}}
+	if err := a.Assert(codes.OK, "", responses(`{"id": 1, "name": "foo"}`)); err != nil {
+		t.Fatalf("Assert must not return an error, but got '%s'", err)
+	}
+	b, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("the golden file must be written, but got '%s'", err)
+	}
+	expected := `{
+  "status": {
+    "code": "OK",
+    "message": ""
+  },
+  "responses": [
+    {
+      "name": "foo"
+    }
+  ]
+}
+`
+	if diff := cmp.Diff(expected, string(b)); diff != "" {
+		t.Errorf("unexpected golden file (-want, +got):\n%s", diff)
+	}
+
+	a = &assertion.Assertion{GoldenFile: golden, IgnoreFields: []string{"id"}}
+	if err := a.Assert(codes.OK, "", responses(`{"name": "foo", "id": 2}`)); err != nil {
+		t.Errorf("Assert must not return an error, but got '%s'", err)
+	}
+
+	err = a.Assert(codes.Internal, "internal", responses(`{"name": "bar", "extra": true}`))
+	var aerr *assertion.Error
+	if !errors.As(err, &aerr) {
+		t.Fatalf("Assert must return *assertion.Error, but got '%v'", err)
+	}
+	expectedFailures := []string{
+		`$.status.code: expected "OK", but got "Internal"`,
+		`$.status.message: expected "", but got "internal"`,
+		`$.responses[0].name: expected "foo", but got "bar"`,
+		`$.responses[0].extra: unexpected field true`,
+	}
+	if diff := cmp.Diff(expectedFailures, aerr.Failures); diff != "" {
+		t.Errorf("unexpected failures (-want, +got):\n%s", diff)
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	cases := map[string]struct {
+		in       string
+		expected codes.Code
+		hasErr   bool
+	}{
+		"name":           {in: "NotFound", expected: codes.NotFound},
+		"snake case":     {in: "NOT_FOUND", expected: codes.NotFound},
+		"number":         {in: "16", expected: codes.Unauthenticated},
+		"unknown name":   {in: "Foo", hasErr: true},
+		"unknown number": {in: "17", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			code, err := assertion.ParseCode(c.in)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("ParseCode must return an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCode must not return an error, but got '%s'", err)
+			}
+			if code != c.expected {
+				t.Errorf("expected %s, but got %s", c.expected, code)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := map[string]struct {
+		expected, actual string
+		matched          bool
+	}{
+		"same":                   {expected: `{"name": "oumae"}`, actual: `{"name": "oumae"}`, matched: true},
+		"extra fields":           {expected: `{"name": "oumae"}`, actual: `{"name": "oumae", "age": 15}`, matched: true},
+		"nested":                 {expected: `{"a": {"b": 1}}`, actual: `{"a": {"b": 1, "c": 2}}`, matched: true},
+		"64-bit integer":         {expected: `{"id": 1}`, actual: `{"id": "1"}`, matched: true},
+		"empty":                  {expected: `{}`, actual: `{"name": "oumae"}`, matched: true},
+		"different value":        {expected: `{"name": "oumae"}`, actual: `{"name": "kousaka"}`},
+		"missing field":          {expected: `{"name": "oumae"}`, actual: `{}`},
+		"different array length": {expected: `{"a": [1]}`, actual: `{"a": [1, 2]}`},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if matched := assertion.Match(mustDecode(t, c.expected), mustDecode(t, c.actual)); matched != c.matched {
+				t.Errorf("expected %t, but got %t", c.matched, matched)
+			}
+		})
+	}
+}
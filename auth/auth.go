@@ -0,0 +1,161 @@
+// Package auth provides per-RPC credentials that set the authorization header to each request.
+// A token is obtained from one of these sources:
+//
+//   - a token file, which is re-read when it is changed.
+//   - the OAuth2 client credentials flow.
+//   - an external command which prints a token.
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktr0731/evans/interpolate"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc/credentials"
+)
+
+// Config describes the token source. At most one of TokenFile, TokenURL and Command can be specified.
+type Config struct {
+	// TokenFile is the path to the file that has a bearer token.
+	TokenFile string
+
+	// TokenURL is the token endpoint of the OAuth2 client credentials flow.
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// Command is the command that prints a token to stdout. The output is either a token itself, or
+	// a JSON object that has "token" and optionally "expiry" in RFC 3339 format.
+	// The command is executed again after the token is expired. If the output doesn't have the expiry,
+	// the token is used during the session.
+	Command string
+
+	// AllowPlaintext allows sending tokens over connections without TLS.
+	AllowPlaintext bool
+}
+
+// Enabled returns true if any token source is specified.
+func (c *Config) Enabled() bool {
+	return c.TokenFile != "" || c.TokenURL != "" || c.Command != ""
+}
+
+// New returns credentials.PerRPCCredentials which obtain a token from the source described by cfg.
+// New returns nil if no sources are specified. cfg must have been validated by config.Config.Validate.
+//
+// The returned credentials require transport security unless cfg.AllowPlaintext is true.
+func New(cfg *Config) (credentials.PerRPCCredentials, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	var src oauth2.TokenSource
+	switch {
+	case cfg.TokenFile != "":
+		src = &fileTokenSource{path: cfg.TokenFile}
+	case cfg.TokenURL != "":
+		cc := &clientcredentials.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			TokenURL:     cfg.TokenURL,
+			Scopes:       cfg.Scopes,
+		}
+		// The returned TokenSource caches the token until it is expired.
+		src = cc.TokenSource(context.Background())
+	case cfg.Command != "":
+		src = oauth2.ReuseTokenSource(nil, &execTokenSource{command: cfg.Command})
+	}
+	return &perRPCCredentials{src: src, allowPlaintext: cfg.AllowPlaintext}, nil
+}
+
+type perRPCCredentials struct {
+	src            oauth2.TokenSource
+	allowPlaintext bool
+}
+
+func (c *perRPCCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	tok, err := c.src.Token()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get a token")
+	}
+	return map[string]string{"authorization": tok.Type() + " " + tok.AccessToken}, nil
+}
+
+func (c *perRPCCredentials) RequireTransportSecurity() bool {
+	return !c.allowPlaintext
+}
+
+// fileTokenSource reads a token from the file. The file is re-read only if it is changed.
+type fileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   *oauth2.Token
+}
+
+func (s *fileTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat the token file")
+	}
+	if s.token != nil && fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return s.token, nil
+	}
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the token file")
+	}
+	v := strings.TrimSpace(string(b))
+	if v == "" {
+		return nil, errors.Errorf("the token file '%s' is empty", s.path)
+	}
+	logger.Printf("read the token file '%s'", s.path)
+	s.token = &oauth2.Token{AccessToken: v, TokenType: "Bearer"}
+	s.modTime, s.size = fi.ModTime(), fi.Size()
+	return s.token, nil
+}
+
+// execTokenSource executes the command each time Token is called.
+type execTokenSource struct {
+	command string
+}
+
+func (s *execTokenSource) Token() (*oauth2.Token, error) {
+	logger.Printf("execute the auth command '%s'", s.command)
+	out, err := interpolate.RunCommand(s.command)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run the auth command")
+	}
+
+	out = bytes.TrimSpace(out)
+	tok := &oauth2.Token{AccessToken: string(out), TokenType: "Bearer"}
+	if len(out) != 0 && out[0] == '{' {
+		var res struct {
+			Token  string    `json:"token"`
+			Expiry time.Time `json:"expiry"`
+		}
+		if err := json.Unmarshal(out, &res); err != nil {
+			return nil, errors.Wrap(err, "failed to decode the output of the auth command")
+		}
+		tok.AccessToken, tok.Expiry = res.Token, res.Expiry
+	}
+	if tok.AccessToken == "" {
+		return nil, errors.New("the auth command printed no token")
+	}
+	return tok, nil
+}
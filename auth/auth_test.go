@@ -0,0 +1,190 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ktr0731/evans/auth"
+)
+
+func TestNew(t *testing.T) {
+	cases := map[string]*auth.Config{
+		"disabled":             {},
+		"token file":           {TokenFile: "token"},
+		"oauth2":               {TokenURL: "http://localhost/token", ClientID: "evans"},
+		"command only":         {Command: "echo token"},
+		"scopes without oauth": {Scopes: []string{"read"}},
+		"allow plaintext":      {TokenFile: "token", AllowPlaintext: true},
+	}
+	for name, cfg := range cases {
+		cfg := cfg
+		t.Run(name, func(t *testing.T) {
+			creds, err := auth.New(cfg)
+			if err != nil {
+				t.Fatalf("New must not return an error, but got '%s'", err)
+			}
+			if cfg.Enabled() != (creds != nil) {
+				t.Fatalf("New must return credentials only if a source is specified")
+			}
+			if creds != nil && creds.RequireTransportSecurity() == cfg.AllowPlaintext {
+				t.Errorf("the credentials must require transport security unless AllowPlaintext is true")
+			}
+		})
+	}
+}
+
+func TestNew_tokenFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(p, []byte("first\n"), 0600); err != nil {
+		t.Fatalf("failed to write the token file: %s", err)
+	}
+	creds, err := auth.New(&auth.Config{TokenFile: p})
+	if err != nil {
+		t.Fatalf("New must not return an error, but got '%s'", err)
+	}
+	get := func() string {
+		md, err := creds.GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("GetRequestMetadata must not return an error, but got '%s'", err)
+		}
+		return md["authorization"]
+	}
+
+	if actual := get(); actual != "Bearer first" {
+		t.Errorf("expected 'Bearer first', but got '%s'", actual)
+	}
+
+	// Change the content and the modification time.
+	if err := os.WriteFile(p, []byte("second"), 0600); err != nil {
+		t.Fatalf("failed to write the token file: %s", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(p, future, future); err != nil {
+		t.Fatalf("failed to change the modification time: %s", err)
+	}
+	if actual := get(); actual != "Bearer second" {
+		t.Errorf("the changed token file must be re-read, expected 'Bearer second', but got '%s'", actual)
+	}
+}
+
+func TestNew_oauth2(t *testing.T) {
+	var requested int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse the form: %s", err)
+		}
+		if gt := r.Form.Get("grant_type"); gt != "client_credentials" {
+			t.Errorf("expected grant_type 'client_credentials', but got '%s'", gt)
+		}
+		if scope := r.Form.Get("scope"); scope != "read write" {
+			t.Errorf("expected scope 'read write', but got '%s'", scope)
+		}
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "evans" || secret != "secret" {
+			t.Errorf("unexpected client credentials: %s, %s", id, secret)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "oauth2-token",
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer srv.Close()
+
+	cfg := &auth.Config{TokenURL: srv.URL, ClientID: "evans", ClientSecret: "secret", Scopes: []string{"read", "write"}}
+	creds, err := auth.New(cfg)
+	if err != nil {
+		t.Fatalf("New must not return an error, but got '%s'", err)
+	}
+	for i := 0; i < 2; i++ {
+		md, err := creds.GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("GetRequestMetadata must not return an error, but got '%s'", err)
+		}
+		if actual := md["authorization"]; actual != "Bearer oauth2-token" {
+			t.Errorf("expected 'Bearer oauth2-token', but got '%s'", actual)
+		}
+	}
+	if requested != 1 {
+		t.Errorf("the token must be reused until it is expired, but requested %d times", requested)
+	}
+}
+
+func TestNew_command(t *testing.T) {
+	cases := map[string]struct {
+		command  string
+		expected string
+		hasErr   bool
+	}{
+		"plain":     {command: "echo plain-token", expected: "Bearer plain-token"},
+		"json":      {command: `echo '{"token": "json-token", "expiry": "2100-01-01T00:00:00Z"}'`, expected: "Bearer json-token"},
+		"no output": {command: "true", hasErr: true},
+		"failed":    {command: "exit 1", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			creds, err := auth.New(&auth.Config{Command: c.command})
+			if err != nil {
+				t.Fatalf("New must not return an error, but got '%s'", err)
+			}
+			md, err := creds.GetRequestMetadata(context.Background())
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("GetRequestMetadata must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetRequestMetadata must not return an error, but got '%s'", err)
+			}
+			if actual := md["authorization"]; actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNew_commandCache(t *testing.T) {
+	cases := map[string]struct {
+		expiry   string
+		expected []string
+	}{
+		// The token without the expiry is used during the session.
+		"no expiry": {expected: []string{"Bearer 1", "Bearer 1"}},
+		// The expired token is obtained again each time.
+		"expired": {expiry: "2000-01-01T00:00:00Z", expected: []string{"Bearer 1", "Bearer 2"}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f := filepath.Join(t.TempDir(), "count")
+			// The command prints the number of times it is executed as a token.
+			command := `echo x >> ` + f + `; echo "{\"token\": \"$(wc -l < ` + f + ` | tr -d ' ')\", \"expiry\": \"` + c.expiry + `\"}"`
+			if c.expiry == "" {
+				command = `echo x >> ` + f + `; wc -l < ` + f + ` | tr -d ' '`
+			}
+			creds, err := auth.New(&auth.Config{Command: command})
+			if err != nil {
+				t.Fatalf("New must not return an error, but got '%s'", err)
+			}
+			for _, expected := range c.expected {
+				md, err := creds.GetRequestMetadata(context.Background())
+				if err != nil {
+					t.Fatalf("GetRequestMetadata must not return an error, but got '%s'", err)
+				}
+				if actual := md["authorization"]; actual != expected {
+					t.Errorf("expected '%s', but got '%s'", expected, actual)
+				}
+			}
+		})
+	}
+}
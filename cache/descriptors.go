@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ktr0731/evans/meta"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+const descriptorsDirName = "descriptors"
+
+// Descriptors represents file descriptors of a server which are resolved by gRPC reflection.
+// Descriptors are cached per server, so use DescriptorsKey to get the key of the server.
+type Descriptors struct {
+	Version   string    `toml:"version"`
+	CreatedAt time.Time `toml:"createdAt"`
+	// Services is the service list of the server when the descriptors are cached.
+	Services []string         `toml:"services"`
+	Files    []DescriptorFile `toml:"files"`
+
+	path string
+}
+
+// DescriptorFile represents a serialized FileDescriptorProto.
+type DescriptorFile struct {
+	Name string `toml:"name"`
+	// Content is the serialized FileDescriptorProto encoded by base64.
+	Content string `toml:"content"`
+}
+
+// DescriptorsKey returns the cache key of the server which is specified by addr and headers.
+// Headers are a part of the key because servers may expose different services depending on them.
+func DescriptorsKey(addr string, headers map[string][]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", addr)
+	for _, k := range keys {
+		vals := append([]string(nil), headers[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			fmt.Fprintf(h, "%s=%s\n", k, v)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GetDescriptors returns cached descriptors associated with key.
+// If the cache is not found, is older than ttl or is created by another version of Evans,
+// GetDescriptors returns empty descriptors. The returned descriptors are written by Save.
+func GetDescriptors(key string, ttl time.Duration) (*Descriptors, error) {
+	p := resolveDescriptorsPath(key)
+	empty := &Descriptors{path: p}
+	empty.Reset(nil)
+
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open the descriptor cache file '%s'", p)
+	}
+	defer f.Close()
+
+	var d Descriptors
+	if err := decodeTOML(f, &d); err != nil {
+		// The broken cache file will be overwritten.
+		return empty, nil
+	}
+	if d.Version != meta.Version.String() || time.Since(d.CreatedAt) > ttl {
+		return empty, nil
+	}
+	d.path = p
+
+	return &d, nil
+}
+
+// Reset clears cached files and replaces the service list with services.
+func (d *Descriptors) Reset(services []string) {
+	d.Version = meta.Version.String()
+	d.CreatedAt = time.Now()
+	d.Services = services
+	d.Files = nil
+}
+
+// HasFile reports whether the file named name is cached.
+func (d *Descriptors) HasFile(name string) bool {
+	for _, f := range d.Files {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFile adds the serialized FileDescriptorProto b named name.
+// Files must be added in the dependency order, that is, a file must be added after all of its imports.
+func (d *Descriptors) AddFile(name string, b []byte) {
+	d.Files = append(d.Files, DescriptorFile{
+		Name:    name,
+		Content: base64.StdEncoding.EncodeToString(b),
+	})
+}
+
+// FileContents returns serialized FileDescriptorProtos in the order they were added.
+func (d *Descriptors) FileContents() ([][]byte, error) {
+	contents := make([][]byte, 0, len(d.Files))
+	for _, f := range d.Files {
+		b, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode the cached file '%s'", f.Name)
+		}
+		contents = append(contents, b)
+	}
+	return contents, nil
+}
+
+// Save writes the receiver to the descriptor cache file.
+func (d *Descriptors) Save() error {
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return errors.Wrap(err, "failed to create the descriptor cache dir")
+	}
+	f, err := os.Create(d.path)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the descriptor cache file")
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(*d)
+}
+
+func resolveDescriptorsPath(key string) string {
+	return filepath.Join(xdgbasedir.CacheHome(), meta.AppName, descriptorsDirName, key+".toml")
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDescriptorsKey(t *testing.T) {
+	base := DescriptorsKey("localhost:50051", map[string][]string{"a": {"1", "2"}, "b": {"3"}})
+
+	if k := DescriptorsKey("localhost:50051", map[string][]string{"b": {"3"}, "a": {"2", "1"}}); k != base {
+		t.Errorf("the key must not depend on the order of headers")
+	}
+	if k := DescriptorsKey("localhost:50052", map[string][]string{"a": {"1", "2"}, "b": {"3"}}); k == base {
+		t.Errorf("the key must depend on the address")
+	}
+	if k := DescriptorsKey("localhost:50051", map[string][]string{"a": {"1"}, "b": {"3"}}); k == base {
+		t.Errorf("the key must depend on header values")
+	}
+}
+
+func TestDescriptors(t *testing.T) {
+	key := DescriptorsKey(t.Name(), nil)
+	os.Remove(resolveDescriptorsPath(key))
+	defer os.Remove(resolveDescriptorsPath(key))
+
+	t.Run("GetDescriptors returns empty descriptors if the cache is not found", func(t *testing.T) {
+		d, err := GetDescriptors(key, time.Hour)
+		if err != nil {
+			t.Fatalf("GetDescriptors must not return an error, but got '%s'", err)
+		}
+		if len(d.Services) != 0 || len(d.Files) != 0 {
+			t.Errorf("descriptors must be empty, but got %+v", d)
+		}
+	})
+
+	t.Run("Save and GetDescriptors", func(t *testing.T) {
+		d, err := GetDescriptors(key, time.Hour)
+		if err != nil {
+			t.Fatalf("GetDescriptors must not return an error, but got '%s'", err)
+		}
+		d.Reset([]string{"api.Example"})
+		d.AddFile("api.proto", []byte{0x0a, 0x00, 0xff})
+		if err := d.Save(); err != nil {
+			t.Fatalf("Save must not return an error, but got '%s'", err)
+		}
+
+		d, err = GetDescriptors(key, time.Hour)
+		if err != nil {
+			t.Fatalf("GetDescriptors must not return an error, but got '%s'", err)
+		}
+		if diff := cmp.Diff([]string{"api.Example"}, d.Services); diff != "" {
+			t.Errorf("unexpected services (-want, +got):\n%s", diff)
+		}
+		if !d.HasFile("api.proto") {
+			t.Errorf("api.proto must be cached")
+		}
+		contents, err := d.FileContents()
+		if err != nil {
+			t.Fatalf("FileContents must not return an error, but got '%s'", err)
+		}
+		if diff := cmp.Diff([][]byte{{0x0a, 0x00, 0xff}}, contents); diff != "" {
+			t.Errorf("unexpected contents (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("GetDescriptors returns empty descriptors if the cache is expired", func(t *testing.T) {
+		time.Sleep(time.Millisecond)
+		d, err := GetDescriptors(key, time.Nanosecond)
+		if err != nil {
+			t.Fatalf("GetDescriptors must not return an error, but got '%s'", err)
+		}
+		if len(d.Services) != 0 || len(d.Files) != 0 {
+			t.Errorf("descriptors must be empty, but got %+v", d)
+		}
+	})
+}
@@ -9,8 +9,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/k0kubun/pp"
+	"github.com/ktr0731/evans/editor"
+	"github.com/ktr0731/evans/interpolate"
 	"github.com/ktr0731/evans/logger"
 	"github.com/ktr0731/evans/meta"
 	"github.com/ktr0731/go-multierror"
@@ -31,6 +34,13 @@ type Server struct {
 	Reflection bool   `toml:"reflection"`
 	TLS        bool   `toml:"tls"`
 	Name       string `toml:"name"`
+
+	// ReflectionCacheTTL is the lifetime of descriptors cached from gRPC reflection.
+	// Zero disables the cache. It is the default, so the cache is opt-in.
+	ReflectionCacheTTL time.Duration `toml:"reflectionCacheTTL"`
+	// RefreshReflection discards the cached descriptors. It is a one-shot option, so it is set only by
+	// --refresh-reflection and never loaded from config files.
+	RefreshReflection bool `toml:"-" mapstructure:"-"`
 }
 
 type Header map[string][]string
@@ -41,6 +51,40 @@ type Request struct {
 	CACertFile  string `toml:"caCertFile"`
 	CertFile    string `toml:"certFile"`
 	CertKeyFile string `toml:"certKeyFile"`
+	// Insecure skips the verification of the server certificate.
+	Insecure bool `toml:"insecure"`
+	// TLSMinVersion is the minimum TLS version such as "1.2". Empty means the default of Go.
+	TLSMinVersion string `toml:"tlsMinVersion"`
+	// CipherSuites are the names of the cipher suites used in TLS 1.0-1.2. Empty means the default of Go.
+	CipherSuites []string `toml:"cipherSuites"`
+	// KeyLogFile is the file which TLS master secrets are written to. If it is empty, SSLKEYLOGFILE is used.
+	KeyLogFile string `toml:"keyLogFile"`
+	// Timeout is the deadline of each RPC. Zero means no deadline.
+	Timeout time.Duration `toml:"timeout"`
+	// AllowCommandInterpolation enables "$(command)" in header values and TLS file paths.
+	// It is accepted only from the global config and --allow-command-interpolation, because a local config or
+	// a profile may come from an untrusted repository.
+	AllowCommandInterpolation bool `toml:"allowCommandInterpolation"`
+	// Auth is the source of the token which is set to the authorization header of each request.
+	Auth *Auth `toml:"auth"`
+}
+
+// Auth specifies one of a token file, the OAuth2 client credentials flow or a command as the token source.
+type Auth struct {
+	// TokenFile is the file that has a bearer token. It is re-read when the file is changed.
+	TokenFile string `toml:"tokenFile"`
+
+	// TokenURL is the token endpoint of the OAuth2 client credentials flow.
+	TokenURL     string   `toml:"tokenURL"`
+	ClientID     string   `toml:"clientID"`
+	ClientSecret string   `toml:"clientSecret"`
+	Scopes       []string `toml:"scopes"`
+
+	// Command is the command that prints a token. It requires Request.AllowCommandInterpolation.
+	Command string `toml:"command"`
+
+	// AllowPlaintext allows sending the token to servers without TLS.
+	AllowPlaintext bool `toml:"allowPlaintext"`
 }
 
 type REPL struct {
@@ -72,6 +116,140 @@ type Config struct {
 	Request *Request `toml:"request"`
 }
 
+// expandRequest expands header values and TLS file paths in r. Header values are regarded as secrets.
+func expandRequest(r *Request) error {
+	for k, v := range r.Header {
+		for i := range v {
+			ev, err := interpolate.ExpandSecret(v[i])
+			if err != nil {
+				return errors.Wrapf(err, "failed to expand the header '%s'", k)
+			}
+			v[i] = ev
+		}
+	}
+	paths := []*string{&r.CACertFile, &r.CertFile, &r.CertKeyFile, &r.KeyLogFile}
+	if r.Auth != nil {
+		paths = append(paths, &r.Auth.TokenFile)
+		v, err := interpolate.ExpandSecret(r.Auth.ClientSecret)
+		if err != nil {
+			return errors.Wrap(err, "failed to expand request.auth.clientSecret")
+		}
+		r.Auth.ClientSecret = v
+	}
+	for _, p := range paths {
+		ep, err := interpolate.Expand(*p)
+		if err != nil {
+			return errors.Wrapf(err, "failed to expand the path '%s'", *p)
+		}
+		*p = ep
+	}
+	return nil
+}
+
+// expandProfileRequest is the same as expandRequest, but for the request section of a profile.
+func expandProfileRequest(req map[string]interface{}) error {
+	for k, v := range req {
+		switch k {
+		case "header":
+			h, _ := v.(map[string]interface{})
+			for hk, hv := range h {
+				switch hv := hv.(type) {
+				case string:
+					ev, err := interpolate.ExpandSecret(hv)
+					if err != nil {
+						return errors.Wrapf(err, "failed to expand the header '%s'", hk)
+					}
+					h[hk] = ev
+				case []interface{}:
+					for i, e := range hv {
+						s, ok := e.(string)
+						if !ok {
+							continue
+						}
+						ev, err := interpolate.ExpandSecret(s)
+						if err != nil {
+							return errors.Wrapf(err, "failed to expand the header '%s'", hk)
+						}
+						hv[i] = ev
+					}
+				}
+			}
+		case "cacertfile", "certfile", "certkeyfile", "keylogfile":
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+			ev, err := interpolate.Expand(s)
+			if err != nil {
+				return errors.Wrapf(err, "failed to expand the path '%s'", s)
+			}
+			req[k] = ev
+		case "auth":
+			a, _ := v.(map[string]interface{})
+			if s, ok := a["tokenfile"].(string); ok {
+				ev, err := interpolate.Expand(s)
+				if err != nil {
+					return errors.Wrapf(err, "failed to expand the path '%s'", s)
+				}
+				a["tokenfile"] = ev
+			}
+			if s, ok := a["clientsecret"].(string); ok {
+				ev, err := interpolate.ExpandSecret(s)
+				if err != nil {
+					return errors.Wrap(err, "failed to expand request.auth.clientSecret")
+				}
+				a["clientsecret"] = ev
+			}
+		}
+	}
+	return nil
+}
+
+// Clone returns a deep copy of c.
+func (c *Config) Clone() *Config {
+	var newCfg Config
+	if c.Default != nil {
+		d := *c.Default
+		d.ProtoPath = append([]string(nil), c.Default.ProtoPath...)
+		d.ProtoFile = append([]string(nil), c.Default.ProtoFile...)
+		d.Protoset = append([]string(nil), c.Default.Protoset...)
+		newCfg.Default = &d
+	}
+	if c.Meta != nil {
+		m := *c.Meta
+		newCfg.Meta = &m
+	}
+	if c.REPL != nil {
+		r := *c.REPL
+		newCfg.REPL = &r
+	}
+	if c.Server != nil {
+		s := *c.Server
+		newCfg.Server = &s
+	}
+	if c.Log != nil {
+		l := *c.Log
+		newCfg.Log = &l
+	}
+	if c.Request != nil {
+		r := *c.Request
+		if c.Request.Header != nil {
+			r.Header = make(Header, len(c.Request.Header))
+			for k, v := range c.Request.Header {
+				r.Header[k] = append([]string(nil), v...)
+			}
+		}
+		r.CipherSuites = append([]string(nil), c.Request.CipherSuites...)
+		if c.Request.Auth != nil {
+			a := *c.Request.Auth
+			a.Scopes = append([]string(nil), c.Request.Auth.Scopes...)
+			r.Auth = &a
+		}
+		newCfg.Request = &r
+	}
+	return &newCfg
+}
+
 // ValidationError contains errors that describes invalid config conditions.
 type ValidationError struct {
 	Err *multierror.Error
@@ -94,9 +272,35 @@ func (c *Config) Validate() error {
 		{"port must not be empty", len(c.Server.Port) == 0},
 		{"certFile config or --cert flag required", c.Request.CertFile == "" && c.Request.CertKeyFile != ""},
 		{"certKeyFile config or --certkey flag required", c.Request.CertFile != "" && c.Request.CertKeyFile == ""},
-		{"one or more proto files, or gRPC reflection required", len(c.Default.ProtoFile) == 0 && !c.Server.Reflection},
-		// TODO: support it.
-		{"currently, gRPC-Web with TLS communication is not supported", c.Request.Web && c.Server.TLS},
+		{"insecure config or --insecure flag requires TLS", c.Request.Insecure && !c.Server.TLS},
+		{"tlsMinVersion config or --tls-min-version flag requires TLS", c.Request.TLSMinVersion != "" && !c.Server.TLS},
+		{"cipherSuites config or --tls-cipher-suites flag requires TLS", len(c.Request.CipherSuites) != 0 && !c.Server.TLS},
+		{
+			"one or more proto files, protoset files, or gRPC reflection required",
+			len(c.Default.ProtoFile) == 0 && len(c.Default.Protoset) == 0 && !c.Server.Reflection,
+		},
+		{"cannot specify both of proto files and protoset files", len(c.Default.ProtoFile) != 0 && len(c.Default.Protoset) != 0},
+		{
+			fmt.Sprintf("default.sourcePriority must be '%s' or '%s'", SourcePriorityReflection, SourcePriorityFiles),
+			c.Default.SourcePriority != SourcePriorityReflection && c.Default.SourcePriority != SourcePriorityFiles,
+		},
+		{
+			"only one of request.auth.tokenFile, request.auth.tokenURL and request.auth.command can be specified",
+			c.Request.Auth != nil && countNonEmpty(c.Request.Auth.TokenFile, c.Request.Auth.TokenURL, c.Request.Auth.Command) > 1,
+		},
+		{
+			"request.auth.clientID config or --auth-client-id flag required",
+			c.Request.Auth != nil && c.Request.Auth.TokenURL != "" && c.Request.Auth.ClientID == "",
+		},
+		{
+			"request.auth requires TLS. enable --tls, or allow sending the token without TLS by request.auth.allowPlaintext or --auth-allow-plaintext flag",
+			c.Request.Auth != nil && countNonEmpty(c.Request.Auth.TokenFile, c.Request.Auth.TokenURL, c.Request.Auth.Command) != 0 &&
+				!c.Server.TLS && !c.Request.Auth.AllowPlaintext,
+		},
+		{
+			"request.auth.command requires request.allowCommandInterpolation in the global config or --allow-command-interpolation flag",
+			c.Request.Auth != nil && c.Request.Auth.Command != "" && !c.Request.AllowCommandInterpolation,
+		},
 	}
 	for _, c := range invalidCases {
 		if c.cond {
@@ -109,11 +313,33 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+func countNonEmpty(s ...string) int {
+	var n int
+	for _, e := range s {
+		if e != "" {
+			n++
+		}
+	}
+	return n
+}
+
+const (
+	// SourcePriorityReflection prefers gRPC reflection to proto or protoset files.
+	SourcePriorityReflection = "reflection"
+	// SourcePriorityFiles prefers proto or protoset files to gRPC reflection.
+	SourcePriorityFiles = "files"
+)
+
 type Default struct {
 	ProtoPath []string `toml:"protoPath"`
 	ProtoFile []string `toml:"protoFile"`
+	Protoset  []string `toml:"protoset"`
 	Package   string   `toml:"package"`
 	Service   string   `toml:"service"`
+
+	// SourcePriority decides which descriptor source is looked up first
+	// if both of gRPC reflection and files are specified.
+	SourcePriority string `toml:"sourcePriority"`
 }
 
 type Log struct {
@@ -122,13 +348,20 @@ type Log struct {
 
 // Get returns the config which loaded from the global and local config files,
 // and command line flags passed as an argument. Note that fs must have been parsed.
+// If the profile is specified by --profile, the profile defined in the config files is also applied.
 //
-// The order of priority is flags > local > global.
+// The order of priority is flags > profile > local > global.
+//
+// Header values and TLS file paths are expanded by interpolate.Expand. See package interpolate for details.
 func Get(fs *pflag.FlagSet) (*Config, error) {
 	cfg, err := initConfig(fs)
 	if err != nil {
 		return nil, err
 	}
+	interpolate.AllowCommand(cfg.Request.AllowCommandInterpolation)
+	if err := expandRequest(cfg.Request); err != nil {
+		return nil, err
+	}
 	logger.Scriptf("the conclusive config: %s\n", func() []interface{} {
 		return []interface{}{pp.Sprint(cfg)}
 	})
@@ -139,8 +372,10 @@ func newDefaultViper() *viper.Viper {
 	v := viper.New()
 	v.SetDefault("default.protoPath", []string{""})
 	v.SetDefault("default.protoFile", []string{""})
+	v.SetDefault("default.protoset", []string{""})
 	v.SetDefault("default.package", "")
 	v.SetDefault("default.service", "")
+	v.SetDefault("default.sourcePriority", SourcePriorityReflection)
 
 	// We set the default version to v0.6.10 because the structure of Config is changed at v0.6.11.
 	v.SetDefault("meta.configVersion", "0.6.10")
@@ -159,6 +394,7 @@ func newDefaultViper() *viper.Viper {
 	v.SetDefault("server.reflection", false)
 	v.SetDefault("server.tls", false)
 	v.SetDefault("server.name", "")
+	v.SetDefault("server.reflectionCacheTTL", "0s")
 
 	v.SetDefault("log.prefix", "evans: ")
 
@@ -167,6 +403,19 @@ func newDefaultViper() *viper.Viper {
 	v.SetDefault("request.certFile", "")
 	v.SetDefault("request.certKeyFile", "")
 	v.SetDefault("request.web", false)
+	v.SetDefault("request.insecure", false)
+	v.SetDefault("request.tlsMinVersion", "")
+	v.SetDefault("request.cipherSuites", []string{})
+	v.SetDefault("request.keyLogFile", "")
+	v.SetDefault("request.timeout", "0s")
+	v.SetDefault("request.allowCommandInterpolation", false)
+	v.SetDefault("request.auth.tokenFile", "")
+	v.SetDefault("request.auth.tokenURL", "")
+	v.SetDefault("request.auth.clientID", "")
+	v.SetDefault("request.auth.clientSecret", "")
+	v.SetDefault("request.auth.scopes", []string{})
+	v.SetDefault("request.auth.command", "")
+	v.SetDefault("request.auth.allowPlaintext", false)
 
 	return v
 }
@@ -175,21 +424,33 @@ func newDefaultViper() *viper.Viper {
 func bindFlags(vp *viper.Viper, fs *pflag.FlagSet) {
 	// kv defines the mapping from a viper config name to a flag name.
 	kv := map[string]string{
-		"default.protoPath":   "path",
-		"default.protoFile":   "proto",
-		"default.package":     "package",
-		"default.service":     "service",
-		"server.host":         "host",
-		"server.port":         "port",
-		"server.reflection":   "reflection",
-		"server.tls":          "tls",
-		"server.name":         "servername",
-		"request.header":      "header",
-		"request.web":         "web",
-		"request.cacertFile":  "cacert",
-		"request.certFile":    "cert",
-		"request.certKeyFile": "certkey",
-		"repl.silent":         "silent",
+		"default.protoPath":           "path",
+		"default.protoFile":           "proto",
+		"default.protoset":            "protoset",
+		"default.package":             "package",
+		"default.service":             "service",
+		"server.host":                 "host",
+		"server.port":                 "port",
+		"server.reflection":           "reflection",
+		"server.tls":                  "tls",
+		"server.name":                 "servername",
+		"request.header":              "header",
+		"request.web":                 "web",
+		"request.cacertFile":          "cacert",
+		"request.certFile":            "cert",
+		"request.certKeyFile":         "certkey",
+		"request.insecure":            "insecure",
+		"request.tlsMinVersion":       "tls-min-version",
+		"request.cipherSuites":        "tls-cipher-suites",
+		"request.timeout":             "timeout",
+		"request.auth.tokenFile":      "auth-token-file",
+		"request.auth.tokenURL":       "auth-token-url",
+		"request.auth.clientID":       "auth-client-id",
+		"request.auth.clientSecret":   "auth-client-secret",
+		"request.auth.scopes":         "auth-scopes",
+		"request.auth.command":        "auth-command",
+		"request.auth.allowPlaintext": "auth-allow-plaintext",
+		"repl.silent":                 "silent",
 	}
 	for k, v := range kv {
 		f := fs.Lookup(v)
@@ -332,10 +593,19 @@ func writeLatestDefaultConfig(path string) (*Config, error) {
 func initConfig(fs *pflag.FlagSet) (cfg *Config, err error) {
 	v := newDefaultViper()
 
+	// allowCommand is request.allowCommandInterpolation of the global config.
+	// Values in the local config and profiles are ignored.
+	var allowCommand bool
+
 	defer func() {
 		if fs == nil {
 			logger.Println("flagset is not found")
 		} else {
+			if err == nil {
+				if err = applyProfile(v, profileName(fs)); err != nil {
+					return
+				}
+			}
 			logger.Println("bind flagset to the loaded config")
 			bindFlags(v, fs)
 			if err = v.Unmarshal(cfg); err != nil {
@@ -344,6 +614,7 @@ func initConfig(fs *pflag.FlagSet) (cfg *Config, err error) {
 		}
 
 		if err == nil {
+			cfg.Request.AllowCommandInterpolation = allowCommand || allowCommandFlag(fs)
 			setupConfig(cfg)
 		}
 	}()
@@ -384,6 +655,7 @@ func initConfig(fs *pflag.FlagSet) (cfg *Config, err error) {
 	if err := v.Unmarshal(&globalCfg); err != nil {
 		return nil, err
 	}
+	allowCommand = globalCfg.Request.AllowCommandInterpolation
 
 	p, found := getLocalConfigPath()
 	if !found {
@@ -410,6 +682,191 @@ func initConfig(fs *pflag.FlagSet) (cfg *Config, err error) {
 	return &mergedCfg, nil
 }
 
+// allowCommandFlag returns the value of --allow-command-interpolation. If fs is nil or doesn't have it,
+// allowCommandFlag returns false.
+func allowCommandFlag(fs *pflag.FlagSet) bool {
+	if fs == nil {
+		return false
+	}
+	f := fs.Lookup("allow-command-interpolation")
+	return f != nil && f.Value.String() == "true"
+}
+
+// profileKeys are top-level keys which profiles can override.
+var profileKeys = []string{"server", "request", "default"}
+
+// profileName returns the profile name specified by --profile. If it is not specified, profileName returns an empty string.
+func profileName(fs *pflag.FlagSet) string {
+	f := fs.Lookup("profile")
+	if f == nil {
+		return ""
+	}
+	return f.Value.String()
+}
+
+// applyProfile merges the profile named name into the loaded config. Profiles take priority over
+// the global and local config, but flags take priority over profiles.
+// If name is empty, applyProfile does nothing.
+func applyProfile(v *viper.Viper, name string) error {
+	if name == "" {
+		return nil
+	}
+	// spf13/viper formats all keys to lower-case. So, profile names are case-insensitive.
+	key := "profiles." + strings.ToLower(name)
+	if !v.IsSet(key) {
+		return errors.Errorf("unknown profile '%s'", name)
+	}
+	p := v.GetStringMap(key)
+	if err := checkProfile(name, p); err != nil {
+		return err
+	}
+	logger.Printf("apply profile '%s'", name)
+	return v.MergeConfigMap(p)
+}
+
+func checkProfile(name string, p map[string]interface{}) error {
+	for k := range p {
+		if !contains(profileKeys, k) {
+			return errors.Errorf("profile '%s' cannot override '%s', only %s are available", name, k, strings.Join(profileKeys, ", "))
+		}
+	}
+	return nil
+}
+
+// ApplyProfile returns a copy of cfg which the profile named name is applied to.
+// Unlike --profile, it is used to switch profiles after the config is loaded.
+func ApplyProfile(cfg *Config, name string) (*Config, error) {
+	profiles, err := Profiles()
+	if err != nil {
+		return nil, err
+	}
+	p, found := profiles[strings.ToLower(name)]
+	if !found {
+		return nil, errors.Errorf("unknown profile '%s'", name)
+	}
+	if err := checkProfile(name, p); err != nil {
+		return nil, err
+	}
+
+	// Values in cfg have already been expanded. So, only values in the profile are expanded.
+	if req, ok := p["request"].(map[string]interface{}); ok {
+		if err := expandProfileRequest(req); err != nil {
+			return nil, errors.Wrapf(err, "failed to apply the profile '%s'", name)
+		}
+	}
+
+	// Decode the profile by spf13/viper to handle values in the same way as config files.
+	v := viper.New()
+	if err := v.MergeConfigMap(p); err != nil {
+		return nil, errors.Wrapf(err, "failed to load the profile '%s'", name)
+	}
+	newCfg := cfg.Clone()
+	if err := v.Unmarshal(newCfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to apply the profile '%s'", name)
+	}
+	// Profiles cannot enable command interpolation.
+	newCfg.Request.AllowCommandInterpolation = cfg.Request.AllowCommandInterpolation
+	logger.Printf("apply profile '%s'", name)
+	return newCfg, nil
+}
+
+// Profiles returns profiles defined in the global and local config files. The key of the returned map
+// is the lower-cased profile name, and the value is the config which the profile overrides.
+func Profiles() (map[string]map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigType("toml")
+
+	var paths []string
+	if p := filepath.Join(xdgbasedir.ConfigHome(), "evans", globalConfigName); fileExists(p) {
+		paths = append(paths, p)
+	}
+	if p, found := getLocalConfigPath(); found {
+		paths = append(paths, p)
+	}
+	for _, p := range paths {
+		if err := mergeConfigFile(v, p); err != nil {
+			return nil, err
+		}
+	}
+
+	profiles := make(map[string]map[string]interface{})
+	for name := range v.GetStringMap("profiles") {
+		profiles[name] = v.GetStringMap("profiles." + name)
+	}
+	return profiles, nil
+}
+
+// MaskProfile returns a copy of profile, which is returned from Profiles, with secrets masked.
+// Secrets are the same as expandProfileRequest: header values and request.auth.clientSecret.
+func MaskProfile(profile map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(profile))
+	for k, v := range profile {
+		masked[k] = v
+	}
+	req, ok := profile["request"].(map[string]interface{})
+	if !ok {
+		return masked
+	}
+
+	maskedReq := make(map[string]interface{}, len(req))
+	for k, v := range req {
+		maskedReq[k] = v
+	}
+	if h, ok := req["header"].(map[string]interface{}); ok {
+		maskedHeader := make(map[string]interface{}, len(h))
+		for k, v := range h {
+			if vs, ok := v.([]interface{}); ok {
+				values := make([]interface{}, len(vs))
+				for i := range vs {
+					values[i] = interpolate.Masked
+				}
+				maskedHeader[k] = values
+				continue
+			}
+			maskedHeader[k] = interpolate.Masked
+		}
+		maskedReq["header"] = maskedHeader
+	}
+	if a, ok := req["auth"].(map[string]interface{}); ok {
+		maskedAuth := make(map[string]interface{}, len(a))
+		for k, v := range a {
+			maskedAuth[k] = v
+		}
+		if _, ok := a["clientsecret"]; ok {
+			maskedAuth["clientsecret"] = interpolate.Masked
+		}
+		maskedReq["auth"] = maskedAuth
+	}
+	masked["request"] = maskedReq
+	return masked
+}
+
+func mergeConfigFile(v *viper.Viper, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open the config file '%s'", p)
+	}
+	defer f.Close()
+	if err := v.MergeConfig(f); err != nil {
+		return errors.Wrapf(err, "failed to load the config file '%s'", p)
+	}
+	return nil
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
 func setupConfig(c *Config) {
 	// To show protofile and protopath field in a config file, set slice which has empty string
 	// if these are nil. (please see default values.)
@@ -421,6 +878,13 @@ func setupConfig(c *Config) {
 		c.Default.ProtoFile = c.Default.ProtoFile[1:]
 	}
 
+	if c.Default.Protoset == nil {
+		c.Default.Protoset = []string{}
+	}
+	if len(c.Default.Protoset) >= 1 && c.Default.Protoset[0] == "" {
+		c.Default.Protoset = c.Default.Protoset[1:]
+	}
+
 	if c.Default.ProtoPath == nil {
 		c.Default.ProtoPath = []string{}
 	}
@@ -469,14 +933,7 @@ func EditGlobal() error {
 	return runEditor(editor, p)
 }
 
-var runEditor = func(editor string, cfgPath string) error {
-	cmd := exec.Command(editor, cfgPath)
-	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
-	if err := cmd.Run(); err != nil {
-		return errors.Wrapf(err, "failed to execute %s", editor)
-	}
-	return nil
-}
+var runEditor = editor.Run
 
 func getLocalConfigPath() (string, bool) {
 	if _, err := os.Stat(localConfigName); err != nil {
@@ -515,6 +972,11 @@ func lookupProjectRootPath() (string, bool) {
 	return p, true
 }
 
+// Editor returns the editor command used by Edit. It returns an empty string if neither $EDITOR nor Vim is found.
+func Editor() string {
+	return getEditor()
+}
+
 func getEditor() string {
 	if env := os.Getenv("EDITOR"); env != "" {
 		return env
@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/interpolate"
 	"github.com/ktr0731/evans/logger"
 	toml "github.com/pelletier/go-toml"
 	"github.com/spf13/pflag"
@@ -293,6 +294,40 @@ func TestLoad(t *testing.T) {
 		return cfg
 	})
 
+	assertWithGolden(t, "apply a profile", func(t *testing.T) *Config {
+		oldCWD := getWorkDir(t)
+
+		cwd, cfgDir, cleanup := setupEnv(t)
+		defer cleanup()
+
+		projDir := filepath.Join(cwd, "local")
+		mkdir(t, projDir)
+
+		// Copy global.toml from testdata to the config dir.
+		copyFile(t, filepath.Join(cfgDir, "config.toml"), filepath.Join(oldCWD, "testdata", "global.toml"))
+		// Copy profiles.toml from testdata to the project dir.
+		// The profile "staging" overrides server, request and default.
+		copyFile(t, filepath.Join(projDir, ".evans.toml"), filepath.Join(oldCWD, "testdata", "profiles.toml"))
+
+		mustChdir(t, projDir)
+		err := exec.Command("git", "init").Run()
+		if err != nil {
+			t.Fatalf("failed to init a pseudo project: %s", err)
+		}
+
+		fs := pflag.NewFlagSet("test", pflag.ExitOnError)
+		fs.String("profile", "", "")
+		fs.String("port", "", "")
+		// --port flag takes priority over the profile.
+		_ = fs.Parse([]string{"--profile", "Staging", "--port", "8443"})
+
+		cfg := mustGet(t, fs)
+
+		checkValues(t, cfg)
+
+		return cfg
+	})
+
 	assertWithGolden(t, "apply some proto files and paths", func(t *testing.T) *Config {
 		_, _, cleanup := setupEnv(t)
 		defer cleanup()
@@ -309,6 +344,292 @@ func TestLoad(t *testing.T) {
 	})
 }
 
+func TestGet_interpolation(t *testing.T) {
+	defer interpolate.Reset()
+	t.Setenv("EVANS_TEST_TOKEN", "secret-token")
+	t.Setenv("EVANS_TEST_CERTS", "/certs")
+
+	newFlagSet := func(header string) *pflag.FlagSet {
+		fs := pflag.NewFlagSet("test", pflag.ExitOnError)
+		fs.StringToString("header", nil, "")
+		fs.String("cacert", "", "")
+		_ = fs.Parse([]string{"--header", header, "--cacert", "${EVANS_TEST_CERTS}/ca.pem"})
+		return fs
+	}
+
+	t.Run("environment variables", func(t *testing.T) {
+		_, _, cleanup := setupEnv(t)
+		defer cleanup()
+
+		cfg := mustGet(t, newFlagSet("authorization=Bearer ${EVANS_TEST_TOKEN}"))
+		if diff := cmp.Diff([]string{"Bearer secret-token"}, cfg.Request.Header["authorization"]); diff != "" {
+			t.Errorf("unexpected header (-want, +got):\n%s", diff)
+		}
+		if cfg.Request.CACertFile != "/certs/ca.pem" {
+			t.Errorf("expected '/certs/ca.pem', but got '%s'", cfg.Request.CACertFile)
+		}
+		if actual := interpolate.Mask("Bearer secret-token"); actual != "****" {
+			t.Errorf("the header value must be masked, but got '%s'", actual)
+		}
+	})
+
+	t.Run("command interpolation is disabled by default", func(t *testing.T) {
+		_, _, cleanup := setupEnv(t)
+		defer cleanup()
+
+		if _, err := Get(newFlagSet("authorization=$(echo token)")); err == nil {
+			t.Errorf("Get must return an error")
+		}
+	})
+
+	allowed := "[request]\nallowCommandInterpolation = true\n"
+	cases := map[string]struct {
+		global, local string
+		flag          bool
+		expected      bool
+	}{
+		"global config":    {global: allowed, expected: true},
+		"flag":             {flag: true, expected: true},
+		"local config":     {local: allowed},
+		"local profile":    {local: "[profiles.dev.request]\nallowCommandInterpolation = true\n"},
+		"global and local": {global: allowed, local: "[request]\nallowCommandInterpolation = false\n", expected: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run("command interpolation enabled by "+name, func(t *testing.T) {
+			cwd, cfgDir, cleanup := setupEnv(t)
+			defer cleanup()
+			defer interpolate.Reset()
+
+			// The local config is loaded only if the global config exists.
+			if err := os.WriteFile(filepath.Join(cfgDir, "config.toml"), []byte(c.global), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if c.local != "" {
+				if err := os.WriteFile(filepath.Join(cwd, ".evans.toml"), []byte(c.local), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := exec.Command("git", "init").Run(); err != nil {
+					t.Fatalf("failed to init a pseudo project: %s", err)
+				}
+			}
+
+			fs := newFlagSet("authorization=$(echo token)")
+			fs.Bool("allow-command-interpolation", false, "")
+			fs.String("profile", "", "")
+			args := []string{}
+			if c.flag {
+				args = append(args, "--allow-command-interpolation")
+			}
+			if strings.Contains(c.local, "profiles.dev") {
+				args = append(args, "--profile", "dev")
+			}
+			_ = fs.Parse(args)
+
+			cfg, err := Get(fs)
+			if !c.expected {
+				if err == nil || !strings.Contains(err.Error(), "disabled") {
+					t.Errorf("Get must return an error because command interpolation is disabled, but got '%v'", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff([]string{"token"}, cfg.Request.Header["authorization"]); diff != "" {
+				t.Errorf("unexpected header (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidate_auth(t *testing.T) {
+	cases := map[string]struct {
+		auth         *Auth
+		tls          bool
+		allowCommand bool
+		hasErr       bool
+	}{
+		"token file":             {auth: &Auth{TokenFile: "token"}, tls: true},
+		"oauth2":                 {auth: &Auth{TokenURL: "http://localhost/token", ClientID: "evans"}, tls: true},
+		"oauth2 without id":      {auth: &Auth{TokenURL: "http://localhost/token"}, tls: true, hasErr: true},
+		"multiple sources":       {auth: &Auth{TokenFile: "token", TokenURL: "http://localhost/token", ClientID: "evans"}, tls: true, hasErr: true},
+		"command":                {auth: &Auth{Command: "echo token"}, tls: true, allowCommand: true},
+		"command not allowed":    {auth: &Auth{Command: "echo token"}, tls: true, hasErr: true},
+		"without TLS":            {auth: &Auth{TokenFile: "token"}, hasErr: true},
+		"plaintext allowed":      {auth: &Auth{TokenFile: "token", AllowPlaintext: true}},
+		"no sources without TLS": {auth: &Auth{Scopes: []string{"read"}}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{
+				Default: &Default{ProtoFile: []string{"api.proto"}, SourcePriority: SourcePriorityReflection},
+				Server:  &Server{Port: "50051", TLS: c.tls},
+				Request: &Request{Auth: c.auth, AllowCommandInterpolation: c.allowCommand},
+			}
+			err := cfg.Validate()
+			if c.hasErr && err == nil {
+				t.Errorf("Validate must return an error, but got nil")
+			}
+			if !c.hasErr && err != nil {
+				t.Errorf("Validate must not return an error, but got '%s'", err)
+			}
+		})
+	}
+}
+
+func TestValidate_tls(t *testing.T) {
+	cases := map[string]struct {
+		req    *Request
+		tls    bool
+		hasErr bool
+	}{
+		"insecure":                   {req: &Request{Insecure: true}, tls: true},
+		"insecure without TLS":       {req: &Request{Insecure: true}, hasErr: true},
+		"min version":                {req: &Request{TLSMinVersion: "1.3"}, tls: true},
+		"min version without TLS":    {req: &Request{TLSMinVersion: "1.3"}, hasErr: true},
+		"cipher suites":              {req: &Request{CipherSuites: []string{"TLS_RSA_WITH_AES_128_GCM_SHA256"}}, tls: true},
+		"cipher suites without TLS":  {req: &Request{CipherSuites: []string{"TLS_RSA_WITH_AES_128_GCM_SHA256"}}, hasErr: true},
+		"no TLS options without TLS": {req: &Request{}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{
+				Default: &Default{ProtoFile: []string{"api.proto"}, SourcePriority: SourcePriorityReflection},
+				Server:  &Server{Port: "50051", TLS: c.tls},
+				Request: c.req,
+			}
+			err := cfg.Validate()
+			if c.hasErr && err == nil {
+				t.Errorf("Validate must return an error, but got nil")
+			}
+			if !c.hasErr && err != nil {
+				t.Errorf("Validate must not return an error, but got '%s'", err)
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	oldCWD := getWorkDir(t)
+
+	cwd, cfgDir, cleanup := setupEnv(t)
+	defer cleanup()
+
+	projDir := filepath.Join(cwd, "local")
+	mkdir(t, projDir)
+	copyFile(t, filepath.Join(cfgDir, "config.toml"), filepath.Join(oldCWD, "testdata", "global.toml"))
+	copyFile(t, filepath.Join(projDir, ".evans.toml"), filepath.Join(oldCWD, "testdata", "profiles.toml"))
+	mustChdir(t, projDir)
+	if err := exec.Command("git", "init").Run(); err != nil {
+		t.Fatalf("failed to init a pseudo project: %s", err)
+	}
+
+	profiles, err := Profiles()
+	if err != nil {
+		t.Fatalf("Profiles must not return an error, but got '%s'", err)
+	}
+	expected := map[string]map[string]interface{}{
+		"staging": {
+			"server":  map[string]interface{}{"host": "staging.example.com", "port": "443", "tls": true},
+			"request": map[string]interface{}{"cacertfile": "staging-ca.pem", "header": map[string]interface{}{"authorization": "Bearer staging"}},
+			"default": map[string]interface{}{"package": "api"},
+		},
+		"prod":   {"server": map[string]interface{}{"host": "prod.example.com"}},
+		"broken": {"repl": map[string]interface{}{"silent": true}},
+	}
+	if diff := cmp.Diff(expected, profiles); diff != "" {
+		t.Errorf("unexpected profiles (-want, +got):\n%s", diff)
+	}
+
+	for name, profile := range map[string]string{
+		"unknown profile":         "dev",
+		"profile overriding repl": "broken",
+	} {
+		fs := pflag.NewFlagSet("test", pflag.ExitOnError)
+		fs.String("profile", "", "")
+		_ = fs.Parse([]string{"--profile", profile})
+		if _, err := Get(fs); err == nil {
+			t.Errorf("%s: Get must return an error", name)
+		}
+		if _, err := ApplyProfile(&Config{}, profile); err == nil {
+			t.Errorf("%s: ApplyProfile must return an error", name)
+		}
+	}
+
+	cfg := &Config{
+		Default: &Default{ProtoFile: []string{"api.proto"}},
+		Server:  &Server{Host: "localhost", Port: "50051"},
+		Request: &Request{Header: Header{"grpc-client": {"evans"}}},
+	}
+	newCfg, err := ApplyProfile(cfg, "Staging")
+	if err != nil {
+		t.Fatalf("ApplyProfile must not return an error, but got '%s'", err)
+	}
+	expectedCfg := &Config{
+		Default: &Default{ProtoFile: []string{"api.proto"}, Package: "api"},
+		Server:  &Server{Host: "staging.example.com", Port: "443", TLS: true},
+		Request: &Request{
+			Header:     Header{"grpc-client": {"evans"}, "authorization": {"Bearer staging"}},
+			CACertFile: "staging-ca.pem",
+		},
+	}
+	if diff := cmp.Diff(expectedCfg, newCfg); diff != "" {
+		t.Errorf("unexpected config (-want, +got):\n%s", diff)
+	}
+	if cfg.Server.Host != "localhost" || len(cfg.Request.Header) != 1 {
+		t.Errorf("ApplyProfile must not modify the passed config")
+	}
+}
+
+func TestMaskProfile(t *testing.T) {
+	profile := map[string]interface{}{
+		"server": map[string]interface{}{"host": "staging.example.com"},
+		"request": map[string]interface{}{
+			"cacertfile": "staging-ca.pem",
+			"header": map[string]interface{}{
+				"authorization": "Bearer staging",
+				"x-keys":        []interface{}{"a", "b"},
+			},
+			"auth": map[string]interface{}{
+				"tokenurl":     "https://auth.example.com/token",
+				"clientid":     "evans",
+				"clientsecret": "secret",
+			},
+		},
+	}
+	expected := map[string]interface{}{
+		"server": map[string]interface{}{"host": "staging.example.com"},
+		"request": map[string]interface{}{
+			"cacertfile": "staging-ca.pem",
+			"header": map[string]interface{}{
+				"authorization": "****",
+				"x-keys":        []interface{}{"****", "****"},
+			},
+			"auth": map[string]interface{}{
+				"tokenurl":     "https://auth.example.com/token",
+				"clientid":     "evans",
+				"clientsecret": "****",
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, MaskProfile(profile)); diff != "" {
+		t.Errorf("unexpected profile (-want, +got):\n%s", diff)
+	}
+
+	req := profile["request"].(map[string]interface{})
+	if req["header"].(map[string]interface{})["authorization"] != "Bearer staging" || req["auth"].(map[string]interface{})["clientsecret"] != "secret" {
+		t.Errorf("MaskProfile must not modify the passed profile")
+	}
+
+	noRequest := map[string]interface{}{"server": map[string]interface{}{"host": "prod.example.com"}}
+	if diff := cmp.Diff(noRequest, MaskProfile(noRequest)); diff != "" {
+		t.Errorf("unexpected profile (-want, +got):\n%s", diff)
+	}
+}
+
 func TestEdit(t *testing.T) {
 	cases := map[string]struct {
 		outsideGitRepo bool
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -15,6 +17,7 @@ import (
 	"github.com/ktr0731/evans/cui"
 	"github.com/ktr0731/evans/meta"
 	"github.com/ktr0731/evans/mode"
+	"github.com/ktr0731/evans/proto"
 	"github.com/ktr0731/evans/usecase"
 	"github.com/pkg/errors"
 )
@@ -23,6 +26,8 @@ var replacer = regexp.MustCompile(`access-control-expose-headers: .*\n`)
 
 func TestE2E_CLI(t *testing.T) {
 	commonFlags := []string{"--verbose"}
+	updatedGoldenFile := filepath.Join(t.TempDir(), "golden.json")
+	updatedFailureGoldenFile := filepath.Join(t.TempDir(), "failure_golden.json")
 
 	cases := map[string]struct {
 		// Common flags all sub-commands can have.
@@ -55,6 +60,8 @@ func TestE2E_CLI(t *testing.T) {
 
 		// The output we expected. It is ignored if expectedCode isn't 0.
 		expectedOut string
+		// The error output we expected. It is ignored if expectedCode isn't 0.
+		expectedErrOut string
 		// assertWithGolden asserts the output with the golden file.
 		assertWithGolden bool
 
@@ -92,10 +99,6 @@ func TestE2E_CLI(t *testing.T) {
 			args:         "--cli --repl",
 			expectedCode: 1,
 		},
-		"cannot specify both of --tls and --web": {
-			args:         "--web --tls testdata/test.proto",
-			expectedCode: 1,
-		},
 		"cannot launch without proto files and reflection": {
 			args:         "",
 			expectedCode: 1,
@@ -220,6 +223,61 @@ func TestE2E_CLI(t *testing.T) {
 			args:        "--file testdata/client_streaming.in api.Example.ClientStreaming",
 			expectedOut: `{ "message": "you sent requests 4 times (oumae, kousaka, kawashima, kato)." }`,
 		},
+		"call unary RPC with a protoset file": {
+			commonFlags: "--protoset testdata/test.protoset",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC with a JSON protoset file": {
+			commonFlags: "--protoset testdata/test.protoset.json",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"cannot launch because the protoset file is missing": {
+			commonFlags:  "--protoset testdata/foo.protoset",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"cannot launch because both of proto files and protoset files are passed": {
+			commonFlags:  "--proto testdata/test.proto --protoset testdata/test.protoset",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"call unary RPC with a textproto input file": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.txtpb api.Example.Unary",
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC with --input-format textproto": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--input-format textproto api.Example.Unary",
+			beforeTest: func(t *testing.T) func(*testing.T) {
+				old := mode.DefaultCLIReader
+				mode.DefaultCLIReader = strings.NewReader(`name: "oumae"`)
+				return func(t *testing.T) {
+					mode.DefaultCLIReader = old
+				}
+			},
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call client streaming RPC with a YAML input file": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/client_streaming.yaml api.Example.ClientStreaming",
+			expectedOut: `{ "message": "you sent requests 4 times (oumae, kousaka, kawashima, kato)." }`,
+		},
+		"cannot call with an unknown input format": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--input-format xml --file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
 		"call server streaming RPC": {
 			commonFlags: "--proto testdata/test.proto",
 			cmd:         "call",
@@ -266,6 +324,59 @@ func TestE2E_CLI(t *testing.T) {
 		// 	},
 		// },
 
+		// call command with per-RPC credentials.
+
+		"call unary RPC with a token file": {
+			commonFlags: "--auth-allow-plaintext --auth-token-file testdata/auth_token --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_header.in api.Example.UnaryHeader",
+			assertTest:  assertAuthorization("Bearer e2e-token"),
+		},
+		"call unary RPC with a token file by gRPC reflection": {
+			commonFlags: "--auth-allow-plaintext --auth-token-file testdata/auth_token -r",
+			cmd:         "call",
+			args:        "--file testdata/unary_header.in api.Example.UnaryHeader",
+			reflection:  true,
+			assertTest:  assertAuthorization("Bearer e2e-token"),
+		},
+		"call unary RPC with a token file against to gRPC-Web server": {
+			commonFlags: "--web --auth-allow-plaintext --auth-token-file testdata/auth_token --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_header.in api.Example.UnaryHeader",
+			web:         true,
+			assertTest:  assertAuthorization("Bearer e2e-token"),
+		},
+		"cannot call unary RPC because the token file is missing": {
+			commonFlags:  "--auth-allow-plaintext --auth-token-file testdata/missing_token --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_header.in api.Example.UnaryHeader",
+			expectedCode: 1,
+		},
+		"cannot send a token without TLS": {
+			commonFlags:  "--auth-token-file testdata/auth_token --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_header.in api.Example.UnaryHeader",
+			expectedCode: 1,
+		},
+		"cannot call unary RPC with --auth-command without --allow-command-interpolation": {
+			commonFlags:  "--auth-allow-plaintext --auth-command testdata/auth_command.sh --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_header.in api.Example.UnaryHeader",
+			expectedCode: 1,
+		},
+		"call unary RPC with --auth-command": {
+			commonFlags: "--auth-allow-plaintext --allow-command-interpolation --auth-command testdata/auth_command.sh --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_header.in api.Example.UnaryHeader",
+			assertTest:  assertAuthorization("Bearer e2e-token"),
+		},
+		"cannot specify both of --auth-token-file and --auth-command": {
+			commonFlags:  "--auth-token-file testdata/auth_token --auth-command true --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_header.in api.Example.UnaryHeader",
+			expectedCode: 1,
+		},
+
 		// call command with timeout header.
 
 		"unary call timed out": {
@@ -360,6 +471,13 @@ func TestE2E_CLI(t *testing.T) {
 			reflection:  true,
 			expectedOut: `{ "message": "oumae" }`,
 		},
+		"call unary RPC with reflection ignoring the descriptor cache": {
+			commonFlags: "--reflection --refresh-reflection",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			reflection:  true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
 
 		// call command with TLS
 
@@ -398,6 +516,53 @@ func TestE2E_CLI(t *testing.T) {
 			tls:         true,
 			expectedOut: `{ "message": "oumae" }`,
 		},
+		"call unary RPC with TLS and --insecure": {
+			commonFlags:    "--tls --insecure --proto testdata/test.proto",
+			cmd:            "call",
+			args:           "--file testdata/unary_call.in api.Example.Unary",
+			tls:            true,
+			expectedOut:    `{ "message": "oumae" }`,
+			expectedErrOut: "WARNING: the verification of the server certificate is disabled by --insecure. the connection is vulnerable to man-in-the-middle attacks.\n",
+		},
+		"cannot launch with --insecure without TLS": {
+			commonFlags:  "--insecure --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"cannot launch with --tls-min-version without TLS": {
+			commonFlags:  "--tls-min-version 1.3 --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"cannot launch with --tls-cipher-suites without TLS": {
+			commonFlags:  "--tls-cipher-suites TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"call unary RPC with TLS and --tls-min-version": {
+			commonFlags: "--tls --host localhost --cacert testdata/rootCA.pem --tls-min-version 1.3 --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			tls:         true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"cannot launch with TLS because the TLS version is unknown": {
+			commonFlags:  "--tls --host localhost --cacert testdata/rootCA.pem --tls-min-version 2.0 --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			tls:          true,
+			expectedCode: 1,
+		},
+		"cannot launch with TLS because the cipher suite is unknown": {
+			commonFlags:  "--tls --host localhost --cacert testdata/rootCA.pem --tls-cipher-suites foo --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			tls:          true,
+			expectedCode: 1,
+		},
 		"cannot launch with TLS and reflection because server didn't enable TLS": {
 			commonFlags:  "--tls -r --host localhost --cacert testdata/rootCA.pem",
 			cmd:          "call",
@@ -445,63 +610,126 @@ func TestE2E_CLI(t *testing.T) {
 
 		// call command with gRPC-Web
 
-		// TODO: Re-enable after fixing gRPC-Web nil pointer panic
-		// "cannot send a request to gRPC-Web server because the server didn't enable gRPC-Web": {
-		// 	commonFlags:  "--web --proto testdata/test.proto",
-		// 	cmd:          "call",
-		// 	args:         "--file testdata/unary_call.in api.Example.Unary",
-		// 	web:          false,
-		// 	expectedCode: 1,
-		// },
-		// TODO: Re-enable after fixing gRPC-Web nil pointer panics
-		// "call unary RPC with an input file against to gRPC-Web server": {
-		// 	commonFlags: "--web --proto testdata/test.proto",
-		// 	cmd:         "call",
-		// 	args:        "--file testdata/unary_call.in api.Example.Unary",
-		// 	web:         true,
-		// 	expectedOut: `{ "message": "oumae" }`,
-		// },
-		// "call unary RPC with an input file and reflection against to gRPC-Web server": {
-		// 	commonFlags: "--web -r",
-		// 	cmd:         "call",
-		// 	args:        "--file testdata/unary_call.in api.Example.Unary",
-		// 	web:         true,
-		// 	reflection:  true,
-		// 	expectedOut: `{ "message": "oumae" }`,
-		// },
-		// "call client streaming RPC against to gRPC-Web server": {
-		// 	commonFlags: "--web --proto testdata/test.proto",
-		// 	cmd:         "call",
-		// 	args:        "--file testdata/client_streaming.in api.Example.ClientStreaming",
-		// 	web:         true,
-		// 	expectedOut: `{ "message": "you sent requests 4 times (oumae, kousaka, kawashima, kato)." }`,
-		// },
-		// "call server streaming RPC against to gRPC-Web server": {
-		// 	commonFlags: "--web --proto testdata/test.proto",
-		// 	cmd:         "call",
-		// 	args:        "--file testdata/server_streaming.in api.Example.ServerStreaming",
-		// 	web:         true,
-		// 	expectedOut: `{ "message": "hello oumae, I greet 1 times." } { "message": "hello oumae, I greet 2 times." } { "message": "hello oumae, I greet 3 times." }`,
-		// },
-		// "call bidi streaming RPC against to gRPC-Web server": {
-		// 	commonFlags: "--web --proto testdata/test.proto",
-		// 	cmd:         "call",
-		// 	args:        "--file testdata/bidi_streaming.in api.Example.BidiStreaming",
-		// 	web:         true,
-		// 	assertTest: func(t *testing.T, output string) {
-		// 		dec := json.NewDecoder(strings.NewReader(output))
-		// 		for {
-		// 			var iface interface{}
-		// 			err := dec.Decode(&iface)
-		// 			if errors.Is(err, io.EOF) {
-		// 				return
-		// 			}
-		// 			if err != nil {
-		// 				t.Errorf("expected no errors, but got '%s'", err)
-		// 			}
-		// 		}
-		// 	},
-		// },
+		"cannot send a request to gRPC-Web server because the server didn't enable gRPC-Web": {
+			commonFlags:  "--web --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			web:          false,
+			expectedCode: 1,
+		},
+		"call unary RPC with an input file against to gRPC-Web server": {
+			commonFlags: "--web --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			web:         true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC with an input file and reflection against to gRPC-Web server": {
+			commonFlags: "--web -r",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			web:         true,
+			reflection:  true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call client streaming RPC against to gRPC-Web server": {
+			commonFlags: "--web --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/client_streaming.in api.Example.ClientStreaming",
+			web:         true,
+			expectedOut: `{ "message": "you sent requests 4 times (oumae, kousaka, kawashima, kato)." }`,
+		},
+		"call server streaming RPC against to gRPC-Web server": {
+			commonFlags: "--web --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/server_streaming.in api.Example.ServerStreaming",
+			web:         true,
+			expectedOut: `{ "message": "hello oumae, I greet 1 times." } { "message": "hello oumae, I greet 2 times." } { "message": "hello oumae, I greet 3 times." }`,
+		},
+		"call bidi streaming RPC against to gRPC-Web server": {
+			commonFlags: "--web --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/bidi_streaming.in api.Example.BidiStreaming",
+			web:         true,
+			assertTest: func(t *testing.T, output string) {
+				dec := json.NewDecoder(strings.NewReader(output))
+				for {
+					var iface interface{}
+					err := dec.Decode(&iface)
+					if errors.Is(err, io.EOF) {
+						return
+					}
+					if err != nil {
+						t.Errorf("expected no errors, but got '%s'", err)
+					}
+				}
+			},
+		},
+		"call unary RPC against to gRPC-Web server with TLS": {
+			commonFlags: "--web --tls --host localhost --cacert testdata/rootCA.pem --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			web:         true,
+			tls:         true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC against to gRPC-Web server with TLS and --servername": {
+			commonFlags: "--web --tls --servername localhost --cacert testdata/rootCA.pem --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			web:         true,
+			tls:         true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call client streaming RPC against to gRPC-Web server with TLS and --servername": {
+			commonFlags: "--web --tls --servername localhost --cacert testdata/rootCA.pem --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/client_streaming.in api.Example.ClientStreaming",
+			web:         true,
+			tls:         true,
+			expectedOut: `{ "message": "you sent requests 4 times (oumae, kousaka, kawashima, kato)." }`,
+		},
+		"call unary RPC with reflection against to gRPC-Web server with TLS": {
+			commonFlags: "--web --tls -r --host localhost --cacert testdata/rootCA.pem",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			web:         true,
+			tls:         true,
+			reflection:  true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call server streaming RPC against to gRPC-Web server with TLS": {
+			commonFlags: "--web --tls --host localhost --cacert testdata/rootCA.pem --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/server_streaming.in api.Example.ServerStreaming",
+			web:         true,
+			tls:         true,
+			expectedOut: `{ "message": "hello oumae, I greet 1 times." } { "message": "hello oumae, I greet 2 times." } { "message": "hello oumae, I greet 3 times." }`,
+		},
+		"call unary RPC against to gRPC-Web server with mutual TLS auth": {
+			commonFlags: "--web --tls --host localhost --cacert testdata/rootCA.pem --cert testdata/localhost.pem --certkey testdata/localhost-key.pem --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			web:         true,
+			tls:         true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"cannot send a request to gRPC-Web server with TLS because signed authority is unknown": {
+			commonFlags:  "--web --tls --host localhost --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			web:          true,
+			tls:          true,
+			expectedCode: 1,
+		},
+		"cannot send a request to gRPC-Web server because the client didn't enable TLS": {
+			commonFlags:  "--web --host localhost --proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			web:          true,
+			tls:          true,
+			expectedCode: 1,
+		},
 		"call unary RPC with --enrich flag": {
 			commonFlags:      "-r",
 			cmd:              "call",
@@ -518,6 +746,78 @@ func TestE2E_CLI(t *testing.T) {
 			unflatten:        true,
 			assertWithGolden: true,
 		},
+		"call unary RPC with --enrich flag and NDJSON format": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.in --enrich --output ndjson api.Example.UnaryHeaderTrailer",
+			reflection:       true,
+			unflatten:        true,
+			assertWithGolden: true,
+		},
+		"call server streaming RPC with YAML format": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--output yaml --file testdata/server_streaming.in api.Example.ServerStreaming",
+			unflatten:   true,
+			expectedOut: `message: hello oumae, I greet 1 times.
+---
+message: hello oumae, I greet 2 times.
+---
+message: hello oumae, I greet 3 times.
+`,
+		},
+		"call server streaming RPC with textproto format": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--output textproto --file testdata/server_streaming.in api.Example.ServerStreaming",
+			unflatten:   true,
+			assertTest: func(t *testing.T, output string) {
+				msgs := strings.Split(output, "---\n")
+				if len(msgs) != 3 {
+					t.Fatalf("expected 3 messages, but got %d: %q", len(msgs), output)
+				}
+				for i, msg := range msgs {
+					expected := fmt.Sprintf(`"hello oumae, I greet %d times."`, i+1)
+					if !strings.Contains(msg, expected) {
+						t.Errorf("expected to contain '%s', but missing in '%s'", expected, msg)
+					}
+				}
+			},
+		},
+		"call unary RPC with binary format": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--output binary --file testdata/unary_call.in api.Example.Unary",
+			unflatten:   true,
+			// The field 1 (tag 0x0a) of length 5 without the length prefix, so that protoc --decode can read it.
+			expectedOut: "\x0a\x05oumae",
+		},
+		"call server streaming RPC with binary format": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--output binary --file testdata/server_streaming.in api.Example.ServerStreaming",
+			unflatten:   true,
+			// Each message is prefixed by its length (31).
+			expectedOut: "\x1f\x0a\x1dhello oumae, I greet 1 times." +
+				"\x1f\x0a\x1dhello oumae, I greet 2 times." +
+				"\x1f\x0a\x1dhello oumae, I greet 3 times.",
+		},
+		"cannot call with an unknown output format": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--output xml --file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"call server streaming RPC with NDJSON format": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--output jsonl --file testdata/server_streaming.in api.Example.ServerStreaming",
+			unflatten:   true,
+			expectedOut: `{"message":{"message":"hello oumae, I greet 1 times."}}
+{"message":{"message":"hello oumae, I greet 2 times."}}
+{"message":{"message":"hello oumae, I greet 3 times."}}
+`,
+		},
 		// TODO: Re-enable after updating golden files for improved grpc-status-details-bin output
 		// "call failure unary RPC with --enrich flag": {
 		// 	commonFlags:      "-r",
@@ -537,26 +837,282 @@ func TestE2E_CLI(t *testing.T) {
 		// 	assertWithGolden: true,
 		// 	expectedCode:     1,
 		// },
-		// TODO: Re-enable after fixing gRPC-Web nil pointer panics
-		// "call unary RPC with --enrich flag against to gRPC-Web server": {
-		// 	commonFlags:      "--web -r",
-		// 	cmd:              "call",
-		// 	args:             "--file testdata/unary_call.in --enrich api.Example.UnaryHeaderTrailer",
-		// 	web:              true,
-		// 	reflection:       true,
-		// 	unflatten:        true,
-		// 	assertWithGolden: true,
-		// },
-		// "call failure unary RPC with --enrich flag against to gRPC-Web server": {
-		// 	commonFlags:      "--web -r",
-		// 	cmd:              "call",
-		// 	args:             "--file testdata/unary_call.in --enrich api.Example.UnaryHeaderTrailerFailure",
-		// 	web:              true,
-		// 	reflection:       true,
-		// 	unflatten:        true,
-		// 	assertWithGolden: true,
-		// 	expectedCode:     1,
-		// },
+		"call unary RPC with --timeout and --enrich flags exceeding the deadline": {
+			commonFlags:      "--proto testdata/test.proto",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.in --timeout 1ns --enrich api.Example.Unary",
+			unflatten:        true,
+			assertWithGolden: true,
+			expectedCode:     1,
+		},
+		"call server streaming RPC with --timeout exceeding the deadline": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/server_streaming.in --timeout 1ns api.Example.ServerStreaming",
+			expectedCode: 1,
+		},
+		"call unary RPC with --timeout": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in --timeout 10s api.Example.Unary",
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC with --expect-code and --expect-json": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        `--file testdata/unary_call.in --expect-code OK --expect-json {"message":"oumae"} api.Example.Unary`,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC with --expect-json which doesn't match": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         `--file testdata/unary_call.in --expect-json {"message":"kousaka"} api.Example.Unary`,
+			expectedOut:  `{ "message": "oumae" }`,
+			expectedCode: 2,
+		},
+		"call server streaming RPC with --expect-json": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/server_streaming.in --expect-json @testdata/golden/server_streaming_expect.json api.Example.ServerStreaming",
+			expectedOut: `{ "message": "hello oumae, I greet 1 times." } { "message": "hello oumae, I greet 2 times." } { "message": "hello oumae, I greet 3 times." }`,
+		},
+		"call failure unary RPC with --expect-code": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in --expect-code INTERNAL api.Example.UnaryHeaderTrailerFailure",
+		},
+		"call unary RPC with --expect-code which doesn't match": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in --expect-code NotFound api.Example.Unary",
+			expectedOut:  `{ "message": "oumae" }`,
+			expectedCode: 2,
+		},
+		"call unary RPC with --golden": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in --golden testdata/golden/unary.json api.Example.Unary",
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC with --golden which doesn't match": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in --golden testdata/golden/unary_mismatch.json api.Example.Unary",
+			expectedOut:  `{ "message": "oumae" }`,
+			expectedCode: 2,
+		},
+		"call unary RPC with --golden and --ignore-fields": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in --golden testdata/golden/unary_mismatch.json --ignore-fields message api.Example.Unary",
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC with --golden and --update": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        fmt.Sprintf("--file testdata/unary_call.in --golden %s --update api.Example.Unary", updatedGoldenFile),
+			beforeTest: func(t *testing.T) func(*testing.T) {
+				os.Remove(updatedGoldenFile)
+				return func(t *testing.T) {
+					defer os.Remove(updatedGoldenFile)
+					actual, err := os.ReadFile(updatedGoldenFile)
+					if err != nil {
+						t.Fatalf("the golden file must be written, but got an error: %s", err)
+					}
+					expected, err := os.ReadFile("testdata/golden/unary.json")
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(string(expected), string(actual)); diff != "" {
+						t.Errorf("unexpected golden file (-want, +got):\n%s", diff)
+					}
+				}
+			},
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call failure unary RPC with --golden": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in --golden testdata/golden/unary_failure.json api.Example.UnaryHeaderTrailerFailure",
+		},
+		"call failure unary RPC with --golden and --update": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        fmt.Sprintf("--file testdata/unary_call.in --golden %s --update api.Example.UnaryHeaderTrailerFailure", updatedFailureGoldenFile),
+			beforeTest: func(t *testing.T) func(*testing.T) {
+				os.Remove(updatedFailureGoldenFile)
+				return func(t *testing.T) {
+					defer os.Remove(updatedFailureGoldenFile)
+					actual, err := os.ReadFile(updatedFailureGoldenFile)
+					if err != nil {
+						t.Fatalf("the golden file must be written, but got an error: %s", err)
+					}
+					expected, err := os.ReadFile("testdata/golden/unary_failure.json")
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(string(expected), string(actual)); diff != "" {
+						t.Errorf("unexpected golden file (-want, +got):\n%s", diff)
+					}
+				}
+			},
+		},
+		"call failure unary RPC with --golden which has the OK status": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in --golden testdata/golden/unary.json api.Example.UnaryHeaderTrailerFailure",
+			expectedCode: 2,
+		},
+		"cannot call unary RPC with --update but without --golden": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in --update api.Example.Unary",
+			expectedCode: 1,
+		},
+		"call unary RPC with --enrich flag against to gRPC-Web server": {
+			commonFlags:      "--web -r",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.in --enrich api.Example.UnaryHeaderTrailer",
+			web:              true,
+			reflection:       true,
+			unflatten:        true,
+			assertWithGolden: true,
+		},
+		"call failure unary RPC with --enrich flag against to gRPC-Web server": {
+			commonFlags:      "--web -r",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.in --enrich api.Example.UnaryHeaderTrailerFailure",
+			web:              true,
+			reflection:       true,
+			unflatten:        true,
+			assertWithGolden: true,
+			expectedCode:     1,
+		},
+
+		// bench command
+		"print bench command usage": {
+			commonFlags:      "",
+			cmd:              "bench",
+			args:             "-h",
+			assertWithGolden: true,
+		},
+		"bench unary RPC": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "bench",
+			args:        "--file testdata/unary_call.in -n 20 -c 4 -o json api.Example.Unary",
+			assertTest: func(t *testing.T, output string) {
+				res := decodeBenchResult(t, output)
+				if res.Requests != 20 || res.ServerStreaming {
+					t.Errorf("unexpected result: %+v", res)
+				}
+				if diff := cmp.Diff(map[string]int{"OK": 20}, res.StatusCodes); diff != "" {
+					t.Errorf("unexpected status codes (-want, +got):\n%s", diff)
+				}
+			},
+		},
+		"bench unary RPC with text output": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "bench",
+			args:        "--file testdata/unary_call.in -n 5 api.Example.Unary",
+			unflatten:   true,
+			assertTest: func(t *testing.T, output string) {
+				for _, re := range []string{`Requests:\s+5\n`, `Latency:\n`, `Histogram:\n`, `OK:\s+5\n`} {
+					if !regexp.MustCompile(re).MatchString(output) {
+						t.Errorf("output must match %q, but got:\n%s", re, output)
+					}
+				}
+			},
+		},
+		"bench unary RPC with --duration and --rate": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "bench",
+			args:        "--file testdata/unary_call.in -d 200ms -c 2 --rate 20 -o json api.Example.Unary",
+			assertTest: func(t *testing.T, output string) {
+				res := decodeBenchResult(t, output)
+				if res.Requests == 0 || res.Requests > 10 {
+					t.Errorf("the number of requests must be limited by the rate, but got %d", res.Requests)
+				}
+			},
+		},
+		"bench server streaming RPC": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "bench",
+			args:        "--file testdata/server_streaming.in -n 4 -c 2 -o json api.Example.ServerStreaming",
+			assertTest: func(t *testing.T, output string) {
+				res := decodeBenchResult(t, output)
+				if res.Requests != 4 || !res.ServerStreaming || res.Messages != 12 {
+					t.Errorf("unexpected result: %+v", res)
+				}
+			},
+		},
+		"cannot bench client streaming RPC": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "bench",
+			args:         "--file testdata/client_streaming.in api.Example.ClientStreaming",
+			expectedCode: 1,
+		},
+
+		// run command
+		"print run command usage": {
+			commonFlags:      "",
+			cmd:              "run",
+			args:             "-h",
+			assertWithGolden: true,
+		},
+		"run YAML script": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "run",
+			args:        "testdata/script.yaml",
+			unflatten:   true,
+			assertTest: func(t *testing.T, output string) {
+				for _, s := range []string{
+					"{\n  \"message\": \"ktr\"\n}\n{\n  \"message\": \"ktr-2\"\n}\n",
+					"key = x-token, val = trailer_val1-ktr-2",
+				} {
+					if !strings.Contains(output, s) {
+						t.Errorf("output must contain %q, but got:\n%s", s, output)
+					}
+				}
+			},
+			// Banners are written to stderr to keep the output parsable.
+			expectedErrOut: "=== first: api.Example.Unary\n\n=== second: api.Example.Unary\n\n=== step3: api.Example.UnaryHeader\n",
+		},
+		"run TOML script with --enrich flag": {
+			commonFlags:      "--proto testdata/test.proto",
+			cmd:              "run",
+			args:             "--enrich testdata/script.toml",
+			unflatten:        true,
+			assertWithGolden: true,
+			expectedErrOut:   "=== first: api.Example.Unary\n\n=== stream: api.Example.ClientStreaming\n",
+		},
+		"run script that fails": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "run",
+			args:         "testdata/script_failure.yaml",
+			unflatten:    true,
+			expectedOut:  "=== failure: api.Example.UnaryHeaderTrailerFailure\n",
+			expectedCode: 1,
+		},
+		"cannot run script if the script file does not exist": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "run",
+			args:         "testdata/not_found.yaml",
+			expectedCode: 1,
+		},
+
+		// profiles command
+		"print profiles command usage": {
+			commonFlags:      "",
+			cmd:              "profiles",
+			args:             "-h",
+			assertWithGolden: true,
+		},
+		"cannot call with an unknown profile": {
+			commonFlags:  "--proto testdata/test.proto --profile unknown",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
 
 		// list command
 		"print list command usage": {
@@ -577,6 +1133,34 @@ func TestE2E_CLI(t *testing.T) {
 			args:        "",
 			expectedOut: `EmptyPackageService api.Example`,
 		},
+		"list services with gRPC reflection": {
+			reflection:  true,
+			commonFlags: "--reflection",
+			cmd:         "list",
+			args:        "",
+			expectedOut: `api.Example`,
+		},
+		"list services with gRPC reflection against to gRPC-Web server": {
+			reflection:  true,
+			web:         true,
+			commonFlags: "--reflection --web",
+			cmd:         "list",
+			args:        "",
+			expectedOut: `api.Example`,
+		},
+		"list services with gRPC reflection and proto files": {
+			reflection:  true,
+			commonFlags: "--reflection --proto testdata/empty_package.proto",
+			cmd:         "list",
+			args:        "",
+			expectedOut: `EmptyPackageService api.Example`,
+		},
+		"list services with a protoset file": {
+			commonFlags: "--protoset testdata/test.protoset",
+			cmd:         "list",
+			args:        "",
+			expectedOut: `api.Example`,
+		},
 		"list services with name format": {
 			commonFlags: "--proto testdata/test.proto",
 			cmd:         "list",
@@ -682,6 +1266,103 @@ func TestE2E_CLI(t *testing.T) {
 			args:         "api.Foo",
 			expectedCode: 1,
 		},
+
+		// skeleton command
+
+		"print skeleton command usage": {
+			commonFlags:      "",
+			cmd:              "skeleton",
+			args:             "-h",
+			assertWithGolden: true,
+		},
+		"print the skeleton of a method": {
+			commonFlags:      "--proto testdata/test.proto",
+			cmd:              "skeleton",
+			args:             "api.Example.UnarySelf",
+			assertWithGolden: true,
+		},
+		"print the skeleton of a message in YAML": {
+			commonFlags:      "--proto testdata/test.proto",
+			cmd:              "skeleton",
+			args:             "-o yaml api.UnaryOneofRequest",
+			assertWithGolden: true,
+		},
+		"print the skeleton of a message in textproto": {
+			commonFlags:      "--proto testdata/test.proto",
+			cmd:              "skeleton",
+			args:             "-o textproto api.UnaryMapMessageRequest",
+			assertWithGolden: true,
+		},
+		"cannot print the skeleton because the symbol is missing": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "skeleton",
+			expectedCode: 1,
+		},
+		"cannot print the skeleton of a service": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "skeleton",
+			args:         "api.Example",
+			expectedCode: 1,
+		},
+		"cannot print the skeleton because of unknown output format": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "skeleton",
+			args:         "-o xml api.SimpleRequest",
+			expectedCode: 1,
+		},
+
+		// export command
+
+		"print export command usage": {
+			commonFlags:      "",
+			cmd:              "export",
+			args:             "-h",
+			assertWithGolden: true,
+		},
+		"export descriptors as proto files": {
+			reflection:  true,
+			commonFlags: "--reflection",
+			cmd:         "export",
+			args:        "out",
+			beforeTest:  useTempDir,
+			expectedOut: "out/api.proto",
+			assertTest: func(t *testing.T, output string) {
+				descSource, err := proto.NewDescriptorSourceFromFiles([]string{"out"}, []string{"api.proto"})
+				if err != nil {
+					t.Fatalf("exported proto files must be compiled, but got an error: '%s'", err)
+				}
+				assertServices(t, descSource, "api.Example")
+			},
+		},
+		"export descriptors as a protoset file": {
+			reflection:  true,
+			commonFlags: "--reflection",
+			cmd:         "export",
+			args:        "-o protoset out",
+			beforeTest:  useTempDir,
+			expectedOut: "out/descriptors.protoset",
+			assertTest: func(t *testing.T, output string) {
+				descSource, err := proto.NewDescriptorSourceFromProtosets([]string{output})
+				if err != nil {
+					t.Fatalf("exported protoset file must be loaded, but got an error: '%s'", err)
+				}
+				assertServices(t, descSource, "api.Example")
+			},
+		},
+		"cannot export because the output directory is missing": {
+			reflection:   true,
+			commonFlags:  "--reflection",
+			cmd:          "export",
+			expectedCode: 1,
+		},
+		"cannot export because of unknown output format": {
+			reflection:   true,
+			commonFlags:  "--reflection",
+			cmd:          "export",
+			args:         "-o foo out",
+			beforeTest:   useTempDir,
+			expectedCode: 1,
+		},
 	}
 	for name, c := range cases {
 		c := c
@@ -734,8 +1415,8 @@ func TestE2E_CLI(t *testing.T) {
 					// Trim "deprecated" message.
 					eout = strings.ReplaceAll(eout, color.YellowString("evans: deprecated usage, please use sub-commands. see `evans -h` for more details.")+"\n", "")
 				}
-				if eout != "" {
-					t.Errorf("expected code is 0, but got an error message: '%s'", eoutBuf.String())
+				if eout != c.expectedErrOut {
+					t.Errorf("unexpected error output:\n%s", cmp.Diff(c.expectedErrOut, eout))
 				}
 			}
 			if c.assertTest != nil {
@@ -749,3 +1430,22 @@ func TestE2E_CLI(t *testing.T) {
 		})
 	}
 }
+
+func decodeBenchResult(t *testing.T, output string) *usecase.BenchResult {
+	t.Helper()
+
+	var res usecase.BenchResult
+	if err := json.Unmarshal([]byte(output), &res); err != nil {
+		t.Fatalf("failed to decode the bench result: %s", err)
+	}
+	return &res
+}
+
+// assertAuthorization returns an assertTest which checks whether the server received the authorization header.
+func assertAuthorization(expected string) func(t *testing.T, output string) {
+	return func(t *testing.T, output string) {
+		if s := "key = authorization, val = " + expected; !strings.Contains(output, s) {
+			t.Errorf("expected to contain '%s', but missing in '%s'", s, output)
+		}
+	}
+}
@@ -1,18 +1,22 @@
 package e2e_test
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ktr0731/evans/logger"
+	"github.com/ktr0731/evans/proto"
 	"github.com/ktr0731/grpc-test/server"
 	"github.com/phayes/freeport"
 	"go.uber.org/goleak"
@@ -62,6 +66,19 @@ func startServer(t *testing.T, useTLS, useReflection, useWeb, registerEmptyPacka
 	if err != nil {
 		t.Fatalf("failed to get a free port for gRPC test server: %s", err)
 	}
+	proxyPort := port
+
+	// The test server doesn't support gRPC-Web with TLS, so a TLS terminating proxy is placed in front of it.
+	var stopProxy func()
+	if useTLS && useWeb {
+		backendPort, err := freeport.GetFreePort()
+		if err != nil {
+			t.Fatalf("failed to get a free port for gRPC test server: %s", err)
+		}
+		stopProxy = startTLSProxy(t, port, backendPort)
+		port = backendPort
+		useTLS = false
+	}
 
 	addr := fmt.Sprintf(":%d", port)
 	opts := []server.Option{server.WithAddr(addr)}
@@ -81,6 +98,14 @@ func startServer(t *testing.T, useTLS, useReflection, useWeb, registerEmptyPacka
 	srv := server.New(opts...)
 	go srv.Serve()
 
+	if stopProxy != nil {
+		return func() {
+			stopProxy()
+			if err := srv.Stop(); err != nil {
+				t.Fatalf("Stop must not return an error, but got '%s'", err)
+			}
+		}, strconv.Itoa(proxyPort)
+	}
 	return func() {
 		if err := srv.Stop(); err != nil {
 			t.Fatalf("Stop must not return an error, but got '%s'", err)
@@ -88,6 +113,62 @@ func startServer(t *testing.T, useTLS, useReflection, useWeb, registerEmptyPacka
 	}, strconv.Itoa(port)
 }
 
+// startTLSProxy listens on port with TLS and forwards connections to backendPort.
+func startTLSProxy(t *testing.T, port, backendPort int) func() {
+	t.Helper()
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join("testdata", "localhost.pem"), filepath.Join("testdata", "localhost-key.pem"))
+	if err != nil {
+		t.Fatalf("failed to load the server certificate: %s", err)
+	}
+	lis, err := tls.Listen("tcp", fmt.Sprintf(":%d", port), &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("failed to listen on %d: %s", port, err)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	forward := func(dst, src net.Conn) {
+		defer wg.Done()
+		io.Copy(dst, src) //nolint:errcheck
+		dst.Close()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			backend, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", backendPort))
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			mu.Lock()
+			conns = append(conns, conn, backend)
+			mu.Unlock()
+			wg.Add(2)
+			go forward(backend, conn)
+			go forward(conn, backend)
+		}
+	}()
+
+	return func() {
+		lis.Close()
+		mu.Lock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	}
+}
+
 func flatten(s string) string {
 	s = strings.ReplaceAll(s, "\n", " ")
 	s = strings.TrimSpace(s)
@@ -124,3 +205,35 @@ func compareWithGolden(t *testing.T, actual string) {
 		t.Errorf("wrong result: \n%s", diff)
 	}
 }
+
+// useTempDir changes the working directory to a temp dir.
+// It can be used as beforeTest.
+func useTempDir(t *testing.T) func(*testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get the working directory: %s", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change the working directory: %s", err)
+	}
+	return func(t *testing.T) {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatalf("failed to restore the working directory: %s", err)
+		}
+	}
+}
+
+// assertServices asserts that descSource has the expected services.
+func assertServices(t *testing.T, descSource proto.DescriptorSource, expected ...string) {
+	t.Helper()
+
+	svcs, err := descSource.ListServices()
+	if err != nil {
+		t.Fatalf("ListServices must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(expected, svcs); diff != "" {
+		t.Errorf("unexpected services (-want, +got):\n%s", diff)
+	}
+}
@@ -71,10 +71,6 @@ func TestE2E_OldCLI(t *testing.T) {
 			args:         "--cli --repl",
 			expectedCode: 1,
 		},
-		"cannot specify both of --tls and --web": {
-			args:         "--web --tls testdata/test.proto",
-			expectedCode: 1,
-		},
 		"cannot launch without proto files and reflection": {
 			args:         "",
 			expectedCode: 1,
@@ -261,50 +257,49 @@ func TestE2E_OldCLI(t *testing.T) {
 
 		// CLI mode with gRPC-Web
 
-		// TODO: Re-enable after fixing gRPC-Web nil pointer panics
-		// "cannot send a request to gRPC-Web server because the server didn't enable gRPC-Web": {
-		// 	args:         "--web --package api --service Example --call Unary --file testdata/unary_call.in testdata/test.proto testdata/test.proto",
-		// 	web:          false,
-		// 	expectedCode: 1,
-		// },
-		// "call unary RPC with an input file by CLI mode against to gRPC-Web server": {
-		// 	args:        "--web --package api --service Example --call Unary --file testdata/unary_call.in testdata/test.proto testdata/test.proto",
-		// 	web:         true,
-		// 	expectedOut: `{ "message": "oumae" }`,
-		// },
-		// "call unary RPC with an input file by CLI mode and reflection against to gRPC-Web server": {
-		// 	args:        "--web -r --service Example --call Unary --file testdata/unary_call.in",
-		// 	web:         true,
-		// 	reflection:  true,
-		// 	expectedOut: `{ "message": "oumae" }`,
-		// },
-		// "call client streaming RPC by CLI mode against to gRPC-Web server": {
-		// 	args:        "--web --service Example --call ClientStreaming --file testdata/client_streaming.in testdata/test.proto",
-		// 	web:         true,
-		// 	expectedOut: `{ "message": "you sent requests 4 times (oumae, kousaka, kawashima, kato)." }`,
-		// },
-		// "call server streaming RPC by CLI mode against to gRPC-Web server": {
-		// 	args:        "--web --service Example --call ServerStreaming --file testdata/server_streaming.in testdata/test.proto",
-		// 	web:         true,
-		// 	expectedOut: `{ "message": "hello oumae, I greet 1 times." } { "message": "hello oumae, I greet 2 times." } { "message": "hello oumae, I greet 3 times." }`,
-		// },
-		// "call bidi streaming RPC by CLI mode against to gRPC-Web server": {
-		// 	args: "--web --service Example --call BidiStreaming --file testdata/bidi_streaming.in testdata/test.proto",
-		// 	web:  true,
-		// 	assertTest: func(t *testing.T, output string) {
-		// 		dec := json.NewDecoder(strings.NewReader(output))
-		// 		for {
-		// 			var iface interface{}
-		// 			err := dec.Decode(&iface)
-		// 			if errors.Is(err, io.EOF) {
-		// 				return
-		// 			}
-		// 			if err != nil {
-		// 				t.Errorf("expected no errors, but got '%s'", err)
-		// 			}
-		// 		}
-		// 	},
-		// },
+		"cannot send a request to gRPC-Web server because the server didn't enable gRPC-Web": {
+			args:         "--web --package api --service Example --call Unary --file testdata/unary_call.in testdata/test.proto testdata/test.proto",
+			web:          false,
+			expectedCode: 1,
+		},
+		"call unary RPC with an input file by CLI mode against to gRPC-Web server": {
+			args:        "--web --package api --service Example --call Unary --file testdata/unary_call.in testdata/test.proto testdata/test.proto",
+			web:         true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call unary RPC with an input file by CLI mode and reflection against to gRPC-Web server": {
+			args:        "--web -r --service Example --call Unary --file testdata/unary_call.in",
+			web:         true,
+			reflection:  true,
+			expectedOut: `{ "message": "oumae" }`,
+		},
+		"call client streaming RPC by CLI mode against to gRPC-Web server": {
+			args:        "--web --service Example --call ClientStreaming --file testdata/client_streaming.in testdata/test.proto",
+			web:         true,
+			expectedOut: `{ "message": "you sent requests 4 times (oumae, kousaka, kawashima, kato)." }`,
+		},
+		"call server streaming RPC by CLI mode against to gRPC-Web server": {
+			args:        "--web --service Example --call ServerStreaming --file testdata/server_streaming.in testdata/test.proto",
+			web:         true,
+			expectedOut: `{ "message": "hello oumae, I greet 1 times." } { "message": "hello oumae, I greet 2 times." } { "message": "hello oumae, I greet 3 times." }`,
+		},
+		"call bidi streaming RPC by CLI mode against to gRPC-Web server": {
+			args: "--web --service Example --call BidiStreaming --file testdata/bidi_streaming.in testdata/test.proto",
+			web:  true,
+			assertTest: func(t *testing.T, output string) {
+				dec := json.NewDecoder(strings.NewReader(output))
+				for {
+					var iface interface{}
+					err := dec.Decode(&iface)
+					if errors.Is(err, io.EOF) {
+						return
+					}
+					if err != nil {
+						t.Errorf("expected no errors, but got '%s'", err)
+					}
+				}
+			},
+		},
 	}
 	for name, c := range cases {
 		c := c
@@ -362,27 +357,43 @@ var expectedUsageOut = fmt.Sprintf(`evans %s
 Usage: evans [global options ...] <command>
 
 Options:
-        --silent, -s                     hide redundant output (default "false")
-        --path strings                   comma-separated proto file paths (default "[]")
-        --proto strings                  comma-separated proto file names (default "[]")
-        --host string                    gRPC server host
-        --port, -p string                gRPC server port (default "50051")
-        --header slice of strings        default headers that set to each requests (example: foo=bar) (default "[]")
-        --web                            use gRPC-Web protocol (default "false")
-        --reflection, -r                 use gRPC reflection (default "false")
-        --tls, -t                        use a secure TLS connection (default "false")
-        --cacert string                  the CA certificate file for verifying the server
-        --cert string                    the certificate file for mutual TLS auth. it must be provided with --certkey.
-        --certkey string                 the private key file for mutual TLS auth. it must be provided with --cert.
-        --servername string              override the server name used to verify the hostname (ignored if --tls is disabled)
-        --edit, -e                       edit the project config file by using $EDITOR (default "false")
-        --edit-global                    edit the global config file by using $EDITOR (default "false")
-        --verbose                        verbose output (default "false")
-        --version, -v                    display version and exit (default "false")
-        --help, -h                       display help text and exit (default "false")
+        --silent, -s                         hide redundant output (default "false")
+        --path strings                       comma-separated proto file paths (default "[]")
+        --proto strings                      comma-separated proto file names (default "[]")
+        --protoset strings                   comma-separated protoset file names (default "[]")
+        --host string                        gRPC server host
+        --port, -p string                    gRPC server port (default "50051")
+        --header slice of strings            default headers that set to each requests (example: foo=bar) (default "[]")
+        --web                                use gRPC-Web protocol (default "false")
+        --reflection, -r                     use gRPC reflection (default "false")
+        --refresh-reflection                 ignore the reflection descriptor cache (default "false")
+        --tls, -t                            use a secure TLS connection (default "false")
+        --cacert string                      the CA certificate file for verifying the server
+        --cert string                        the certificate file for mutual TLS auth. it must be provided with --certkey.
+        --certkey string                     the private key file for mutual TLS auth. it must be provided with --cert.
+        --servername string                  override the server name used to verify the hostname (ignored if --tls is disabled)
+        --insecure                           skip the verification of the server certificate. it requires --tls (default "false")
+        --tls-min-version string             the minimum TLS version (1.0, 1.1, 1.2 or 1.3). it requires --tls
+        --tls-cipher-suites strings          comma-separated cipher suites used in TLS 1.0-1.2. it requires --tls (default "[]")
+        --profile string                     the profile defined in the config files (see 'evans cli profiles')
+        --auth-token-file string             the file that has a bearer token. it is re-read when the file is changed
+        --auth-token-url string              the token endpoint of the OAuth2 client credentials flow
+        --auth-client-id string              the client ID of the OAuth2 client credentials flow
+        --auth-client-secret string          the client secret of the OAuth2 client credentials flow
+        --auth-scopes strings                comma-separated scopes of the OAuth2 client credentials flow (default "[]")
+        --auth-command string                the command that prints a bearer token. it requires --allow-command-interpolation
+        --auth-allow-plaintext               allow sending the bearer token to servers without TLS (default "false")
+        --allow-command-interpolation        enable $(command) in header values and TLS file paths (default "false")
+        --edit, -e                           edit the project config file by using $EDITOR (default "false")
+        --edit-global                        edit the global config file by using $EDITOR (default "false")
+        --verbose                            verbose output (default "false")
+        --version, -v                        display version and exit (default "false")
+        --help, -h                           display help text and exit (default "false")
 
 Available Commands:
-        cli         CLI mode
-        repl        REPL mode
+        cli          CLI mode
+        proxy        record/replay proxy mode
+        repl         REPL mode
+        serve        mock server mode
 
 `, meta.Version)
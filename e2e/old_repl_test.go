@@ -141,24 +141,23 @@ func TestE2E_OldREPL(t *testing.T) {
 			input: []interface{}{"call UnaryRepeatedEnum", 0, 0, 1, io.EOF},
 		},
 
-		// TODO: Re-enable after fixing gRPC-Web nil pointer panics
 		// call (gRPC-Web)
 
-		// "call client streaming RPC against to gRPC-Web server": {
-		// 	args:  "--web testdata/test.proto",
-		// 	web:   true,
-		// 	input: []interface{}{"call ClientStreaming", "oumae", "kousaka", "kawashima", "kato", io.EOF},
-		// },
-		// "call server streaming RPC against to gRPC-Web server": {
-		// 	args:  "--web testdata/test.proto",
-		// 	web:   true,
-		// 	input: []interface{}{"call ServerStreaming", "violet"},
-		// },
-		// "call bidi streaming RPC against to gRPC-Web server": {
-		// 	args:  "--web testdata/test.proto",
-		// 	web:   true,
-		// 	input: []interface{}{"call BidiStreaming", "oumae", "kousaka", "kawashima", "kato", io.EOF},
-		// },
+		"call client streaming RPC against to gRPC-Web server": {
+			args:  "--web testdata/test.proto",
+			web:   true,
+			input: []interface{}{"call ClientStreaming", "oumae", "kousaka", "kawashima", "kato", io.EOF},
+		},
+		"call server streaming RPC against to gRPC-Web server": {
+			args:  "--web testdata/test.proto",
+			web:   true,
+			input: []interface{}{"call ServerStreaming", "violet"},
+		},
+		"call bidi streaming RPC against to gRPC-Web server": {
+			args:  "--web testdata/test.proto",
+			web:   true,
+			input: []interface{}{"call BidiStreaming", "oumae", "kousaka", "kawashima", "kato", io.EOF},
+		},
 
 		// show command.
 
@@ -40,6 +40,10 @@ func TestE2E_REPL(t *testing.T) {
 		// skipGolden skips golden file testing.
 		skipGolden bool
 
+		// expectedOutContains are substrings the output must contain in any order.
+		// It is used for outputs which cannot be compared with a golden file.
+		expectedOutContains []string
+
 		// hasErr checks whether REPL wrote some errors to UI.ErrWriter.
 		hasErr bool
 	}{
@@ -89,6 +93,10 @@ func TestE2E_REPL(t *testing.T) {
 			commonFlags: "--service Example --proto testdata/test.proto",
 			input:       []interface{}{"call Unary", "kaguya"},
 		},
+		"call Unary with a protoset file": {
+			commonFlags: "--protoset testdata/test.protoset",
+			input:       []interface{}{"call Unary", "kaguya"},
+		},
 		"call Unary with --emit-defaults": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"package api", "service Example", "call --emit-defaults Unary", ""},
@@ -103,6 +111,35 @@ func TestE2E_REPL(t *testing.T) {
 			// io.EOF means end of inputting.
 			input: []interface{}{"call BidiStreaming", "kaguya", "chika", "miko", io.EOF},
 		},
+		"call BidiStreaming with --session": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --session BidiStreaming", "send", "kaguya", `send {"name": "chika"}`, io.EOF},
+			// Output has timestamps, and responses are interleaved with requests.
+			skipGolden: true,
+			expectedOutContains: []string{
+				"bidi streaming session of api.Example.BidiStreaming. type 'help' to show commands\n",
+				"] sent\n{\n  \"name\": \"kaguya\"\n}\n",
+				"] sent\n{\n  \"name\": \"chika\"\n}\n",
+				"] header\n",
+				"header_key1: header_val1\n",
+				"] received\n{\n  \"message\": \"hello kaguya, I greet 3 times.\"\n}\n",
+				"] received\n{\n  \"message\": \"hello chika, I greet 3 times.\"\n}\n",
+				"] closed the send direction\n",
+				"] finished with code = OK\n",
+			},
+		},
+		"call --session with --output": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --session -o json BidiStreaming"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"call --session against to a non-bidi streaming RPC": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --session Unary"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
 		"call UnaryMessage": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"call UnaryMessage", "kaguya", "shinomiya"},
@@ -152,6 +189,12 @@ func TestE2E_REPL(t *testing.T) {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"call UnaryRepeatedEnum", 0, 0, 1, io.EOF},
 		},
+		"call Unary with the binary output format": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"package api", "service Example", "call -o binary Unary"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
 		"call Unary with an invalid flag": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"package api", "service Example", "call -foo Unary", "kaguya"},
@@ -171,24 +214,23 @@ func TestE2E_REPL(t *testing.T) {
 			input:       []interface{}{"call --dig-manually UnaryEcho", 0, prompt.ErrAbort},
 		},
 
-		// TODO: Re-enable after fixing gRPC-Web nil pointer panics
 		// call (gRPC-Web)
 
-		// "call client streaming RPC against to gRPC-Web server": {
-		// 	commonFlags: "--web --proto testdata/test.proto",
-		// 	web:         true,
-		// 	input:       []interface{}{"call ClientStreaming", "oumae", "kousaka", "kawashima", "kato", io.EOF},
-		// },
-		// "call server streaming RPC against to gRPC-Web server": {
-		// 	commonFlags: "--web --proto testdata/test.proto",
-		// 	web:         true,
-		// 	input:       []interface{}{"call ServerStreaming", "violet"},
-		// },
-		// "call bidi streaming RPC against to gRPC-Web server": {
-		// 	commonFlags: "--web --proto testdata/test.proto",
-		// 	web:         true,
-		// 	input:       []interface{}{"call BidiStreaming", "oumae", "kousaka", "kawashima", "kato", io.EOF},
-		// },
+		"call client streaming RPC against to gRPC-Web server": {
+			commonFlags: "--web --proto testdata/test.proto",
+			web:         true,
+			input:       []interface{}{"call ClientStreaming", "oumae", "kousaka", "kawashima", "kato", io.EOF},
+		},
+		"call server streaming RPC against to gRPC-Web server": {
+			commonFlags: "--web --proto testdata/test.proto",
+			web:         true,
+			input:       []interface{}{"call ServerStreaming", "violet"},
+		},
+		"call bidi streaming RPC against to gRPC-Web server": {
+			commonFlags: "--web --proto testdata/test.proto",
+			web:         true,
+			input:       []interface{}{"call BidiStreaming", "oumae", "kousaka", "kawashima", "kato", io.EOF},
+		},
 
 		// show command.
 
@@ -209,6 +251,17 @@ func TestE2E_REPL(t *testing.T) {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"show service"},
 		},
+		"show service with gRPC reflection": {
+			reflection:  true,
+			commonFlags: "--reflection",
+			input:       []interface{}{"show service"},
+		},
+		"show service with gRPC reflection against to gRPC-Web server": {
+			reflection:  true,
+			web:         true,
+			commonFlags: "--reflection --web",
+			input:       []interface{}{"show service"},
+		},
 		"show message": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"show message"},
@@ -410,6 +463,11 @@ func TestE2E_REPL(t *testing.T) {
 			if !c.skipGolden {
 				compareWithGolden(t, w.String())
 			}
+			for _, s := range c.expectedOutContains {
+				if !strings.Contains(w.String(), s) {
+					t.Errorf("expected the output contains '%s', but got '%s'", s, w.String())
+				}
+			}
 
 			if c.hasErr {
 				if ew.String() == "" {
@@ -0,0 +1,19 @@
+// Package editor runs a text editor such as $EDITOR.
+package editor
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// Run opens path with editor, and waits for the editor to exit. The editor uses the standard I/O.
+func Run(editor, path string) error {
+	cmd := exec.Command(editor, path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "failed to execute %s", editor)
+	}
+	return nil
+}
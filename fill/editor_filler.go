@@ -0,0 +1,155 @@
+package fill
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ktr0731/evans/editor"
+	"github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const editorErrorPrefix = "// error: "
+
+// EditorFiller is a Filler implementation that opens a JSON template of the message with an editor.
+// The saved file is decoded in the JSON mapping of Protocol Buffers. Lines starting with "//" are comments,
+// and trailing commas are allowed.
+type EditorFiller struct {
+	editor string
+	dec    *protojson.UnmarshalOptions
+}
+
+// NewEditorFiller returns an instance of EditorFiller. editor is the command of the editor like $EDITOR.
+// resolver is used to resolve types of google.protobuf.Any. If it is nil, protoregistry.GlobalTypes is used.
+func NewEditorFiller(editor string, resolver proto.TypeResolver) *EditorFiller {
+	return &EditorFiller{
+		editor: editor,
+		dec:    &protojson.UnmarshalOptions{Resolver: resolver},
+	}
+}
+
+// Fill opens the template of v with the editor, and fills v with the saved file.
+// If the saved file has no message, Fill returns io.EOF. If the file is invalid, Fill opens the editor again
+// with the error.
+func (f *EditorFiller) Fill(v *dynamicpb.Message) error {
+	file, err := os.CreateTemp("", "evans-*.json")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a temporary file")
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	content := editorHeader(v.Descriptor()) + JSONTemplate(v.Descriptor()) + "\n"
+	for {
+		if err := os.WriteFile(file.Name(), []byte(content), 0600); err != nil {
+			return errors.Wrap(err, "failed to write the template")
+		}
+		if err := runEditor(f.editor, file.Name()); err != nil {
+			return err
+		}
+		b, err := os.ReadFile(file.Name())
+		if err != nil {
+			return errors.Wrap(err, "failed to read the edited file")
+		}
+		in := normalizeJSON(b)
+		if len(bytes.TrimSpace(in)) == 0 {
+			return io.EOF
+		}
+		err = f.dec.Unmarshal(in, v)
+		if err == nil {
+			return nil
+		}
+		content = editorErrorPrefix + strings.ReplaceAll(err.Error(), "\n", " ") + "\n" + dropEditorError(string(b))
+	}
+}
+
+var runEditor = editor.Run
+
+func editorHeader(md protoreflect.MessageDescriptor) string {
+	return fmt.Sprintf(`// %s
+// Edit the message in the JSON mapping of Protocol Buffers. Lines starting with "//" are ignored.
+// Delete the message and save the file to finish inputting.
+`, md.FullName())
+}
+
+// dropEditorError removes the error lines written by the previous Fill.
+func dropEditorError(s string) string {
+	for strings.HasPrefix(s, editorErrorPrefix) {
+		i := strings.IndexByte(s, '\n')
+		if i == -1 {
+			return ""
+		}
+		s = s[i+1:]
+	}
+	return s
+}
+
+// normalizeJSON removes "//" comments and trailing commas from b.
+func normalizeJSON(b []byte) []byte {
+	var (
+		out      []byte
+		inString bool
+		escaped  bool
+	)
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if inString {
+			out = append(out, c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+			if i < len(b) {
+				out = append(out, '\n')
+			}
+			continue
+		case c == '}' || c == ']':
+			// Remove the trailing comma before c.
+			j := len(out) - 1
+			for j >= 0 && isJSONSpace(out[j]) {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// JSONTemplate returns a template of md in the JSON mapping of Protocol Buffers. All fields have their default values.
+// Repeated and map fields of messages have one element so that their fields are shown.
+// Enum fields and oneofs have comments that list the values and the other fields, and only the first field of each
+// oneof is included. Fields with the optional keyword are commented out so that they are not set unless they are
+// edited. Fields that refer to a message type recursively are omitted. The template has "//" comments,
+// so they must be removed before decoding it as JSON.
+func JSONTemplate(md protoreflect.MessageDescriptor) string {
+	var w bytes.Buffer
+	newSkeletonBuilder(false, true).message(md, string(md.Name())).writeJSON(&w, 0, true)
+	return w.String()
+}
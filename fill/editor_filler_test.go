@@ -0,0 +1,125 @@
+package fill_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/fill"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestJSONTemplate(t *testing.T) {
+	expected := `{
+  "name": "",
+  "id": "0",
+  // enum api.Request.Kind: KIND_UNSPECIFIED, KIND_FOO
+  "kind": "KIND_UNSPECIFIED",
+  // oneof: "text" can be replaced with one of the following fields
+  //   "number": 0
+  //   "sub": {...}
+  "text": "",
+  "subs": [
+    {
+      "ok": false
+    }
+  ],
+  "counts": {},
+  "createdAt": "1970-01-01T00:00:00Z",
+  "ttl": "0s",
+  "alias": "",
+  "attrs": {},
+  "detail": {},
+  "ratio": 0,
+  "raw": "",
+  // "nickname": ""
+  "tags": []
+}`
+	md := compileTestMessage(t, "Request")
+	actual := fill.JSONTemplate(md)
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	// The template must be a valid message after removing comments.
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal(fill.NormalizeJSON([]byte(actual)), msg); err != nil {
+		t.Fatalf("the template must be decodable, but got '%s'", err)
+	}
+	// Optional fields must not be set unless they are edited.
+	if msg.Has(md.Fields().ByName("nickname")) {
+		t.Error("the optional field must not be set by the template")
+	}
+}
+
+func TestEditorFiller(t *testing.T) {
+	cases := map[string]struct {
+		edits    []string
+		expected string
+		err      error
+	}{
+		"normal": {
+			edits:    []string{"// comment\n{\n  \"name\": \"oumae // kumiko\", // comment\n  \"tags\": [\"a\",],\n}\n"},
+			expected: `{"name":"oumae // kumiko","tags":["a"]}`,
+		},
+		"empty": {
+			edits: []string{"// comment only\n"},
+			err:   io.EOF,
+		},
+		"invalid then valid": {
+			edits:    []string{`{"name": 1}`, `{"name": "kousaka"}`},
+			expected: `{"name":"kousaka"}`,
+		},
+	}
+
+	md := compileTestMessage(t, "Request")
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var opened []string
+			defer fill.SetRunEditor(func(editor, path string) error {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				opened = append(opened, string(b))
+				edit := c.edits[0]
+				c.edits = c.edits[1:]
+				return os.WriteFile(path, []byte(edit), 0600)
+			})()
+
+			msg := dynamicpb.NewMessage(md)
+			err := fill.NewEditorFiller("vim", nil).Fill(msg)
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Fatalf("expected '%s', but got '%v'", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fill must not return an error, but got '%s'", err)
+			}
+
+			expected := dynamicpb.NewMessage(md)
+			if err := protojson.Unmarshal([]byte(c.expected), expected); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(protojson.Format(expected), protojson.Format(msg)); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+
+			if !strings.HasPrefix(opened[0], "// api.Request\n") {
+				t.Errorf("the template must start with the message name, but got '%s'", opened[0])
+			}
+			for _, s := range opened[1:] {
+				if !strings.HasPrefix(s, fill.EditorErrorPrefix) {
+					t.Errorf("the reopened file must start with the error, but got '%s'", s)
+				}
+			}
+		})
+	}
+}
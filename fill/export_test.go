@@ -0,0 +1,14 @@
+package fill
+
+// Exported for tests in the package fill_test.
+
+const EditorErrorPrefix = editorErrorPrefix
+
+var NormalizeJSON = normalizeJSON
+
+// SetRunEditor replaces the function that runs the editor with f, and returns a function that restores it.
+func SetRunEditor(f func(editor, path string) error) (restore func()) {
+	old := runEditor
+	runEditor = f
+	return func() { runEditor = old }
+}
@@ -0,0 +1,64 @@
+package fill
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// PlaceholderFiller is a Filler implementation that fills each field with a placeholder value.
+// Placeholder values are not zero values so that all fields appear in the JSON mapping.
+// Strings and bytes are the field name, numbers are 1 (or 1.5), bools are true and enums are the first non-zero value.
+// Repeated and map fields have one element, and only the first field of each oneof is filled.
+// Fields that refer to a message type recursively are left empty.
+type PlaceholderFiller struct{}
+
+// NewPlaceholderFiller returns an instance of PlaceholderFiller.
+func NewPlaceholderFiller() *PlaceholderFiller {
+	return &PlaceholderFiller{}
+}
+
+// Fill fills each field of v with a placeholder value. v is filled with the JSON skeleton of its descriptor,
+// so well-known types have the same placeholder values as Skeleton.
+func (f *PlaceholderFiller) Fill(v *dynamicpb.Message) error {
+	var w bytes.Buffer
+	newSkeletonBuilder(false, false).message(v.Descriptor(), string(v.Descriptor().Name())).writeJSON(&w, 0, false)
+	if err := protojson.Unmarshal(w.Bytes(), v); err != nil {
+		return errors.Wrap(err, "failed to fill placeholder values")
+	}
+	return nil
+}
+
+func placeholderScalar(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			if n := values.Get(i).Number(); n != 0 {
+				return protoreflect.ValueOfEnum(n)
+			}
+		}
+		return protoreflect.ValueOfEnum(values.Get(0).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(1)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(1)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(1)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(1)
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(1.5)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1.5)
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(fd.Name()))
+	default:
+		return protoreflect.ValueOfString(string(fd.Name()))
+	}
+}
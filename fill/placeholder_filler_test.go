@@ -0,0 +1,52 @@
+package fill_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/fill"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestPlaceholderFiller(t *testing.T) {
+	md := compileTestMessage(t, "Message")
+
+	msg := dynamicpb.NewMessage(md)
+	if err := fill.NewPlaceholderFiller().Fill(msg); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to marshal the message: %s", err)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(b, &actual); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"a": []interface{}{map[string]interface{}{}},
+		"b": "enum2",
+		"c": 1.5,
+		"d": 1.5,
+		"e": "1",
+		"f": "1",
+		"g": "1",
+		"h": "1",
+		"i": "1",
+		"j": float64(1),
+		"k": float64(1),
+		"l": float64(1),
+		"m": float64(1),
+		"n": float64(1),
+		"o": true,
+		"p": "p",
+		"q": "cQ==",
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 
+	"github.com/ktr0731/evans/proto"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
@@ -15,10 +16,12 @@ type SilentFiller struct {
 }
 
 // NewSilentFiller receives input as io.Reader and returns an instance of SilentFiller.
-func NewSilentFiller(in io.Reader) *SilentFiller {
+// resolver is used to resolve types of google.protobuf.Any and extensions.
+// If it is nil, protoregistry.GlobalTypes is used.
+func NewSilentFiller(in io.Reader, resolver proto.TypeResolver) *SilentFiller {
 	return &SilentFiller{
 		dec: &protojson.UnmarshalOptions{
-			Resolver: nil, // TODO
+			Resolver: resolver,
 		},
 		in: json.NewDecoder(in),
 	}
@@ -8,8 +8,10 @@ import (
 
 	"github.com/bufbuild/protocompile"
 	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 func TestSilentFiller(t *testing.T) {
@@ -17,27 +19,19 @@ func TestSilentFiller(t *testing.T) {
 		in     string
 		hasErr bool
 	}{
-		"normal":       {in: `{"p": "bar"}`},
-		"invalid JSON": {in: `foo`, hasErr: true},
+		"normal":              {in: `{"p": "bar"}`},
+		"slashes in a string": {in: `{"p": "//bar\""}`},
+		"comments":            {in: "// comment\n{\"p\": \"bar\"}", hasErr: true},
+		"invalid JSON":        {in: `foo`, hasErr: true},
 	}
 
-	c := &protocompile.Compiler{
-		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
-			ImportPaths: []string{filepath.Join("proto", "testdata")},
-		}),
-	}
-	compiled, err := c.Compile(context.TODO(), "test.proto")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	md := compiled[0].Messages().ByName(protoreflect.Name("Message"))
+	md := compileTestMessage(t, "Message")
 
 	for name, c := range cases {
 		c := c
 		t.Run(name, func(t *testing.T) {
 
-			f := fill.NewSilentFiller(strings.NewReader(c.in))
+			f := fill.NewSilentFiller(strings.NewReader(c.in), nil)
 			i := dynamicpb.NewMessage(md)
 			err := f.Fill(i)
 			if c.hasErr {
@@ -50,3 +44,50 @@ func TestSilentFiller(t *testing.T) {
 		})
 	}
 }
+
+func TestSilentFiller_Any(t *testing.T) {
+	const in = `{"@type": "type.googleapis.com/api.Message", "p": "bar"}`
+
+	md := compileTestMessage(t, "Message")
+	anyDesc := (&anypb.Any{}).ProtoReflect().Descriptor()
+
+	t.Run("global registry", func(t *testing.T) {
+		f := fill.NewSilentFiller(strings.NewReader(in), nil)
+		if err := f.Fill(dynamicpb.NewMessage(anyDesc)); err == nil {
+			t.Errorf("Fill must return an error because api.Message is not registered globally, but got nil")
+		}
+	})
+
+	t.Run("descriptor source", func(t *testing.T) {
+		descSource := &proto.DescriptorSourceMock{
+			FindSymbolFunc: func(name string) (protoreflect.Descriptor, error) {
+				if name != "api.Message" {
+					t.Errorf("unexpected symbol: %s", name)
+				}
+				return md, nil
+			},
+		}
+		f := fill.NewSilentFiller(strings.NewReader(in), proto.NewTypeResolver(descSource))
+		if err := f.Fill(dynamicpb.NewMessage(anyDesc)); err != nil {
+			t.Errorf("Fill must not return an error, but got an error: '%s'", err)
+		}
+	})
+}
+
+// compileTestMessage returns the descriptor of the message named name in the package api defined in
+// proto/testdata/test.proto.
+func compileTestMessage(t *testing.T, name protoreflect.Name) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	c := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{filepath.Join("proto", "testdata")},
+		}),
+	}
+	compiled, err := c.Compile(context.TODO(), "test.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return compiled[0].Messages().ByName(name)
+}
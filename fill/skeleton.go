@@ -0,0 +1,467 @@
+package fill
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// Skeleton returns an example of md in format. format is one of "json", "yaml" or "textproto" (alias "text").
+// Each field has the same placeholder value as PlaceholderFiller. In YAML and textproto, enum values and the other
+// fields of each oneof are listed in comments. The JSON skeleton has no comments so that it can be read by
+// SilentFiller as it is. In JSON and YAML, well-known types are represented in their JSON mapping.
+func Skeleton(md protoreflect.MessageDescriptor, format string) (string, error) {
+	switch format {
+	case "json":
+		var w bytes.Buffer
+		newSkeletonBuilder(false, false).message(md, string(md.Name())).writeJSON(&w, 0, false)
+		w.WriteByte('\n')
+		return w.String(), nil
+	case "yaml":
+		var w bytes.Buffer
+		enc := yaml.NewEncoder(&w)
+		enc.SetIndent(2)
+		if err := enc.Encode(newSkeletonBuilder(false, false).message(md, string(md.Name())).yamlNode()); err != nil {
+			return "", errors.Wrap(err, "failed to encode the skeleton")
+		}
+		if err := enc.Close(); err != nil {
+			return "", errors.Wrap(err, "failed to encode the skeleton")
+		}
+		return w.String(), nil
+	case "textproto", "text":
+		var w bytes.Buffer
+		writeTextFields(&w, newSkeletonBuilder(true, false).message(md, string(md.Name())).fields, 0)
+		return w.String(), nil
+	default:
+		return "", errors.Errorf("unknown skeleton format '%s'", format)
+	}
+}
+
+// skeletonNode is an object, a list or a scalar of a skeleton.
+type skeletonNode struct {
+	isObject bool
+	fields   []*skeletonField
+
+	isList bool
+	elems  []*skeletonNode
+
+	// literal is the formatted scalar value.
+	literal string
+}
+
+type skeletonField struct {
+	name  string
+	value *skeletonNode
+	// optional is true if the field has the optional keyword.
+	optional bool
+	// enum is the comment that lists the values of the enum type of the field.
+	enum string
+	// alternatives are the other fields of the oneof that the field belongs to.
+	alternatives []*skeletonField
+}
+
+// skeletonBuilder builds a skeleton in the JSON mapping, or in the text format if text is true.
+// Fields have placeholder values, or their default values if zero is true.
+type skeletonBuilder struct {
+	text, zero bool
+	visited    map[protoreflect.FullName]bool
+}
+
+func newSkeletonBuilder(text, zero bool) *skeletonBuilder {
+	return &skeletonBuilder{text: text, zero: zero, visited: map[protoreflect.FullName]bool{}}
+}
+
+// message returns the skeleton of md. Only the first field of each oneof is included, and fields that refer to
+// a message type recursively are omitted. name is used as the placeholder of string wrappers.
+func (b *skeletonBuilder) message(md protoreflect.MessageDescriptor, name string) *skeletonNode {
+	// The JSON mapping of google.protobuf.Any requires a resolvable type URL.
+	if md.FullName() == "google.protobuf.Any" {
+		return &skeletonNode{isObject: true}
+	}
+	if !b.text {
+		if n, ok := wellKnownTypeSkeleton(md, name, b.zero); ok {
+			return n
+		}
+	}
+	b.visited[md.FullName()] = true
+	defer delete(b.visited, md.FullName())
+
+	n := &skeletonNode{isObject: true}
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		oneof := fd.ContainingOneof()
+		if oneof != nil && oneof.Fields().Get(0) != fd {
+			continue
+		}
+		if md := fieldMessage(fd); md != nil && b.visited[md.FullName()] {
+			continue
+		}
+
+		f := &skeletonField{
+			name:     b.fieldName(fd),
+			value:    b.field(fd),
+			optional: fd.HasOptionalKeyword(),
+			enum:     enumComment(fd),
+		}
+		if oneof != nil {
+			for j := 1; j < oneof.Fields().Len(); j++ {
+				alt := oneof.Fields().Get(j)
+				if md := fieldMessage(alt); md != nil && b.visited[md.FullName()] {
+					continue
+				}
+				f.alternatives = append(f.alternatives, &skeletonField{name: b.fieldName(alt), value: b.field(alt)})
+			}
+		}
+		n.fields = append(n.fields, f)
+	}
+	return n
+}
+
+func (b *skeletonBuilder) fieldName(fd protoreflect.FieldDescriptor) string {
+	if b.text {
+		return string(fd.Name())
+	}
+	return fd.JSONName()
+}
+
+func (b *skeletonBuilder) field(fd protoreflect.FieldDescriptor) *skeletonNode {
+	switch {
+	case fd.IsMap():
+		// Default values of scalar maps are empty, but maps of messages have one entry so that their fields are shown.
+		if b.zero && !isMessageKind(fd.MapValue().Kind()) {
+			return &skeletonNode{isObject: true}
+		}
+		key := b.scalarValue(fd.MapKey())
+		if b.text {
+			// A map entry is a message that has the key and the value.
+			return &skeletonNode{isObject: true, fields: []*skeletonField{
+				{name: "key", value: &skeletonNode{literal: b.scalar(fd.MapKey(), key)}},
+				{name: "value", value: b.value(fd.MapValue())},
+			}}
+		}
+		return &skeletonNode{isObject: true, fields: []*skeletonField{
+			{name: key.MapKey().String(), value: b.value(fd.MapValue())},
+		}}
+	case fd.IsList():
+		if b.zero && !isMessageKind(fd.Kind()) {
+			return &skeletonNode{isList: true}
+		}
+		// In the text format, a list which has one element is the same as a singular field.
+		if b.text {
+			return b.value(fd)
+		}
+		return &skeletonNode{isList: true, elems: []*skeletonNode{b.value(fd)}}
+	default:
+		return b.value(fd)
+	}
+}
+
+// value returns the skeleton of a singular field or an element of fd.
+func (b *skeletonBuilder) value(fd protoreflect.FieldDescriptor) *skeletonNode {
+	if isMessageKind(fd.Kind()) {
+		return b.message(fd.Message(), string(fd.Name()))
+	}
+	return &skeletonNode{literal: b.scalar(fd, b.scalarValue(fd))}
+}
+
+func (b *skeletonBuilder) scalarValue(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	if b.zero {
+		return fd.Default()
+	}
+	return placeholderScalar(fd)
+}
+
+func (b *skeletonBuilder) scalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if !b.text && fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return "null"
+		}
+		name := string(fd.Enum().Values().ByNumber(v.Enum()).Name())
+		if b.text {
+			return name
+		}
+		return strconv.Quote(name)
+	case protoreflect.StringKind:
+		return b.quote(v.String())
+	case protoreflect.BytesKind:
+		if b.text {
+			return strconv.Quote(string(v.Bytes()))
+		}
+		return strconv.Quote(base64.StdEncoding.EncodeToString(v.Bytes()))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// 64-bit integers are strings in the JSON mapping.
+		if b.text {
+			return v.String()
+		}
+		return strconv.Quote(v.String())
+	default:
+		return v.String()
+	}
+}
+
+func (b *skeletonBuilder) quote(s string) string {
+	if b.text {
+		return strconv.Quote(s)
+	}
+	q, _ := json.Marshal(s)
+	return string(q)
+}
+
+// wellKnownTypeSkeleton returns the skeleton of a well-known type which has a special JSON mapping.
+// If zero is true, the skeleton is the default value.
+func wellKnownTypeSkeleton(md protoreflect.MessageDescriptor, name string, zero bool) (*skeletonNode, bool) {
+	scalar := func(placeholder, defaultValue string) (*skeletonNode, bool) {
+		if zero {
+			return &skeletonNode{literal: defaultValue}, true
+		}
+		return &skeletonNode{literal: placeholder}, true
+	}
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return scalar(`"2006-01-02T15:04:05Z"`, `"1970-01-01T00:00:00Z"`)
+	case "google.protobuf.Duration":
+		return scalar(`"1s"`, `"0s"`)
+	case "google.protobuf.FieldMask", "google.protobuf.StringValue":
+		return scalar(strconv.Quote(name), `""`)
+	case "google.protobuf.Empty":
+		return &skeletonNode{isObject: true}, true
+	case "google.protobuf.Struct":
+		if zero {
+			return &skeletonNode{isObject: true}, true
+		}
+		return &skeletonNode{isObject: true, fields: []*skeletonField{
+			{name: "key", value: &skeletonNode{literal: `"value"`}},
+		}}, true
+	case "google.protobuf.Value":
+		return scalar(`"value"`, "null")
+	case "google.protobuf.ListValue":
+		if zero {
+			return &skeletonNode{isList: true}, true
+		}
+		return &skeletonNode{isList: true, elems: []*skeletonNode{{literal: `"value"`}}}, true
+	case "google.protobuf.BytesValue":
+		return scalar(strconv.Quote(base64.StdEncoding.EncodeToString([]byte(name))), `""`)
+	case "google.protobuf.BoolValue":
+		return scalar("true", "false")
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return scalar(`"1"`, `"0"`)
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return scalar("1", "0")
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue":
+		return scalar("1.5", "0")
+	}
+	return nil, false
+}
+
+// fieldMessage returns the message type of fd, or the value type if fd is a map field.
+// It returns nil if the type is not a message.
+func fieldMessage(fd protoreflect.FieldDescriptor) protoreflect.MessageDescriptor {
+	if fd.IsMap() {
+		return fd.MapValue().Message()
+	}
+	return fd.Message()
+}
+
+func isMessageKind(k protoreflect.Kind) bool {
+	return k == protoreflect.MessageKind || k == protoreflect.GroupKind
+}
+
+// enumComment returns the comment that lists the values of the enum type of fd, or the map value type if fd is
+// a map field. It returns an empty string if the type is not an enum.
+func enumComment(fd protoreflect.FieldDescriptor) string {
+	enum := fd.Enum()
+	if fd.IsMap() {
+		enum = fd.MapValue().Enum()
+	}
+	if enum == nil || enum.FullName() == "google.protobuf.NullValue" {
+		return ""
+	}
+	names := make([]string, enum.Values().Len())
+	for i := range names {
+		names[i] = string(enum.Values().Get(i).Name())
+	}
+	return fmt.Sprintf("enum %s: %s", enum.FullName(), strings.Join(names, ", "))
+}
+
+// brief returns the one-line representation of n which is used in comments.
+func (n *skeletonNode) brief() string {
+	switch {
+	case n.isObject && len(n.fields) == 0:
+		return "{}"
+	case n.isObject:
+		return "{...}"
+	case n.isList && len(n.elems) == 0:
+		return "[]"
+	case n.isList:
+		return "[...]"
+	default:
+		return n.literal
+	}
+}
+
+func alternativesComment(name string) string {
+	return fmt.Sprintf("oneof: %s can be replaced with one of the following fields", name)
+}
+
+func writeIndent(w *bytes.Buffer, depth int) {
+	w.WriteString(strings.Repeat("  ", depth))
+}
+
+// writeJSON writes n in JSON. If comments is true, enum values and the other fields of each oneof are written in
+// "//" comments, and optional fields are commented out so that they are not set unless they are uncommented.
+func (n *skeletonNode) writeJSON(w *bytes.Buffer, depth int, comments bool) {
+	switch {
+	case n.isObject:
+		if len(n.fields) == 0 {
+			w.WriteString("{}")
+			return
+		}
+		// last is the index of the last field which is not commented out.
+		last := -1
+		for i, f := range n.fields {
+			if !comments || !f.optional {
+				last = i
+			}
+		}
+		w.WriteString("{\n")
+		for i, f := range n.fields {
+			name := strconv.Quote(f.name)
+			if comments {
+				if f.enum != "" {
+					writeIndent(w, depth+1)
+					w.WriteString("// " + f.enum + "\n")
+				}
+				if len(f.alternatives) != 0 {
+					writeIndent(w, depth+1)
+					w.WriteString("// " + alternativesComment(name) + "\n")
+					for _, alt := range f.alternatives {
+						writeIndent(w, depth+1)
+						fmt.Fprintf(w, "//   %q: %s\n", alt.name, alt.value.brief())
+					}
+				}
+				if f.optional {
+					// Uncommented values must be valid, so messages are empty.
+					v := f.value.brief()
+					if f.value.isObject {
+						v = "{}"
+					}
+					writeIndent(w, depth+1)
+					fmt.Fprintf(w, "// %s: %s\n", name, v)
+					continue
+				}
+			}
+			writeIndent(w, depth+1)
+			w.WriteString(name + ": ")
+			f.value.writeJSON(w, depth+1, comments)
+			if i < last {
+				w.WriteByte(',')
+			}
+			w.WriteByte('\n')
+		}
+		writeIndent(w, depth)
+		w.WriteByte('}')
+	case n.isList:
+		if len(n.elems) == 0 {
+			w.WriteString("[]")
+			return
+		}
+		w.WriteString("[\n")
+		for i, e := range n.elems {
+			writeIndent(w, depth+1)
+			e.writeJSON(w, depth+1, comments)
+			if i != len(n.elems)-1 {
+				w.WriteByte(',')
+			}
+			w.WriteByte('\n')
+		}
+		writeIndent(w, depth)
+		w.WriteByte(']')
+	default:
+		w.WriteString(n.literal)
+	}
+}
+
+func (n *skeletonNode) yamlNode() *yaml.Node {
+	switch {
+	case n.isObject:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		if len(n.fields) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		for _, f := range n.fields {
+			key := &yaml.Node{Kind: yaml.ScalarNode, Value: f.name}
+			var lines []string
+			if f.enum != "" {
+				lines = append(lines, f.enum)
+			}
+			if len(f.alternatives) != 0 {
+				lines = append(lines, alternativesComment(f.name))
+				for _, alt := range f.alternatives {
+					lines = append(lines, fmt.Sprintf("  %s: %s", alt.name, alt.value.brief()))
+				}
+			}
+			if len(lines) != 0 {
+				key.HeadComment = "# " + strings.Join(lines, "\n# ")
+			}
+			node.Content = append(node.Content, key, f.value.yamlNode())
+		}
+		return node
+	case n.isList:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if len(n.elems) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		for _, e := range n.elems {
+			node.Content = append(node.Content, e.yamlNode())
+		}
+		return node
+	default:
+		// Literals are in JSON, so strings are kept double-quoted.
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: n.literal}
+		if s, err := strconv.Unquote(n.literal); err == nil {
+			node.Value, node.Style = s, yaml.DoubleQuotedStyle
+		}
+		return node
+	}
+}
+
+func writeTextFields(w *bytes.Buffer, fields []*skeletonField, depth int) {
+	for _, f := range fields {
+		if f.enum != "" {
+			writeIndent(w, depth)
+			w.WriteString("# " + f.enum + "\n")
+		}
+		if len(f.alternatives) != 0 {
+			writeIndent(w, depth)
+			w.WriteString("# " + alternativesComment(f.name) + "\n")
+			for _, alt := range f.alternatives {
+				writeIndent(w, depth)
+				if alt.value.isObject {
+					fmt.Fprintf(w, "#   %s %s\n", alt.name, alt.value.brief())
+				} else {
+					fmt.Fprintf(w, "#   %s: %s\n", alt.name, alt.value.brief())
+				}
+			}
+		}
+		writeIndent(w, depth)
+		switch {
+		case f.value.isObject && len(f.value.fields) == 0:
+			w.WriteString(f.name + " {}\n")
+		case f.value.isObject:
+			w.WriteString(f.name + " {\n")
+			writeTextFields(w, f.value.fields, depth+1)
+			writeIndent(w, depth)
+			w.WriteString("}\n")
+		default:
+			w.WriteString(f.name + ": " + f.value.literal + "\n")
+		}
+	}
+}
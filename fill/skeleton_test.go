@@ -0,0 +1,101 @@
+package fill_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/fill"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestSkeleton(t *testing.T) {
+	md := compileTestMessage(t, "Request")
+
+	t.Run("json", func(t *testing.T) {
+		// The JSON skeleton has no comments, so it can be read by SilentFiller as it is.
+		expected := `{
+  "name": "name",
+  "id": "1",
+  "kind": "KIND_FOO",
+  "text": "text",
+  "subs": [
+    {
+      "ok": true
+    }
+  ],
+  "counts": {
+    "key": 1
+  },
+  "createdAt": "2006-01-02T15:04:05Z",
+  "ttl": "1s",
+  "alias": "alias",
+  "attrs": {
+    "key": "value"
+  },
+  "detail": {},
+  "ratio": 1.5,
+  "raw": "cmF3",
+  "nickname": "nickname",
+  "tags": [
+    "tags"
+  ]
+}
+`
+		actual, err := fill.Skeleton(md, "json")
+		if err != nil {
+			t.Fatalf("Skeleton must not return an error, but got '%s'", err)
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Errorf("(-want, +got)\n%s", diff)
+		}
+	})
+
+	t.Run("yaml comments", func(t *testing.T) {
+		expected := `# enum api.Request.Kind: KIND_UNSPECIFIED, KIND_FOO
+kind: "KIND_FOO"
+# oneof: text can be replaced with one of the following fields
+#   number: 1
+#   sub: {...}
+text: "text"
+`
+		actual, err := fill.Skeleton(md, "yaml")
+		if err != nil {
+			t.Fatalf("Skeleton must not return an error, but got '%s'", err)
+		}
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected the skeleton contains '%s', but got '%s'", expected, actual)
+		}
+	})
+
+	// Each skeleton must be read by the filler of the format, and JSON and YAML must represent the same message.
+	newFillers := map[string]func(s string) fill.Filler{
+		"json":      func(s string) fill.Filler { return fill.NewSilentFiller(strings.NewReader(s), nil) },
+		"yaml":      func(s string) fill.Filler { return fill.NewYAMLFiller(strings.NewReader(s), nil) },
+		"textproto": func(s string) fill.Filler { return fill.NewTextFiller(strings.NewReader(s), nil) },
+	}
+	results := map[string]*dynamicpb.Message{}
+	for _, format := range []string{"json", "yaml", "textproto"} {
+		s, err := fill.Skeleton(md, format)
+		if err != nil {
+			t.Fatalf("%s: Skeleton must not return an error, but got '%s'", format, err)
+		}
+		msg := dynamicpb.NewMessage(md)
+		if err := newFillers[format](s).Fill(msg); err != nil {
+			t.Fatalf("%s: failed to fill the skeleton: %s\n%s", format, err, s)
+		}
+		results[format] = msg
+	}
+	if diff := cmp.Diff(protojson.Format(results["json"]), protojson.Format(results["yaml"])); diff != "" {
+		t.Errorf("json and yaml: (-want, +got)\n%s", diff)
+	}
+	// Well-known types have message forms in the text format.
+	if !results["textproto"].Has(md.Fields().ByName("created_at")) {
+		t.Error("created_at must be filled in the textproto skeleton")
+	}
+
+	if _, err := fill.Skeleton(md, "xml"); err == nil {
+		t.Error("Skeleton must return an error for an unknown format")
+	}
+}
@@ -0,0 +1,65 @@
+package fill
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/ktr0731/evans/proto"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// TextSeparator separates messages in the input of TextFiller.
+// It must be placed on its own line.
+const TextSeparator = "---"
+
+// maxTextLineSize is large enough for long string or bytes literals.
+const maxTextLineSize = 16 * 1024 * 1024
+
+// TextFiller is a Filler implementation that reads messages written in the Protocol Buffers text format.
+// Each message is separated by a line consisting of TextSeparator.
+type TextFiller struct {
+	dec *prototext.UnmarshalOptions
+	in  *bufio.Scanner
+}
+
+// NewTextFiller receives input as io.Reader and returns an instance of TextFiller.
+// resolver is used to resolve types of google.protobuf.Any and extensions.
+// If it is nil, protoregistry.GlobalTypes is used.
+func NewTextFiller(in io.Reader, resolver proto.TypeResolver) *TextFiller {
+	s := bufio.NewScanner(in)
+	s.Buffer(nil, maxTextLineSize)
+	return &TextFiller{
+		dec: &prototext.UnmarshalOptions{Resolver: resolver},
+		in:  s,
+	}
+}
+
+// Fill fills values of each field from a text format message. If the input is invalid text format,
+// Fill returns an error.
+func (f *TextFiller) Fill(v *dynamicpb.Message) error {
+	var (
+		buf       bytes.Buffer
+		separated bool
+	)
+	for f.in.Scan() {
+		line := f.in.Text()
+		if strings.TrimSpace(line) == TextSeparator {
+			separated = true
+			break
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err := f.in.Err(); err != nil {
+		return err
+	}
+	// If the input reached the end and there is no content, there are no more messages.
+	if !separated && len(bytes.TrimSpace(buf.Bytes())) == 0 {
+		return io.EOF
+	}
+
+	return f.dec.Unmarshal(buf.Bytes(), v)
+}
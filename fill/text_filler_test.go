@@ -0,0 +1,59 @@
+package fill_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/evans/fill"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestTextFiller(t *testing.T) {
+	cases := map[string]struct {
+		in       string
+		expected []string
+		hasErr   bool
+	}{
+		"normal":        {in: `p: "bar"`, expected: []string{"bar"}},
+		"with comments": {in: "# comment\np: \"bar\" # trailing\n", expected: []string{"bar"}},
+		"multiple messages": {
+			in:       "p: \"foo\"\n---\np: \"bar\"\n---\n",
+			expected: []string{"foo", "bar"},
+		},
+		"empty message":       {in: "---\np: \"bar\"", expected: []string{"", "bar"}},
+		"empty input":         {in: ""},
+		"invalid text format": {in: `p: `, hasErr: true},
+	}
+
+	md := compileTestMessage(t, "Message")
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f := fill.NewTextFiller(strings.NewReader(c.in), nil)
+			var actual []string
+			for {
+				i := dynamicpb.NewMessage(md)
+				err := f.Fill(i)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if c.hasErr {
+					if err == nil {
+						t.Errorf("Fill must return an error, but got nil")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Fill must not return an error, but got an error: '%s'", err)
+				}
+				actual = append(actual, i.Get(md.Fields().ByName("p")).String())
+			}
+			if strings.Join(actual, ",") != strings.Join(c.expected, ",") {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package fill
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ktr0731/evans/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLFiller is a Filler implementation that reads messages from a stream of YAML documents.
+// Each document corresponds to a message, so client streaming RPCs accept multi-document input.
+// The document structure is the same as the JSON mapping of Protocol Buffers.
+type YAMLFiller struct {
+	dec *protojson.UnmarshalOptions
+	in  *yaml.Decoder
+}
+
+// NewYAMLFiller receives input as io.Reader and returns an instance of YAMLFiller.
+// resolver is used to resolve types of google.protobuf.Any and extensions.
+// If it is nil, protoregistry.GlobalTypes is used.
+func NewYAMLFiller(in io.Reader, resolver proto.TypeResolver) *YAMLFiller {
+	return &YAMLFiller{
+		dec: &protojson.UnmarshalOptions{Resolver: resolver},
+		in:  yaml.NewDecoder(in),
+	}
+}
+
+// Fill fills values of each field from a YAML document. If the document is invalid YAML format,
+// Fill returns an error.
+func (f *YAMLFiller) Fill(v *dynamicpb.Message) error {
+	var in interface{}
+	if err := f.in.Decode(&in); err != nil {
+		return err
+	}
+	// An empty document means an empty message.
+	if in == nil {
+		in = map[string]interface{}{}
+	}
+
+	b, err := json.Marshal(ToJSONCompatible(in))
+	if err != nil {
+		return err
+	}
+
+	return f.dec.Unmarshal(b, v)
+}
+
+// ToJSONCompatible converts maps that have non-string keys, which are decoded from YAML or TOML, into
+// map[string]interface{} recursively because encoding/json cannot encode them.
+func ToJSONCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = ToJSONCompatible(e)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = ToJSONCompatible(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = ToJSONCompatible(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,79 @@
+package fill_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/evans/fill"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestYAMLFiller(t *testing.T) {
+	cases := map[string]struct {
+		in       string
+		expected []string
+		// values are the expected values of the other fields of the last message.
+		values map[protoreflect.Name]interface{}
+		hasErr bool
+	}{
+		"normal":        {in: `p: bar`, expected: []string{"bar"}},
+		"with comments": {in: "# comment\np: bar # trailing\n", expected: []string{"bar"}},
+		"64-bit integers": {
+			in:       "e: -9223372036854775808\nh: 18446744073709551615\np: bar",
+			expected: []string{"bar"},
+			values: map[protoreflect.Name]interface{}{
+				"e": int64(-9223372036854775808),
+				"h": uint64(18446744073709551615),
+			},
+		},
+		"multiple documents": {
+			in:       "p: foo\n---\np: bar\n",
+			expected: []string{"foo", "bar"},
+		},
+		"empty input":   {in: ""},
+		"invalid YAML":  {in: `p: [`, hasErr: true},
+		"unknown field": {in: `foo: bar`, hasErr: true},
+	}
+
+	md := compileTestMessage(t, "Message")
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f := fill.NewYAMLFiller(strings.NewReader(c.in), nil)
+			var (
+				actual []string
+				last   *dynamicpb.Message
+			)
+			for {
+				i := dynamicpb.NewMessage(md)
+				err := f.Fill(i)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if c.hasErr {
+					if err == nil {
+						t.Errorf("Fill must return an error, but got nil")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Fill must not return an error, but got an error: '%s'", err)
+				}
+				actual = append(actual, i.Get(md.Fields().ByName("p")).String())
+				last = i
+			}
+			if strings.Join(actual, ",") != strings.Join(c.expected, ",") {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+			for name, expected := range c.values {
+				if v := last.Get(md.Fields().ByName(name)).Interface(); v != expected {
+					t.Errorf("%s: expected %v, but got %v", name, expected, v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Package binary provides a formatter implementation that writes messages in the Protocol Buffers wire format.
+package binary
+
+import (
+	"io"
+
+	"github.com/ktr0731/evans/format"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// responseFormatter is a formatter that writes messages in the wire format.
+// Header, trailer and status are ignored because they are not messages.
+type responseFormatter struct {
+	w         io.Writer
+	delimited bool
+}
+
+// NewResponseFormatter returns a formatter for the wire format. If delimited is false, the message is written as it
+// is, so the output can be decoded by "protoc --decode". If delimited is true, each message is prefixed by its length
+// as a varint to separate messages of a stream. It is the same framing as writeDelimitedTo in the Java implementation.
+func NewResponseFormatter(w io.Writer, delimited bool) format.ResponseFormatterInterface {
+	return &responseFormatter{w: w, delimited: delimited}
+}
+
+func (p *responseFormatter) FormatHeader(metadata.MD) {}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	if p.delimited {
+		if _, err := protodelim.MarshalTo(p.w, v.(proto.Message)); err != nil {
+			return errors.Wrap(err, "failed to write the message in the wire format")
+		}
+		return nil
+	}
+	b, err := proto.Marshal(v.(proto.Message))
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the message into the wire format")
+	}
+	if _, err := p.w.Write(b); err != nil {
+		return errors.Wrap(err, "failed to write the message in the wire format")
+	}
+	return nil
+}
+
+func (p *responseFormatter) FormatTrailer(metadata.MD) {}
+
+func (p *responseFormatter) FormatStatus(*status.Status) error {
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	return nil
+}
@@ -0,0 +1,68 @@
+package binary_test
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/ktr0731/evans/format/binary"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+func format(t *testing.T, delimited bool, msgs ...proto.Message) []byte {
+	t.Helper()
+
+	var w bytes.Buffer
+	f := binary.NewResponseFormatter(&w, delimited)
+	f.FormatHeader(metadata.Pairs("key", "val"))
+	for _, m := range msgs {
+		if err := f.FormatMessage(m); err != nil {
+			t.Fatalf("FormatMessage must not return an error, but got '%s'", err)
+		}
+	}
+	f.FormatTrailer(metadata.Pairs("key", "val"))
+	if err := f.FormatStatus(status.New(codes.OK, "")); err != nil {
+		t.Fatalf("FormatStatus must not return an error, but got '%s'", err)
+	}
+	if err := f.Done(); err != nil {
+		t.Fatalf("Done must not return an error, but got '%s'", err)
+	}
+	return w.Bytes()
+}
+
+func TestResponseFormatter(t *testing.T) {
+	msgs := []proto.Message{&errdetails.ErrorInfo{Reason: "oumae"}, &errdetails.ErrorInfo{Reason: "kousaka"}}
+
+	t.Run("not delimited", func(t *testing.T) {
+		b := format(t, false, msgs[0])
+		// Header, trailer and status must not be written, so the output is decodable as a message.
+		var actual errdetails.ErrorInfo
+		if err := proto.Unmarshal(b, &actual); err != nil {
+			t.Fatalf("the output must be decodable as a message, but got '%s'", err)
+		}
+		if !proto.Equal(msgs[0], &actual) {
+			t.Errorf("expected %v, but got %v", msgs[0], &actual)
+		}
+	})
+
+	t.Run("delimited", func(t *testing.T) {
+		r := bufio.NewReader(bytes.NewReader(format(t, true, msgs...)))
+		for _, expected := range msgs {
+			var actual errdetails.ErrorInfo
+			if err := protodelim.UnmarshalFrom(r, &actual); err != nil {
+				t.Fatalf("the output must be decodable as delimited messages, but got '%s'", err)
+			}
+			if !proto.Equal(expected, &actual) {
+				t.Errorf("expected %v, but got %v", expected, &actual)
+			}
+		}
+		if r.Buffered() != 0 {
+			t.Errorf("unexpected trailing bytes: %d bytes", r.Buffered())
+		}
+	})
+}
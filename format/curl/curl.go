@@ -2,42 +2,45 @@
 package curl
 
 import (
-	"bytes"
 	gojson "encoding/json"
 	"fmt"
 	"io"
 	"sort"
 	"strings"
 
-	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
-	"github.com/golang/protobuf/proto"  //nolint:staticcheck
 	"github.com/ktr0731/evans/format"
 	"github.com/ktr0731/evans/present"
 	"github.com/ktr0731/evans/present/json"
-	"github.com/pkg/errors"
+	pb "github.com/ktr0731/evans/proto"
 	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // For calling RegisterType.
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 type responseFormatter struct {
 	w io.Writer
 
 	json        present.Presenter
-	pbMarshaler *jsonpb.Marshaler
+	pbMarshaler *protojson.MarshalOptions
+	resolver    pb.TypeResolver
 
 	wroteHeader, wroteMessage, wroteTrailer bool
 }
 
-func NewResponseFormatter(w io.Writer, emitDefaults bool) format.ResponseFormatterInterface {
+// NewResponseFormatter returns a curl-like formatter.
+// resolver is used to resolve types of google.protobuf.Any. If it is nil, protoregistry.GlobalTypes is used.
+func NewResponseFormatter(w io.Writer, emitDefaults bool, resolver pb.TypeResolver) format.ResponseFormatterInterface {
 	return &responseFormatter{
 		w:    w,
 		json: json.NewPresenter("  "),
-		pbMarshaler: &jsonpb.Marshaler{
-			EmitDefaults: emitDefaults,
+		pbMarshaler: &protojson.MarshalOptions{
+			EmitUnpopulated: emitDefaults,
+			Resolver:        resolver,
 		},
+		resolver: resolver,
 	}
 }
 
@@ -106,15 +109,11 @@ func (p *responseFormatter) FormatStatus(status *status.Status) error {
 		fmt.Fprintf(p.w, "\n")
 	}
 	fmt.Fprintf(p.w, "code: %s\nnumber: %d\nmessage: %q\n", status.Code().String(), status.Code(), status.Message())
-	if len(status.Details()) > 0 {
-		details := make([]string, 0, len(status.Details()))
-		for _, d := range status.Details() {
-			d, ok := d.(proto.Message)
-			if !ok {
-				continue
-			}
-			// Convert to Any to insert @type field.
-			m, err := p.convertProtoMessageAsAnyToMap(d)
+	if ds := format.StatusDetails(status, p.resolver); len(ds) > 0 {
+		details := make([]string, 0, len(ds))
+		// Details are formatted as Any to insert @type field.
+		for _, d := range ds {
+			m, err := p.convertProtoMessageToMap(d)
 			if err != nil {
 				return err
 			}
@@ -138,22 +137,13 @@ func (p *responseFormatter) Done() error {
 }
 
 func (p *responseFormatter) convertProtoMessageToMap(m proto.Message) (map[string]interface{}, error) {
-	var buf bytes.Buffer
-	err := p.pbMarshaler.Marshal(&buf, m)
+	b, err := p.pbMarshaler.Marshal(m)
 	if err != nil {
 		return nil, err
 	}
 	var res map[string]interface{}
-	if err := gojson.Unmarshal(buf.Bytes(), &res); err != nil {
+	if err := gojson.Unmarshal(b, &res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
-
-func (p *responseFormatter) convertProtoMessageAsAnyToMap(m proto.Message) (map[string]interface{}, error) {
-	any, err := anypb.New(proto.MessageV2(m))
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to convert a message to *any.Any")
-	}
-	return p.convertProtoMessageToMap(any)
-}
@@ -2,8 +2,12 @@
 package format
 
 import (
+	"github.com/ktr0731/evans/logger"
+	"github.com/ktr0731/evans/proto"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // ResponseFormatter provides formatting feature for gRPC response.
@@ -72,3 +76,20 @@ type ResponseFormatterInterface interface {
 	// The client of ResponseFormatter should call it at the end.
 	Done() error
 }
+
+// StatusDetails returns the details of s as Any messages. Details that resolver cannot resolve are skipped
+// because they cannot be formatted. If resolver is nil, protoregistry.GlobalTypes is used.
+func StatusDetails(s *status.Status, resolver proto.TypeResolver) []*anypb.Any {
+	if resolver == nil {
+		resolver = protoregistry.GlobalTypes
+	}
+	var details []*anypb.Any
+	for _, d := range s.Proto().GetDetails() {
+		if _, err := resolver.FindMessageByURL(d.GetTypeUrl()); err != nil {
+			logger.Printf("skip the status detail because its type is unknown: %s", err)
+			continue
+		}
+		details = append(details, d)
+	}
+	return details
+}
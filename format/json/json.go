@@ -2,20 +2,18 @@
 package json
 
 import (
-	"bytes"
 	gojson "encoding/json"
 	"io"
 
-	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
-	"github.com/golang/protobuf/proto"  //nolint:staticcheck
 	"github.com/ktr0731/evans/format"
 	"github.com/ktr0731/evans/present"
 	"github.com/ktr0731/evans/present/json"
-	"github.com/pkg/errors"
+	pb "github.com/ktr0731/evans/proto"
 	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // For calling RegisterType.
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 // responseFormatter is a formatter that formats *usecase.GRPCResponse into a JSON object.
@@ -33,13 +31,17 @@ type responseFormatter struct {
 		Trailer  *metadata.MD             `json:"trailer,omitempty"`
 	}
 	p           present.Presenter
-	pbMarshaler *jsonpb.Marshaler
+	pbMarshaler *protojson.MarshalOptions
+	resolver    pb.TypeResolver
 }
 
-func NewResponseFormatter(w io.Writer, emitDefaults bool) format.ResponseFormatterInterface {
-	return &responseFormatter{w: w, p: json.NewPresenter("  "), pbMarshaler: &jsonpb.Marshaler{
-		EmitDefaults: emitDefaults,
-	}}
+// NewResponseFormatter returns a JSON formatter.
+// resolver is used to resolve types of google.protobuf.Any. If it is nil, protoregistry.GlobalTypes is used.
+func NewResponseFormatter(w io.Writer, emitDefaults bool, resolver pb.TypeResolver) format.ResponseFormatterInterface {
+	return &responseFormatter{w: w, p: json.NewPresenter("  "), pbMarshaler: &protojson.MarshalOptions{
+		EmitUnpopulated: emitDefaults,
+		Resolver:        resolver,
+	}, resolver: resolver}
 }
 
 func (p *responseFormatter) FormatHeader(header metadata.MD) {
@@ -61,15 +63,11 @@ func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {
 
 func (p *responseFormatter) FormatStatus(s *status.Status) error {
 	var details []interface{}
-	if len(s.Details()) != 0 {
-		details = make([]interface{}, 0, len(s.Details()))
-		for _, d := range s.Details() {
-			d, ok := d.(proto.Message)
-			if !ok {
-				continue
-			}
-			// Convert to Any to insert @type field.
-			m, err := p.convertProtoMessageAsAnyToMap(d)
+	if ds := format.StatusDetails(s, p.resolver); len(ds) != 0 {
+		details = make([]interface{}, 0, len(ds))
+		// Details are formatted as Any to insert @type field.
+		for _, d := range ds {
+			m, err := p.convertProtoMessageToMap(d)
 			if err != nil {
 				return err
 			}
@@ -101,22 +99,26 @@ func (p *responseFormatter) Done() error {
 }
 
 func (p *responseFormatter) convertProtoMessageToMap(m proto.Message) (map[string]interface{}, error) {
-	var buf bytes.Buffer
-	err := p.pbMarshaler.Marshal(&buf, m)
+	return convertProtoMessageToMap(p.pbMarshaler, m)
+}
+
+// ConvertMessage converts m into a map which is encoded as the JSON mapping of Protocol Buffers by encoding/json.
+// resolver is used to resolve types of google.protobuf.Any. If it is nil, protoregistry.GlobalTypes is used.
+func ConvertMessage(m proto.Message, emitDefaults bool, resolver pb.TypeResolver) (map[string]interface{}, error) {
+	return convertProtoMessageToMap(&protojson.MarshalOptions{
+		EmitUnpopulated: emitDefaults,
+		Resolver:        resolver,
+	}, m)
+}
+
+func convertProtoMessageToMap(marshaler *protojson.MarshalOptions, m proto.Message) (map[string]interface{}, error) {
+	b, err := marshaler.Marshal(m)
 	if err != nil {
 		return nil, err
 	}
 	var res map[string]interface{}
-	if err := gojson.Unmarshal(buf.Bytes(), &res); err != nil {
+	if err := gojson.Unmarshal(b, &res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
-
-func (p *responseFormatter) convertProtoMessageAsAnyToMap(m proto.Message) (map[string]interface{}, error) {
-	any, err := anypb.New(proto.MessageV2(m))
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to convert a message to *any.Any")
-	}
-	return p.convertProtoMessageToMap(any)
-}
@@ -0,0 +1,119 @@
+// Package ndjson provides a newline-delimited JSON formatter implementation.
+// Unlike format/json, each response event is written as soon as it is formatted.
+package ndjson
+
+import (
+	"bytes"
+	gojson "encoding/json"
+	"io"
+
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/logger"
+	pb "github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // For calling RegisterType.
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type statusEvent struct {
+	Code    string              `json:"code"`
+	Number  uint32              `json:"number"`
+	Message string              `json:"message"`
+	Details []gojson.RawMessage `json:"details,omitempty"`
+}
+
+// event is a line written by responseFormatter. Exactly one of the fields is set.
+type event struct {
+	Header  *metadata.MD      `json:"header,omitempty"`
+	Message gojson.RawMessage `json:"message,omitempty"`
+	Trailer *metadata.MD      `json:"trailer,omitempty"`
+	Status  *statusEvent      `json:"status,omitempty"`
+}
+
+// responseFormatter is a formatter that writes each response event as a self-contained JSON object
+// followed by a newline.
+type responseFormatter struct {
+	w           io.Writer
+	pbMarshaler *protojson.MarshalOptions
+	resolver    pb.TypeResolver
+}
+
+// NewResponseFormatter returns a newline-delimited JSON formatter.
+// resolver is used to resolve types of google.protobuf.Any. If it is nil, protoregistry.GlobalTypes is used.
+func NewResponseFormatter(w io.Writer, emitDefaults bool, resolver pb.TypeResolver) format.ResponseFormatterInterface {
+	return &responseFormatter{w: w, pbMarshaler: &protojson.MarshalOptions{
+		EmitUnpopulated: emitDefaults,
+		Resolver:        resolver,
+	}, resolver: resolver}
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {
+	if err := p.write(&event{Header: &header}); err != nil {
+		logger.Printf("failed to write the response header: %s", err)
+	}
+}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	b, err := p.marshal(v.(proto.Message))
+	if err != nil {
+		return err
+	}
+	return p.write(&event{Message: b})
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {
+	if err := p.write(&event{Trailer: &trailer}); err != nil {
+		logger.Printf("failed to write the response trailer: %s", err)
+	}
+}
+
+func (p *responseFormatter) FormatStatus(s *status.Status) error {
+	var details []gojson.RawMessage
+	if ds := format.StatusDetails(s, p.resolver); len(ds) != 0 {
+		details = make([]gojson.RawMessage, 0, len(ds))
+		// Details are formatted as Any to insert @type field.
+		for _, d := range ds {
+			b, err := p.marshal(d)
+			if err != nil {
+				return err
+			}
+			details = append(details, b)
+		}
+	}
+
+	return p.write(&event{Status: &statusEvent{
+		Code:    s.Code().String(),
+		Number:  uint32(s.Code()),
+		Message: s.Message(),
+		Details: details,
+	}})
+}
+
+func (p *responseFormatter) Done() error {
+	return nil
+}
+
+func (p *responseFormatter) marshal(m proto.Message) (gojson.RawMessage, error) {
+	b, err := p.pbMarshaler.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	// Compact the output because protojson may insert spaces.
+	var compacted bytes.Buffer
+	if err := gojson.Compact(&compacted, b); err != nil {
+		return nil, err
+	}
+	return compacted.Bytes(), nil
+}
+
+func (p *responseFormatter) write(e *event) error {
+	b, err := gojson.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode an event into JSON")
+	}
+	_, err = p.w.Write(append(b, '\n'))
+	return err
+}
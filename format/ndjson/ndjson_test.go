@@ -0,0 +1,88 @@
+package ndjson_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/format/ndjson"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestResponseFormatter(t *testing.T) {
+	cases := map[string]struct {
+		emitDefaults bool
+		status       func(t *testing.T) *status.Status
+		expected     string
+	}{
+		"ok": {
+			status: func(*testing.T) *status.Status { return status.New(codes.OK, "") },
+			expected: `{"header":{"key":["val"]}}
+{"message":{"reason":"oumae"}}
+{"message":{}}
+{"trailer":{"key":["val"]}}
+{"status":{"code":"OK","number":0,"message":""}}
+`,
+		},
+		"emitDefaults": {
+			emitDefaults: true,
+			status:       func(*testing.T) *status.Status { return status.New(codes.OK, "") },
+			expected: `{"header":{"key":["val"]}}
+{"message":{"reason":"oumae","domain":"","metadata":{}}}
+{"message":{"reason":"","domain":"","metadata":{}}}
+{"trailer":{"key":["val"]}}
+{"status":{"code":"OK","number":0,"message":""}}
+`,
+		},
+		"non-OK status with details": {
+			status: func(t *testing.T) *status.Status {
+				s, err := status.New(codes.InvalidArgument, "invalid name").WithDetails(&errdetails.BadRequest{
+					FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "empty"}},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+			expected: `{"header":{"key":["val"]}}
+{"message":{"reason":"oumae"}}
+{"message":{}}
+{"trailer":{"key":["val"]}}
+{"status":{"code":"InvalidArgument","number":3,"message":"invalid name","details":[{"@type":"type.googleapis.com/google.rpc.BadRequest","fieldViolations":[{"field":"name","description":"empty"}]}]}}
+`,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var w bytes.Buffer
+			f := ndjson.NewResponseFormatter(&w, c.emitDefaults, nil)
+
+			// Each event must be written as soon as it is formatted.
+			f.FormatHeader(metadata.Pairs("key", "val"))
+			if w.Len() == 0 {
+				t.Fatal("FormatHeader must write the header immediately")
+			}
+			for _, m := range []*errdetails.ErrorInfo{{Reason: "oumae"}, {}} {
+				if err := f.FormatMessage(m); err != nil {
+					t.Fatalf("FormatMessage must not return an error, but got '%s'", err)
+				}
+			}
+			f.FormatTrailer(metadata.Pairs("key", "val"))
+			if err := f.FormatStatus(c.status(t)); err != nil {
+				t.Fatalf("FormatStatus must not return an error, but got '%s'", err)
+			}
+			if err := f.Done(); err != nil {
+				t.Fatalf("Done must not return an error, but got '%s'", err)
+			}
+
+			if diff := cmp.Diff(c.expected, w.String()); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package textproto provides a Protocol Buffers text format formatter implementation.
+package textproto
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/format"
+	pb "github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // For calling RegisterType.
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+// responseFormatter is a formatter that formats messages in the text format.
+// Messages are separated by fill.TextSeparator, so the output can be used as input for fill.TextFiller.
+// Header, trailer and status are written as comments.
+type responseFormatter struct {
+	w io.Writer
+
+	marshaler *prototext.MarshalOptions
+	resolver  pb.TypeResolver
+
+	wroteMessage bool
+}
+
+// NewResponseFormatter returns a formatter for the text format.
+// Note that the text format always omits fields with default values.
+// resolver is used to resolve types of google.protobuf.Any. If it is nil, protoregistry.GlobalTypes is used.
+func NewResponseFormatter(w io.Writer, resolver pb.TypeResolver) format.ResponseFormatterInterface {
+	return &responseFormatter{
+		w: w,
+		marshaler: &prototext.MarshalOptions{
+			Multiline: true,
+			Indent:    "  ",
+			Resolver:  resolver,
+		},
+		resolver: resolver,
+	}
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {
+	writeMetadataAsComment(p.w, header)
+	fmt.Fprintf(p.w, "\n")
+}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	b, err := p.marshaler.Marshal(v.(proto.Message))
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the message into the text format")
+	}
+	if p.wroteMessage {
+		fmt.Fprintf(p.w, "%s\n", fill.TextSeparator)
+	}
+	if _, err := p.w.Write(b); err != nil {
+		return err
+	}
+
+	p.wroteMessage = true
+
+	return nil
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {
+	if len(trailer) == 0 {
+		return
+	}
+	fmt.Fprintf(p.w, "\n")
+	writeMetadataAsComment(p.w, trailer)
+}
+
+func (p *responseFormatter) FormatStatus(status *status.Status) error {
+	fmt.Fprintf(p.w, "\n# code: %s\n# number: %d\n# message: %q\n", status.Code().String(), status.Code(), status.Message())
+	ds := format.StatusDetails(status, p.resolver)
+	if len(ds) == 0 {
+		return nil
+	}
+	fmt.Fprintf(p.w, "# details:\n")
+	for _, d := range ds {
+		b, err := prototext.MarshalOptions{Resolver: p.resolver}.Marshal(d)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal the detail into the text format")
+		}
+		fmt.Fprintf(p.w, "#   %s\n", b)
+	}
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	return nil
+}
+
+func writeMetadataAsComment(w io.Writer, md metadata.MD) {
+	var s []string
+	for k, v := range md {
+		for _, vv := range v {
+			s = append(s, fmt.Sprintf("# %s: %s", k, vv))
+		}
+	}
+	sort.Strings(s)
+	for _, l := range s {
+		fmt.Fprintf(w, "%s\n", l)
+	}
+}
@@ -0,0 +1,82 @@
+package textproto_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/format/textproto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestResponseFormatter(t *testing.T) {
+	s, err := status.New(codes.InvalidArgument, "invalid name").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "empty"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []proto.Message{&errdetails.ErrorInfo{Reason: "oumae"}, &errdetails.ErrorInfo{}}
+
+	var w bytes.Buffer
+	f := textproto.NewResponseFormatter(&w, nil)
+	f.FormatHeader(metadata.Pairs("key", "val"))
+	for _, m := range msgs {
+		if err := f.FormatMessage(m); err != nil {
+			t.Fatalf("FormatMessage must not return an error, but got '%s'", err)
+		}
+	}
+	f.FormatTrailer(metadata.Pairs("key", "val"))
+	if err := f.FormatStatus(s); err != nil {
+		t.Fatalf("FormatStatus must not return an error, but got '%s'", err)
+	}
+	if err := f.Done(); err != nil {
+		t.Fatalf("Done must not return an error, but got '%s'", err)
+	}
+
+	// The spacing of the text format is unstable, so messages are compared after decoding them,
+	// and only comments are compared as they are.
+	var comments []string
+	for _, l := range strings.Split(w.String(), "\n") {
+		if strings.HasPrefix(l, "#") {
+			comments = append(comments, l)
+		}
+	}
+	if len(comments) != 7 {
+		t.Fatalf("unexpected output:\n%s", w.String())
+	}
+	// The last comment is the detail in the text format of Any.
+	if !strings.HasPrefix(comments[6], "#   [type.googleapis.com/google.rpc.BadRequest]:") {
+		t.Errorf("the detail must be written in the text format, but got '%s'", comments[6])
+	}
+	expectedComments := []string{
+		"# key: val",
+		"# key: val",
+		"# code: InvalidArgument",
+		"# number: 3",
+		`# message: "invalid name"`,
+		"# details:",
+	}
+	if diff := cmp.Diff(expectedComments, comments[:6]); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	// The output must be readable by TextFiller.
+	filler := fill.NewTextFiller(strings.NewReader(w.String()), nil)
+	for _, expected := range msgs {
+		actual := dynamicpb.NewMessage(expected.ProtoReflect().Descriptor())
+		if err := filler.Fill(actual); err != nil {
+			t.Fatalf("Fill must not return an error, but got '%s'", err)
+		}
+		if !proto.Equal(expected, actual) {
+			t.Errorf("expected %v, but got %v", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package yaml provides a YAML formatter implementation.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ktr0731/evans/format"
+	pb "github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // For calling RegisterType.
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// responseFormatter is a formatter that formats each message as a YAML document.
+// The document structure is the same as the JSON mapping of Protocol Buffers, so the output
+// can be used as input for fill.YAMLFiller. Header, trailer and status are written as comments.
+type responseFormatter struct {
+	w io.Writer
+
+	marshaler *protojson.MarshalOptions
+	resolver  pb.TypeResolver
+	enc       *yaml.Encoder
+}
+
+// NewResponseFormatter returns a YAML formatter.
+// resolver is used to resolve types of google.protobuf.Any. If it is nil, protoregistry.GlobalTypes is used.
+func NewResponseFormatter(w io.Writer, emitDefaults bool, resolver pb.TypeResolver) format.ResponseFormatterInterface {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	return &responseFormatter{
+		w:   w,
+		enc: enc,
+		marshaler: &protojson.MarshalOptions{
+			EmitUnpopulated: emitDefaults,
+			Resolver:        resolver,
+		},
+		resolver: resolver,
+	}
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {
+	writeMetadataAsComment(p.w, header)
+	fmt.Fprintf(p.w, "\n")
+}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	n, err := p.convertProtoMessageToNode(v.(proto.Message))
+	if err != nil {
+		return err
+	}
+	// yaml.Encoder inserts the document separator between messages.
+	if err := p.enc.Encode(n); err != nil {
+		return errors.Wrap(err, "failed to encode the message into YAML")
+	}
+	return nil
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {
+	if len(trailer) == 0 {
+		return
+	}
+	fmt.Fprintf(p.w, "\n")
+	writeMetadataAsComment(p.w, trailer)
+}
+
+func (p *responseFormatter) FormatStatus(status *status.Status) error {
+	fmt.Fprintf(p.w, "\n# code: %s\n# number: %d\n# message: %q\n", status.Code().String(), status.Code(), status.Message())
+	ds := format.StatusDetails(status, p.resolver)
+	if len(ds) == 0 {
+		return nil
+	}
+	fmt.Fprintf(p.w, "# details:\n")
+	// Details are formatted as Any to insert @type field.
+	for _, d := range ds {
+		b, err := protojson.MarshalOptions{Resolver: p.resolver}.Marshal(d)
+		if err != nil {
+			return err
+		}
+		// Compact the output because protojson may insert spaces.
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, b); err != nil {
+			return err
+		}
+		fmt.Fprintf(p.w, "#   %s\n", compacted.Bytes())
+	}
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	return p.enc.Close()
+}
+
+// convertProtoMessageToNode converts m into a YAML node via the JSON mapping.
+// Unlike converting via a map, it preserves the field order.
+func (p *responseFormatter) convertProtoMessageToNode(m proto.Message) (*yaml.Node, error) {
+	b, err := p.marshaler.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var n yaml.Node
+	if err := yaml.Unmarshal(b, &n); err != nil {
+		return nil, errors.Wrap(err, "failed to convert JSON into a YAML node")
+	}
+	resetStyle(&n)
+	return &n, nil
+}
+
+// resetStyle clears the flow style of the JSON input to print nodes in the block style.
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
+
+func writeMetadataAsComment(w io.Writer, md metadata.MD) {
+	var s []string
+	for k, v := range md {
+		for _, vv := range v {
+			s = append(s, fmt.Sprintf("# %s: %s", k, vv))
+		}
+	}
+	sort.Strings(s)
+	fmt.Fprint(w, strings.Join(s, "\n"))
+	if len(s) != 0 {
+		fmt.Fprintf(w, "\n")
+	}
+}
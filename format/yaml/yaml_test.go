@@ -0,0 +1,90 @@
+package yaml_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/format/yaml"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestResponseFormatter(t *testing.T) {
+	cases := map[string]struct {
+		emitDefaults bool
+		expected     string
+	}{
+		"normal": {
+			expected: `# key: val
+
+reason: oumae
+---
+{}
+
+# key: val
+
+# code: InvalidArgument
+# number: 3
+# message: "invalid name"
+# details:
+#   {"@type":"type.googleapis.com/google.rpc.BadRequest","fieldViolations":[{"field":"name","description":"empty"}]}
+`,
+		},
+		"emitDefaults": {
+			emitDefaults: true,
+			expected: `# key: val
+
+reason: oumae
+domain: ""
+metadata: {}
+---
+reason: ""
+domain: ""
+metadata: {}
+
+# key: val
+
+# code: InvalidArgument
+# number: 3
+# message: "invalid name"
+# details:
+#   {"@type":"type.googleapis.com/google.rpc.BadRequest","fieldViolations":[{"field":"name","description":"empty"}]}
+`,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			s, err := status.New(codes.InvalidArgument, "invalid name").WithDetails(&errdetails.BadRequest{
+				FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "empty"}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var w bytes.Buffer
+			f := yaml.NewResponseFormatter(&w, c.emitDefaults, nil)
+			f.FormatHeader(metadata.Pairs("key", "val"))
+			for _, m := range []*errdetails.ErrorInfo{{Reason: "oumae"}, {}} {
+				if err := f.FormatMessage(m); err != nil {
+					t.Fatalf("FormatMessage must not return an error, but got '%s'", err)
+				}
+			}
+			f.FormatTrailer(metadata.Pairs("key", "val"))
+			if err := f.FormatStatus(s); err != nil {
+				t.Fatalf("FormatStatus must not return an error, but got '%s'", err)
+			}
+			if err := f.Done(); err != nil {
+				t.Fatalf("Done must not return an error, but got '%s'", err)
+			}
+
+			if diff := cmp.Diff(c.expected, w.String()); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -5,13 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 	"time"
 
-	"crypto/tls"
-	"crypto/x509"
-
 	"github.com/hashicorp/go-multierror"
 	"github.com/ktr0731/evans/grpc/grpcreflection"
 	"github.com/ktr0731/evans/logger"
@@ -101,10 +97,30 @@ type BidiStream interface {
 type client struct {
 	conn    *grpc.ClientConn
 	headers Headers
+	// keyLog is the key log file. It is nil if the key log is disabled.
+	keyLog io.Closer
 
 	grpcreflection.Client
 }
 
+type clientOptions struct {
+	perRPCCredentials  credentials.PerRPCCredentials
+	insecureSkipVerify bool
+	tlsMinVersion      uint16
+	cipherSuites       []uint16
+	keyLogFile         string
+}
+
+// ClientOption configures optional behaviors of NewClient and NewWebClient.
+type ClientOption func(*clientOptions)
+
+// WithPerRPCCredentials attaches creds to each RPC, including gRPC reflection requests.
+func WithPerRPCCredentials(creds credentials.PerRPCCredentials) ClientOption {
+	return func(o *clientOptions) {
+		o.perRPCCredentials = creds
+	}
+}
+
 // NewClient creates a new gRPC client. It dials to the server specified by addr.
 // addr format is the same as the first argument of grpc.Dial.
 // If serverName is not empty, it overrides the gRPC server name used to
@@ -114,36 +130,30 @@ type client struct {
 //
 // The set of cert and certKey enables mutual authentication if useTLS is enabled.
 // If one of it is not found, NewClient returns ErrMutualAuthParamsAreNotEnough.
-// If useTLS is false, cacert, cert and certKey are ignored.
-func NewClient(addr, serverName string, useReflection, useTLS bool, cacert, cert, certKey string, headers map[string][]string) (Client, error) {
-	var opts []grpc.DialOption
+// If useTLS is false, cacert, cert, certKey and TLS related options are ignored.
+func NewClient(addr, serverName string, useReflection, useTLS bool, cacert, cert, certKey string, headers map[string][]string, clientOpts ...ClientOption) (Client, error) {
+	var copts clientOptions
+	for _, o := range clientOpts {
+		o(&copts)
+	}
+
+	var (
+		opts   []grpc.DialOption
+		keyLog io.Closer
+	)
+	if copts.perRPCCredentials != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(copts.perRPCCredentials))
+	}
 	if !useTLS {
 		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	} else { // Enable TLS authentication
-		var tlsCfg tls.Config
-		if cacert != "" {
-			b, err := os.ReadFile(cacert)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to read the CA certificate")
-			}
-			cp := x509.NewCertPool()
-			if !cp.AppendCertsFromPEM(b) {
-				return nil, errors.New("failed to append the client certificate")
-			}
-			tlsCfg.RootCAs = cp
-		}
-		if cert != "" && certKey != "" {
-			// Enable mutual authentication
-			certificate, err := tls.LoadX509KeyPair(cert, certKey)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to read the client certificate")
-			}
-			tlsCfg.Certificates = append(tlsCfg.Certificates, certificate)
-		} else if cert != "" || certKey != "" {
-			return nil, ErrMutualAuthParamsAreNotEnough
+		tlsCfg, closer, err := newTLSConfig(cacert, cert, certKey, &copts)
+		if err != nil {
+			return nil, err
 		}
+		keyLog = closer
 
-		creds := credentials.NewTLS(&tlsCfg)
+		creds := credentials.NewTLS(tlsCfg)
 		opts = append(opts, grpc.WithTransportCredentials(creds))
 
 		if serverName != "" {
@@ -154,12 +164,16 @@ func NewClient(addr, serverName string, useReflection, useTLS bool, cacert, cert
 	defer cancel()
 	conn, err := grpc.DialContext(ctx, addr, opts...)
 	if err != nil {
+		if keyLog != nil {
+			keyLog.Close()
+		}
 		return nil, errors.Wrap(err, "failed to dial to gRPC server")
 	}
 
 	client := &client{
 		conn:    conn,
 		headers: Headers{},
+		keyLog:  keyLog,
 	}
 
 	if useReflection {
@@ -199,6 +213,11 @@ func (c *client) Close(ctx context.Context) error {
 		if err := c.conn.Close(); err != nil {
 			result = multierror.Append(result, errors.Wrap(err, "failed to close gRPC client"))
 		}
+		if c.keyLog != nil {
+			if err := c.keyLog.Close(); err != nil {
+				result = multierror.Append(result, errors.Wrap(err, "failed to close the key log file"))
+			}
+		}
 		doneCh <- result
 	}()
 
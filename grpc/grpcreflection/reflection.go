@@ -6,9 +6,9 @@ import (
 	"context"
 	"strings"
 
+	"github.com/jhump/protoreflect/desc"
 	gr "github.com/jhump/protoreflect/grpcreflect"
-	"github.com/ktr0731/grpc-web-go-client/grpcweb"
-	"github.com/ktr0731/grpc-web-go-client/grpcweb/grpcweb_reflection_v1alpha"
+	"github.com/ktr0731/evans/logger"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -18,8 +18,17 @@ import (
 	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
-// ServiceName represents the gRPC reflection service name.
-const ServiceName = "grpc.reflection.v1alpha.ServerReflection"
+const (
+	// ServiceNameV1 represents the gRPC reflection v1 service name.
+	ServiceNameV1 = "grpc.reflection.v1.ServerReflection"
+	// ServiceNameV1Alpha represents the gRPC reflection v1alpha service name.
+	ServiceNameV1Alpha = "grpc.reflection.v1alpha.ServerReflection"
+)
+
+// IsReflectionService reports whether svcName is one of the gRPC reflection services.
+func IsReflectionService(svcName string) bool {
+	return svcName == ServiceNameV1 || svcName == ServiceNameV1Alpha
+}
 
 var ErrTLSHandshakeFailed = errors.New("TLS handshake failed")
 
@@ -30,6 +39,7 @@ type Client interface {
 	//   - ErrTLSHandshakeFailed: TLS misconfig.
 	ListServices() ([]string, error)
 	// FindSymbol returns the symbol associated with the given name.
+	// If the server doesn't have the symbol, FindSymbol returns an error wrapping protoregistry.NotFound.
 	FindSymbol(name string) (protoreflect.Descriptor, error)
 	// Reset clears internal states of Client.
 	Reset()
@@ -38,6 +48,8 @@ type Client interface {
 }
 
 type client struct {
+	// resolver holds the files resolved from the server. It is owned by the client so that descriptors of other
+	// servers or of the Go packages linked to Evans are never mixed.
 	resolver *protoregistry.Files
 	client   *gr.Client
 }
@@ -51,6 +63,7 @@ func getCtx(headers map[string][]string) context.Context {
 }
 
 // NewClient returns an instance of gRPC reflection client for gRPC protocol.
+// It tries gRPC reflection v1 first, and falls back to v1alpha if the server doesn't support v1.
 func NewClient(conn grpc.ClientConnInterface, headers map[string][]string) Client {
 	ctx := getCtx(headers)
 	// Use the same approach as grpcurl: NewClientAuto + AllowMissingFileDescriptors
@@ -59,20 +72,7 @@ func NewClient(conn grpc.ClientConnInterface, headers map[string][]string) Clien
 
 	return &client{
 		client:   reflectionClient,
-		resolver: protoregistry.GlobalFiles,
-	}
-}
-
-// NewWebClient returns an instance of gRPC reflection client for gRPC-Web protocol.
-func NewWebClient(conn *grpcweb.ClientConn, headers map[string][]string) Client {
-	ctx := getCtx(headers)
-	// Use the same pattern as the regular client
-	reflectionClient := gr.NewClientV1Alpha(ctx, grpcweb_reflection_v1alpha.NewServerReflectionClient(conn))
-	reflectionClient.AllowMissingFileDescriptors() // Apply the same fix for web client
-
-	return &client{
-		client:   reflectionClient,
-		resolver: protoregistry.GlobalFiles,
+		resolver: new(protoregistry.Files),
 	}
 }
 
@@ -91,7 +91,15 @@ func (c *client) ListServices() ([]string, error) {
 		return nil, errors.Wrap(err, "failed to list services from reflection enabled gRPC server")
 	}
 
-	return svcs, nil
+	// Reflection services are provided by the server implicitly, so hide them.
+	filtered := make([]string, 0, len(svcs))
+	for _, svc := range svcs {
+		if !IsReflectionService(svc) {
+			filtered = append(filtered, svc)
+		}
+	}
+
+	return filtered, nil
 }
 
 func (c *client) FindSymbol(name string) (protoreflect.Descriptor, error) {
@@ -107,28 +115,53 @@ func (c *client) FindSymbol(name string) (protoreflect.Descriptor, error) {
 
 	// Get file from reflection - this should work now with AllowMissingFileDescriptors()
 	jfd, err := c.client.FileContainingSymbol(name)
+	if gr.IsElementNotFoundError(err) {
+		return nil, errors.Wrapf(protoregistry.NotFound, "%s", err)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to find file containing symbol")
 	}
 
+	if err := c.registerFile(jfd); err != nil {
+		return nil, err
+	}
+
+	return c.resolver.FindDescriptorByName(fullName)
+}
+
+// registerFile registers jfd and its dependencies to c.resolver.
+// Dependencies are registered first so that the imports of jfd are resolved as real files instead of placeholders.
+func (c *client) registerFile(jfd *desc.FileDescriptor) error {
+	if _, err := c.resolver.FindFileByPath(jfd.GetName()); err == nil {
+		return nil
+	}
+
+	for _, dep := range jfd.GetDependencies() {
+		if err := c.registerFile(dep); err != nil {
+			// Unresolved dependencies are allowed as placeholders.
+			logger.Printf("failed to register dependency %s of %s: %s", dep.GetName(), jfd.GetName(), err)
+		}
+	}
+
 	// Convert from jhump/protoreflect descriptor to protoreflect.Descriptor
 	opts := protodesc.FileOptions{
 		AllowUnresolvable: true,
 	}
 	fd, err := opts.New(jfd.AsFileDescriptorProto(), c.resolver)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create file descriptor")
+		return errors.Wrap(err, "failed to create file descriptor")
 	}
 
 	if err := c.resolver.RegisterFile(fd); err != nil {
-		return nil, errors.Wrap(err, "failed to register file descriptor")
+		return errors.Wrap(err, "failed to register file descriptor")
 	}
 
-	return c.resolver.FindDescriptorByName(fullName)
+	return nil
 }
 
 func (c *client) Reset() {
 	c.client.Reset()
+	c.resolver = new(protoregistry.Files)
 }
 
 // GetAllMessages extracts all message types from all available services without full dependency resolution
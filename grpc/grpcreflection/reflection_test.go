@@ -0,0 +1,97 @@
+package grpcreflection
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	v1pb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	v1alphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestClient(t *testing.T) {
+	cases := map[string]struct {
+		register func(s *grpc.Server)
+	}{
+		"v1 and v1alpha": {
+			register: func(s *grpc.Server) {
+				reflection.Register(s)
+			},
+		},
+		"v1 only": {
+			register: func(s *grpc.Server) {
+				v1pb.RegisterServerReflectionServer(s, reflection.NewServerV1(reflection.ServerOptions{Services: s}))
+			},
+		},
+		"v1alpha only": {
+			register: func(s *grpc.Server) {
+				v1alphapb.RegisterServerReflectionServer(s, reflection.NewServer(reflection.ServerOptions{Services: s}))
+			},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			lis := bufconn.Listen(1024 * 1024)
+			s := grpc.NewServer()
+			healthpb.RegisterHealthServer(s, health.NewServer())
+			c.register(s)
+			go s.Serve(lis) //nolint:errcheck
+			defer s.Stop()
+
+			conn, err := grpc.NewClient(
+				"passthrough:///bufnet",
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return lis.DialContext(ctx)
+				}),
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+			)
+			if err != nil {
+				t.Fatalf("failed to dial: %s", err)
+			}
+			defer conn.Close()
+
+			client := NewClient(conn, nil)
+			defer client.Reset()
+
+			svcs, err := client.ListServices()
+			if err != nil {
+				t.Fatalf("ListServices must not return an error, but got '%s'", err)
+			}
+			// Reflection services must be hidden.
+			if diff := cmp.Diff([]string{"grpc.health.v1.Health"}, svcs); diff != "" {
+				t.Errorf("unexpected services (-want, +got):\n%s", diff)
+			}
+
+			d, err := client.FindSymbol("grpc.health.v1.HealthCheckRequest")
+			if err != nil {
+				t.Fatalf("FindSymbol must not return an error, but got '%s'", err)
+			}
+			// The symbol must be resolved from the server instead of the linked healthpb package.
+			if d == healthpb.File_grpc_health_v1_health_proto.Messages().ByName("HealthCheckRequest") {
+				t.Error("FindSymbol must not return the descriptor registered to protoregistry.GlobalFiles")
+			}
+		})
+	}
+}
+
+func TestIsReflectionService(t *testing.T) {
+	cases := map[string]bool{
+		ServiceNameV1:           true,
+		ServiceNameV1Alpha:      true,
+		"grpc.health.v1.Health": false,
+	}
+	for svc, expected := range cases {
+		if actual := IsReflectionService(svc); actual != expected {
+			t.Errorf("%s: expected %t, but got %t", svc, expected, actual)
+		}
+	}
+}
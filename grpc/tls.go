@@ -0,0 +1,122 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSVersion returns the TLS version identified by name such as "1.2".
+func TLSVersion(name string) (uint16, error) {
+	v, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(name), "tls")]
+	if !ok {
+		names := make([]string, 0, len(tlsVersions))
+		for n := range tlsVersions {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return 0, errors.Errorf("unknown TLS version '%s', it must be one of %s", name, strings.Join(names, ", "))
+	}
+	return v, nil
+}
+
+// CipherSuites returns the IDs of the cipher suites identified by names such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+// Insecure cipher suites are also available.
+func CipherSuites(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := suites[strings.ToUpper(name)]
+		if !ok {
+			return nil, errors.Errorf("unknown cipher suite '%s'", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// WithInsecureSkipVerify disables the verification of the server certificate chain and the host name.
+func WithInsecureSkipVerify() ClientOption {
+	return func(o *clientOptions) {
+		o.insecureSkipVerify = true
+	}
+}
+
+// WithTLSMinVersion sets the minimum TLS version. It is ignored if TLS is disabled.
+func WithTLSMinVersion(v uint16) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsMinVersion = v
+	}
+}
+
+// WithCipherSuites restricts the cipher suites for TLS 1.0-1.2. It is ignored if TLS is disabled.
+func WithCipherSuites(ids []uint16) ClientOption {
+	return func(o *clientOptions) {
+		o.cipherSuites = ids
+	}
+}
+
+// WithKeyLogFile appends TLS master secrets to the file in NSS key log format.
+// It is used to decrypt captured packets by external programs such as Wireshark.
+func WithKeyLogFile(name string) ClientOption {
+	return func(o *clientOptions) {
+		o.keyLogFile = name
+	}
+}
+
+// newTLSConfig returns the TLS config for the client. If the key log file is enabled,
+// newTLSConfig also returns the opened file which must be closed by the caller.
+func newTLSConfig(cacert, cert, certKey string, opts *clientOptions) (*tls.Config, io.Closer, error) {
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: opts.insecureSkipVerify, //nolint:gosec
+		MinVersion:         opts.tlsMinVersion,
+		CipherSuites:       opts.cipherSuites,
+	}
+	if cacert != "" {
+		b, err := os.ReadFile(cacert)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read the CA certificate")
+		}
+		cp := x509.NewCertPool()
+		if !cp.AppendCertsFromPEM(b) {
+			return nil, nil, errors.New("failed to append the client certificate")
+		}
+		tlsCfg.RootCAs = cp
+	}
+	if cert != "" && certKey != "" {
+		// Enable mutual authentication
+		certificate, err := tls.LoadX509KeyPair(cert, certKey)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read the client certificate")
+		}
+		tlsCfg.Certificates = append(tlsCfg.Certificates, certificate)
+	} else if cert != "" || certKey != "" {
+		return nil, nil, ErrMutualAuthParamsAreNotEnough
+	}
+
+	if opts.keyLogFile == "" {
+		return tlsCfg, nil, nil
+	}
+	f, err := os.OpenFile(opts.keyLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to open the key log file")
+	}
+	tlsCfg.KeyLogWriter = f
+	return tlsCfg, f, nil
+}
@@ -0,0 +1,87 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTLSVersion(t *testing.T) {
+	cases := map[string]struct {
+		name     string
+		expected uint16
+		hasErr   bool
+	}{
+		"1.2":           {name: "1.2", expected: tls.VersionTLS12},
+		"1.3":           {name: "1.3", expected: tls.VersionTLS13},
+		"with a prefix": {name: "TLS1.3", expected: tls.VersionTLS13},
+		"unknown":       {name: "2.0", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			v, err := TLSVersion(c.name)
+			if c.hasErr {
+				if err == nil {
+					t.Fatal("TLSVersion must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TLSVersion must not return an error, but got '%s'", err)
+			}
+			if v != c.expected {
+				t.Errorf("expected %x, but got %x", c.expected, v)
+			}
+		})
+	}
+}
+
+func TestCipherSuites(t *testing.T) {
+	ids, err := CipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "tls_rsa_with_aes_128_cbc_sha"})
+	if err != nil {
+		t.Fatalf("CipherSuites must not return an error, but got '%s'", err)
+	}
+	expected := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_128_CBC_SHA}
+	if diff := cmp.Diff(expected, ids); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	if _, err := CipherSuites([]string{"foo"}); err == nil {
+		t.Error("CipherSuites must return an error, but got nil")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	keyLogFile := filepath.Join(t.TempDir(), "keylog")
+	opts := &clientOptions{
+		insecureSkipVerify: true,
+		tlsMinVersion:      tls.VersionTLS12,
+		cipherSuites:       []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		keyLogFile:         keyLogFile,
+	}
+	cfg, closer, err := newTLSConfig("", "", "", opts)
+	if err != nil {
+		t.Fatalf("newTLSConfig must not return an error, but got '%s'", err)
+	}
+	defer closer.Close()
+
+	if !cfg.InsecureSkipVerify {
+		t.Error("InsecureSkipVerify must be true")
+	}
+	if cfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("expected MinVersion %x, but got %x", tls.VersionTLS12, cfg.MinVersion)
+	}
+	if diff := cmp.Diff(opts.cipherSuites, cfg.CipherSuites); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+	if cfg.KeyLogWriter == nil {
+		t.Error("KeyLogWriter must not be nil")
+	}
+	if _, err := os.Stat(keyLogFile); err != nil {
+		t.Errorf("the key log file must be created, but got '%s'", err)
+	}
+}
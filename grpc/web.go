@@ -2,37 +2,62 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 
 	"github.com/ktr0731/evans/grpc/grpcreflection"
-	"github.com/ktr0731/grpc-web-go-client/grpcweb"
 	"github.com/pkg/errors"
 	gogrpc "google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
 
 type webClient struct {
-	conn    *grpcweb.ClientConn
+	conn    *webConn
 	headers Headers
+	// keyLog is the key log file. It is nil if the key log is disabled.
+	keyLog io.Closer
 
 	grpcreflection.Client
 }
 
-func NewWebClient(addr string, useReflection, useTLS bool, cacert, cert, certKey string, headers Headers) Client {
-	conn, err := grpcweb.DialContext(addr)
-	if err != nil {
-		panic(err)
+// NewWebClient creates a new gRPC-Web client.
+// If serverName is not empty, it overrides the server name used to
+// verify the hostname on the returned certificates.
+// If useReflection is true, the gRPC-Web client enables gRPC reflection.
+// If useTLS is true, the gRPC-Web client communicates with the server over HTTPS and WebSocket over TLS.
+//
+// The set of cert and certKey enables mutual authentication if useTLS is enabled.
+// If one of it is not found, NewWebClient returns ErrMutualAuthParamsAreNotEnough.
+// If useTLS is false, serverName, cacert, cert, certKey and TLS related options are ignored.
+func NewWebClient(addr, serverName string, useReflection, useTLS bool, cacert, cert, certKey string, headers Headers, clientOpts ...ClientOption) (Client, error) {
+	var copts clientOptions
+	for _, o := range clientOpts {
+		o(&copts)
+	}
+
+	var (
+		tlsCfg *tls.Config
+		keyLog io.Closer
+	)
+	if useTLS {
+		var err error
+		tlsCfg, keyLog, err = newTLSConfig(cacert, cert, certKey, &copts)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.ServerName = serverName
 	}
+
 	client := &webClient{
-		conn:    conn,
+		conn:    newWebConn(addr, tlsCfg, copts.perRPCCredentials),
 		headers: Headers{},
+		keyLog:  keyLog,
 	}
-
 	if useReflection {
-		client.Client = grpcreflection.NewWebClient(conn, headers)
+		client.Client = grpcreflection.NewClient(client.conn, headers)
 	}
 
-	return client
+	return client, nil
 }
 
 func (c *webClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (header, trailer metadata.MD, _ error) {
@@ -43,161 +68,48 @@ func (c *webClient) Invoke(ctx context.Context, fqrn string, req, res interface{
 
 	loggingRequest(req)
 
-	err = c.conn.Invoke(ctx, endpoint, req, res, grpcweb.Header(&header), grpcweb.Trailer(&trailer))
+	err = c.conn.Invoke(ctx, endpoint, req, res, gogrpc.Header(&header), gogrpc.Trailer(&trailer))
 	return header, trailer, errors.Wrap(err, "grpc-web: failed to send a request")
 }
 
-type webClientStream struct {
-	ctx    context.Context
-	stream grpcweb.ClientStream
-}
-
-func (s *webClientStream) Header() (metadata.MD, error) {
-	return s.stream.Header()
-}
-
-func (s *webClientStream) Trailer() metadata.MD {
-	return s.stream.Trailer()
-}
-
-func (s *webClientStream) Send(req interface{}) error {
-	loggingRequest(req)
-	if err := s.stream.Send(s.ctx, req); err != nil {
-		return errors.Wrap(err, "failed to send a request")
-	}
-	return nil
-}
-
-func (s *webClientStream) CloseAndReceive(res interface{}) error {
-	err := s.stream.CloseAndReceive(s.ctx, res)
-	if err != nil {
-		return errors.Wrap(err, "failed to send CloseAndReceive")
-	}
-	return nil
-}
-
 func (c *webClient) NewClientStream(ctx context.Context, streamDesc *gogrpc.StreamDesc, fqrn string) (ClientStream, error) {
 	endpoint, err := fqrnToEndpoint(fqrn)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to convert FQRN to endpoint")
 	}
-
-	stream, err := c.conn.NewClientStream(streamDesc, endpoint)
+	cs, err := c.conn.NewStream(ctx, streamDesc, endpoint)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create a new client stream")
 	}
-	return &webClientStream{
-		ctx:    ctx,
-		stream: stream,
-	}, nil
-}
-
-type webServerStream struct {
-	ctx    context.Context
-	stream grpcweb.ServerStream
-}
-
-func (s *webServerStream) Header() (metadata.MD, error) {
-	return s.stream.Header()
-}
-
-func (s *webServerStream) Trailer() metadata.MD {
-	return s.stream.Trailer()
-}
-
-func (s *webServerStream) Send(req interface{}) (err error) {
-	loggingRequest(req)
-	if err := s.stream.Send(s.ctx, req); err != nil {
-		return errors.Wrap(err, "failed to send a request")
-	}
-	return nil
-}
-
-func (s *webServerStream) Receive(res interface{}) error {
-	if s.stream == nil {
-		return errors.New("Receive must be call after Send method")
-	}
-	err := s.stream.Receive(s.ctx, res)
-	if err != nil {
-		return err
-	}
-	return nil
+	return &clientStream{cs}, nil
 }
 
 func (c *webClient) NewServerStream(ctx context.Context, streamDesc *gogrpc.StreamDesc, fqrn string) (ServerStream, error) {
-	endpoint, err := fqrnToEndpoint(fqrn)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to convert FQRN to endpoint")
-	}
-
-	stream, err := c.conn.NewServerStream(streamDesc, endpoint)
+	s, err := c.NewClientStream(ctx, streamDesc, fqrn)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create a new server stream")
 	}
-
-	return &webServerStream{ctx: ctx, stream: stream}, nil
-}
-
-type webBidiStream struct {
-	ctx    context.Context
-	stream grpcweb.BidiStream
-}
-
-func (s *webBidiStream) Header() (metadata.MD, error) {
-	return s.stream.Header()
-}
-
-func (s *webBidiStream) Trailer() metadata.MD {
-	return s.stream.Trailer()
-}
-
-func (s *webBidiStream) Send(req interface{}) error {
-	loggingRequest(req)
-	if err := s.stream.Send(s.ctx, req); err != nil {
-		return errors.Wrap(err, "failed to send a request")
-	}
-	return nil
-}
-
-func (s *webBidiStream) Receive(res interface{}) error {
-	err := s.stream.Receive(s.ctx, res)
-	if errors.Is(err, io.EOF) {
-		return io.EOF
-	}
-	if err != nil {
-		return errors.Wrap(err, "failed to receive a response")
-	}
-	return nil
-}
-
-func (s *webBidiStream) CloseSend() error {
-	if err := s.stream.CloseSend(); err != nil {
-		return errors.Wrap(err, "failed to close the send stream")
-	}
-	return nil
+	return &serverStream{s.(*clientStream)}, nil
 }
 
 func (c *webClient) NewBidiStream(ctx context.Context, streamDesc *gogrpc.StreamDesc, fqrn string) (BidiStream, error) {
-	endpoint, err := fqrnToEndpoint(fqrn)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to convert FQRN to endpoint")
-	}
-
-	stream, err := c.conn.NewBidiStream(streamDesc, endpoint)
+	s, err := c.NewServerStream(ctx, streamDesc, fqrn)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create a new bidi stream")
 	}
-
-	return &webBidiStream{
-		ctx:    ctx,
-		stream: stream,
-	}, nil
+	return &bidiStream{s.(*serverStream)}, nil
 }
 
 func (c *webClient) Close(ctx context.Context) error {
 	if c.Client != nil {
 		c.Client.Reset()
 	}
+	c.conn.Close()
+	if c.keyLog != nil {
+		if err := c.keyLog.Close(); err != nil {
+			return errors.Wrap(err, "failed to close the key log file")
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,448 @@
+package grpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/ktr0731/grpc-web-go-client/grpcweb/parser"
+	"github.com/pkg/errors"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/mem"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const webContentType = "application/grpc-web+" + proto.Name
+
+// webConn is a connection to a gRPC-Web server. It implements grpc.ClientConnInterface, so RPCs and gRPC reflection
+// share the same code path with gRPC.
+//
+// Unary and server streaming RPCs are sent over HTTP. Since gRPC-Web doesn't support client streaming, client and
+// bidirectional streaming RPCs are sent over WebSocket in the protocol of improbable-eng/grpc-web.
+// The codec and the TLS config belong to each connection, so neither the codec registry of gRPC nor the default
+// HTTP client and WebSocket dialer are modified.
+type webConn struct {
+	host   string
+	codec  encoding.CodecV2
+	creds  credentials.PerRPCCredentials
+	useTLS bool
+
+	httpClient *http.Client
+	dialer     *websocket.Dialer
+}
+
+// newWebConn returns a connection to the gRPC-Web server specified by host.
+// If tlsCfg is not nil, the connection communicates with the server over HTTPS and WebSocket over TLS.
+// creds may be nil.
+func newWebConn(host string, tlsCfg *tls.Config, creds credentials.PerRPCCredentials) *webConn {
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tlsCfg
+	return &webConn{
+		host:   host,
+		codec:  encoding.GetCodecV2(proto.Name),
+		creds:  creds,
+		useTLS: tlsCfg != nil,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+		},
+		dialer: &dialer,
+	}
+}
+
+// Invoke sends a unary RPC. grpc.Header and grpc.Trailer are supported, and the other options are ignored.
+func (c *webConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...gogrpc.CallOption) error {
+	s, err := c.NewStream(ctx, &gogrpc.StreamDesc{}, method)
+	if err != nil {
+		return err
+	}
+	if err := s.SendMsg(args); err != nil {
+		return err
+	}
+	err = s.RecvMsg(reply)
+	for _, o := range opts {
+		switch o := o.(type) {
+		case gogrpc.HeaderCallOption:
+			*o.HeaderAddr, _ = s.Header()
+		case gogrpc.TrailerCallOption:
+			*o.TrailerAddr = s.Trailer()
+		}
+	}
+	return err
+}
+
+// NewStream creates a new stream. opts are ignored.
+func (c *webConn) NewStream(ctx context.Context, desc *gogrpc.StreamDesc, method string, _ ...gogrpc.CallOption) (gogrpc.ClientStream, error) {
+	ctx, err := c.withCredentials(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	s := &webStream{ctx: ctx, conn: c, method: method, serverStreams: desc.ServerStreams}
+	if !desc.ClientStreams {
+		return s, nil
+	}
+
+	scheme := "ws"
+	if c.useTLS {
+		scheme = "wss"
+	}
+	u := url.URL{Scheme: scheme, Host: c.host, Path: method}
+	h := http.Header{}
+	h.Set("Sec-WebSocket-Protocol", "grpc-websockets")
+	ws, _, err := c.dialer.DialContext(ctx, u.String(), h)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial to '%s'", u.String())
+	}
+	s.ws = ws
+	s.res = &webResponse{r: &webSocketReader{conn: ws}}
+
+	// The first message has request headers.
+	var b bytes.Buffer
+	requestHeader(ctx).Write(&b)
+	if err := s.writeMessage(b.Bytes()); err != nil {
+		ws.Close()
+		return nil, errors.Wrap(err, "failed to send request headers")
+	}
+	return s, nil
+}
+
+// withCredentials appends the metadata from the per-RPC credentials to ctx.
+func (c *webConn) withCredentials(ctx context.Context, method string) (context.Context, error) {
+	if c.creds == nil {
+		return ctx, nil
+	}
+	if c.creds.RequireTransportSecurity() && !c.useTLS {
+		return nil, errors.New("grpc-web: the per-RPC credentials require TLS")
+	}
+	md, err := c.creds.GetRequestMetadata(ctx, method)
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc-web: failed to get the per-RPC credentials")
+	}
+	for k, v := range md {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	return ctx, nil
+}
+
+// Close closes idle connections of the connection.
+func (c *webConn) Close() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// webStream is a stream of gRPC-Web. If ws is nil, the request is sent over HTTP by the first SendMsg.
+type webStream struct {
+	ctx           context.Context
+	conn          *webConn
+	method        string
+	serverStreams bool
+
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+
+	// res is nil until the request is sent over HTTP.
+	res *webResponse
+}
+
+func (s *webStream) Header() (metadata.MD, error) {
+	if s.res == nil {
+		return nil, errors.New("Header must be called after sending a request")
+	}
+	return s.res.readHeader()
+}
+
+func (s *webStream) Trailer() metadata.MD {
+	if s.res == nil {
+		return nil
+	}
+	return s.res.trailer
+}
+
+func (s *webStream) CloseSend() error {
+	if s.ws == nil {
+		return nil
+	}
+	// 0x01 means that the client finished sending. See transports/websocket/websocket.ts of improbable-eng/grpc-web.
+	return s.writeMessage([]byte{0x01})
+}
+
+func (s *webStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *webStream) SendMsg(m interface{}) error {
+	b, err := s.conn.codec.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the request")
+	}
+	defer b.Free()
+
+	var buf bytes.Buffer
+	if s.ws != nil {
+		// 0x00 means that the message has data.
+		buf.WriteByte(0x00)
+	}
+	writeFrame(&buf, 0x00, b.Materialize())
+
+	if s.ws != nil {
+		return s.writeMessage(buf.Bytes())
+	}
+	return s.send(&buf)
+}
+
+// send sends the request over HTTP.
+func (s *webStream) send(body io.Reader) error {
+	if s.res != nil {
+		return errors.New("only one request can be sent over HTTP")
+	}
+
+	scheme := "http"
+	if s.conn.useTLS {
+		scheme = "https"
+	}
+	u := url.URL{Scheme: scheme, Host: s.conn.host, Path: s.method}
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, u.String(), body)
+	if err != nil {
+		return errors.Wrap(err, "failed to build the request")
+	}
+	req.Header = requestHeader(s.ctx)
+	res, err := s.conn.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send the request")
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return status.Errorf(codes.Unknown, "grpc-web: unexpected HTTP status: %s", res.Status)
+	}
+
+	header := metadata.MD{}
+	for k, v := range res.Header {
+		header.Append(k, v...)
+	}
+	s.res = &webResponse{r: res.Body, header: header}
+	return nil
+}
+
+func (s *webStream) writeMessage(b []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.ws.WriteMessage(websocket.BinaryMessage, b)
+}
+
+// RecvMsg receives a response. If the stream is not server streaming, RecvMsg also waits for the status of the RPC.
+// RecvMsg returns io.EOF if the RPC finished successfully, or the status error if it failed.
+func (s *webStream) RecvMsg(m interface{}) error {
+	if s.res == nil {
+		return errors.New("RecvMsg must be called after sending a request")
+	}
+	err := s.res.recv(s.conn.codec, m)
+	if s.serverStreams || err != nil {
+		return err
+	}
+	// The response of a non-server streaming RPC is followed by the status.
+	if err := s.res.recv(s.conn.codec, m); err != io.EOF {
+		if err == nil {
+			return status.Error(codes.Internal, "grpc-web: the server sent more than one response")
+		}
+		return err
+	}
+	return nil
+}
+
+// webResponse reads gRPC-Web frames of a response.
+type webResponse struct {
+	mu sync.Mutex
+	r  io.ReadCloser
+	// header is nil until the response headers are received.
+	header, trailer metadata.MD
+	// err is io.EOF or the status error after the response finished.
+	err error
+}
+
+func (r *webResponse) readHeader() (metadata.MD, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.header == nil && r.err == nil {
+		flag, b, err := readFrame(r.r)
+		switch {
+		case errors.Is(err, io.EOF):
+			r.finish(statusFromHeader(nil).Err())
+		case err != nil:
+			r.finish(err)
+		case flag&0x80 == 0:
+			r.finish(status.Error(codes.Internal, "grpc-web: received a message before headers"))
+		default:
+			r.header, err = parseHeaderFrame(b)
+			if err != nil {
+				r.finish(err)
+			}
+		}
+	}
+	if r.header == nil && r.err != io.EOF {
+		return nil, r.err
+	}
+	return r.header, nil
+}
+
+// recv reads frames until a message is received or the response finishes.
+func (r *webResponse) recv(codec encoding.CodecV2, m interface{}) error {
+	if _, err := r.readHeader(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.err == nil {
+		flag, b, err := readFrame(r.r)
+		if errors.Is(err, io.EOF) {
+			// Trailers-only responses have the status in their headers.
+			r.finish(statusFromHeader(r.header).Err())
+			break
+		}
+		if err != nil {
+			r.finish(err)
+			break
+		}
+		if flag&0x80 == 0 {
+			if err := codec.Unmarshal(mem.BufferSlice{mem.SliceBuffer(b)}, m); err != nil {
+				return errors.Wrap(err, "failed to unmarshal the response")
+			}
+			return nil
+		}
+
+		stat, trailer, err := parser.ParseStatusAndTrailer(bytes.NewReader(b), uint32(len(b)))
+		if err != nil {
+			r.finish(errors.Wrap(err, "failed to parse the trailer"))
+			break
+		}
+		r.trailer = trailer
+		r.finish(stat.Err())
+	}
+	return r.err
+}
+
+// finish closes the response. A nil err means that the RPC finished successfully.
+func (r *webResponse) finish(err error) {
+	if err == nil {
+		err = io.EOF
+	}
+	r.err = err
+	r.r.Close()
+}
+
+// requestHeader returns HTTP headers of a request which has the outgoing metadata of ctx.
+func requestHeader(ctx context.Context) http.Header {
+	h := http.Header{}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for k, v := range md {
+		for _, vv := range v {
+			h.Add(k, vv)
+		}
+	}
+	h.Set("content-type", webContentType)
+	h.Set("x-grpc-web", "1")
+	return h
+}
+
+// writeFrame writes a gRPC-Web frame which consists of flag, the length of b and b.
+func writeFrame(w io.Writer, flag byte, b []byte) {
+	var h [5]byte
+	h[0] = flag
+	binary.BigEndian.PutUint32(h[1:], uint32(len(b)))
+	w.Write(h[:])
+	w.Write(b)
+}
+
+// readFrame reads a gRPC-Web frame. It returns io.EOF if r has no more frames.
+func readFrame(r io.Reader) (flag byte, b []byte, _ error) {
+	var h [5]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		if err == io.EOF {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, errors.Wrap(err, "failed to read the frame header")
+	}
+	b = make([]byte, binary.BigEndian.Uint32(h[1:]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, nil, errors.Wrap(err, "failed to read the frame")
+	}
+	return h[0], b, nil
+}
+
+func parseHeaderFrame(b []byte) (metadata.MD, error) {
+	h, err := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(b), strings.NewReader("\r\n")))).ReadMIMEHeader()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse response headers")
+	}
+	md := metadata.MD{}
+	for k, v := range h {
+		md.Append(k, v...)
+	}
+	return md, nil
+}
+
+func statusFromHeader(h metadata.MD) *status.Status {
+	code := h.Get("grpc-status")
+	if len(code) == 0 {
+		return status.New(codes.Unknown, "grpc-web: response closed without grpc-status")
+	}
+	n, err := strconv.Atoi(code[0])
+	if err != nil {
+		return status.New(codes.Unknown, err.Error())
+	}
+	var msg string
+	if msgs := h.Get("grpc-message"); len(msgs) != 0 {
+		msg = msgs[0]
+	}
+	return status.New(codes.Code(n), msg)
+}
+
+// webSocketReader reads binary messages of a WebSocket connection as a byte stream.
+type webSocketReader struct {
+	conn *websocket.Conn
+	r    io.Reader
+}
+
+func (r *webSocketReader) Read(p []byte) (int, error) {
+	for {
+		if r.r != nil {
+			n, err := r.r.Read(p)
+			if err == io.EOF {
+				r.r = nil
+				err = nil
+			}
+			if n != 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		_, rr, err := r.conn.NextReader()
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.r = rr
+	}
+}
+
+func (r *webSocketReader) Close() error {
+	return r.conn.Close()
+}
@@ -0,0 +1,188 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type tokenCredentials string
+
+func (c tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(c)}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func webFrame(flag byte, b []byte) []byte {
+	var buf bytes.Buffer
+	writeFrame(&buf, flag, b)
+	return buf.Bytes()
+}
+
+func TestWebConn_Invoke(t *testing.T) {
+	res, err := proto.Marshal(wrapperspb.String("oumae"))
+	if err != nil {
+		t.Fatalf("failed to marshal the response: %s", err)
+	}
+
+	cases := map[string]struct {
+		body      []byte
+		header    map[string]string
+		expected  string
+		code      codes.Code
+		trailerMD metadata.MD
+	}{
+		"OK": {
+			body:      append(webFrame(0x00, res), webFrame(0x80, []byte("grpc-status: 0\r\nkumiko: oumae\r\n"))...),
+			expected:  "oumae",
+			trailerMD: metadata.Pairs("kumiko", "oumae"),
+		},
+		"status in the trailer": {
+			body: webFrame(0x80, []byte("grpc-status: 5\r\ngrpc-message: not found\r\n")),
+			code: codes.NotFound,
+		},
+		"trailers-only": {
+			header: map[string]string{"grpc-status": "7", "grpc-message": "denied"},
+			code:   codes.PermissionDenied,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var gotAuth, gotContentType string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuth, gotContentType = r.Header.Get("authorization"), r.Header.Get("content-type")
+				for k, v := range c.header {
+					w.Header().Set(k, v)
+				}
+				w.Write(c.body)
+			}))
+			defer srv.Close()
+
+			conn := newWebConn(strings.TrimPrefix(srv.URL, "http://"), nil, tokenCredentials("token"))
+			defer conn.Close()
+
+			var trailer metadata.MD
+			reply := &wrapperspb.StringValue{}
+			err := conn.Invoke(context.Background(), "/api.Example/Unary", wrapperspb.String("kumiko"), reply, gogrpc.Trailer(&trailer))
+			if code := status.Code(err); code != c.code {
+				t.Fatalf("expected code %s, but got '%v'", c.code, err)
+			}
+			if gotAuth != "Bearer token" {
+				t.Errorf("the credentials must be attached to the request, but got '%s'", gotAuth)
+			}
+			if gotContentType != webContentType {
+				t.Errorf("expected content-type '%s', but got '%s'", webContentType, gotContentType)
+			}
+			if reply.GetValue() != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, reply.GetValue())
+			}
+			if diff := cmp.Diff(c.trailerMD, trailer); diff != "" {
+				t.Errorf("unexpected trailer (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWebConn_NewStream_clientStreaming(t *testing.T) {
+	var gotHeader string
+	var gotReqs [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{Subprotocols: websocket.Subprotocols(r)}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		gotHeader = string(b)
+		for {
+			_, b, err := conn.ReadMessage()
+			if err != nil || bytes.Equal(b, []byte{0x01}) {
+				break
+			}
+			gotReqs = append(gotReqs, b)
+		}
+
+		res, _ := proto.Marshal(wrapperspb.String("oumae"))
+		header := []byte("kumiko: oumae\r\n")
+		trailer := []byte("grpc-status: 0\r\n")
+		// improbable-eng/grpc-web writes frame headers and payloads as separate messages.
+		for _, b := range [][]byte{
+			webFrame(0x80, header)[:5], header,
+			webFrame(0x00, res)[:5], res,
+			webFrame(0x80, trailer)[:5], trailer,
+		} {
+			conn.WriteMessage(websocket.BinaryMessage, b)
+		}
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	}))
+	defer srv.Close()
+
+	conn := newWebConn(strings.TrimPrefix(srv.URL, "http://"), nil, tokenCredentials("token"))
+	defer conn.Close()
+
+	desc := &gogrpc.StreamDesc{ClientStreams: true}
+	s, err := conn.NewStream(context.Background(), desc, "/api.Example/ClientStreaming")
+	if err != nil {
+		t.Fatalf("NewStream must not return an error, but got '%s'", err)
+	}
+	for _, v := range []string{"kumiko", "reina"} {
+		if err := s.SendMsg(wrapperspb.String(v)); err != nil {
+			t.Fatalf("SendMsg must not return an error, but got '%s'", err)
+		}
+	}
+	if err := s.CloseSend(); err != nil {
+		t.Fatalf("CloseSend must not return an error, but got '%s'", err)
+	}
+	reply := &wrapperspb.StringValue{}
+	if err := s.RecvMsg(reply); err != nil {
+		t.Fatalf("RecvMsg must not return an error, but got '%s'", err)
+	}
+	if reply.GetValue() != "oumae" {
+		t.Errorf("expected 'oumae', but got '%s'", reply.GetValue())
+	}
+	header, err := s.Header()
+	if err != nil {
+		t.Fatalf("Header must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(metadata.Pairs("kumiko", "oumae"), header); diff != "" {
+		t.Errorf("unexpected header (-want, +got):\n%s", diff)
+	}
+	if err := s.RecvMsg(reply); err != io.EOF {
+		t.Errorf("RecvMsg must return io.EOF after the RPC finished, but got '%v'", err)
+	}
+
+	if !strings.Contains(gotHeader, "Authorization: Bearer token\r\n") {
+		t.Errorf("the credentials must be sent in the first message, but got '%s'", gotHeader)
+	}
+	if len(gotReqs) != 2 {
+		t.Fatalf("expected 2 requests, but got %d", len(gotReqs))
+	}
+	for i, v := range []string{"kumiko", "reina"} {
+		b, _ := proto.Marshal(wrapperspb.String(v))
+		if want := append([]byte{0x00}, webFrame(0x00, b)...); !bytes.Equal(want, gotReqs[i]) {
+			t.Errorf("unexpected request %d: want %x, got %x", i, want, gotReqs[i])
+		}
+	}
+}
@@ -8,7 +8,10 @@ import (
 )
 
 func TestWebClient(t *testing.T) {
-	client := grpc.NewWebClient("", false, false, "", "", "", nil)
+	client, err := grpc.NewWebClient("", "", false, false, "", "", "", nil)
+	if err != nil {
+		t.Fatalf("NewWebClient must not return an error, but got '%s'", err)
+	}
 	t.Run("Invoke returns an error if FQRN is invalid", func(t *testing.T) {
 		_, _, err := client.Invoke(context.Background(), "invalid-fqrn", nil, nil)
 		if err == nil {
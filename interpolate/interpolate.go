@@ -0,0 +1,263 @@
+// Package interpolate expands environment variables and command substitutions in config values.
+//
+// Values expanded by ExpandSecret are regarded as secrets because they are typically credentials such as
+// bearer tokens. Secrets are masked by Mask and the writer returned from NewMaskWriter. Only whole values are
+// masked, so a secret never corrupts other text which happens to contain it.
+package interpolate
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Masked replaces secrets in masked output.
+const Masked = "****"
+
+var (
+	mu           sync.Mutex
+	allowCommand bool
+	// commandCache holds the output of each command. Commands are executed once per session.
+	commandCache = map[string]string{}
+	secrets      = map[string]struct{}{}
+)
+
+// AllowCommand enables or disables "$(command)". It is disabled by default because commands are
+// executed with the privileges of the user.
+func AllowCommand(allow bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	allowCommand = allow
+}
+
+// Reset clears all executed commands and secrets, and disables "$(command)".
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	allowCommand = false
+	commandCache = map[string]string{}
+	secrets = map[string]struct{}{}
+}
+
+// Expand expands these forms in s:
+//
+//   - ${NAME}: the value of the environment variable NAME. It is an error if NAME is not set.
+//   - $(command): the output of command without trailing newlines. command is executed by the shell only once,
+//     and the output is reused after that. It is an error if AllowCommand is not enabled.
+//   - $${ and $$(: "${" and "$(" respectively.
+//
+// Other "$" are left as they are, so "$$" in existing values such as passwords isn't changed.
+func Expand(s string) (string, error) {
+	v, _, err := expand(s)
+	return v, err
+}
+
+// ExpandSecret is the same as Expand, but the result is registered as a secret if s contains
+// environment variables or commands.
+func ExpandSecret(s string) (string, error) {
+	v, expanded, err := expand(s)
+	if err != nil {
+		return "", err
+	}
+	if expanded && v != "" {
+		mu.Lock()
+		secrets[v] = struct{}{}
+		mu.Unlock()
+	}
+	return v, nil
+}
+
+// Expand expands s. expanded reports whether s contains environment variables or commands.
+func expand(s string) (_ string, expanded bool, _ error) {
+	if !strings.Contains(s, "$") {
+		return s, false, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		var (
+			v   string
+			end int
+			err error
+		)
+		switch s[i+1] {
+		case '$':
+			if i+2 < len(s) && (s[i+2] == '{' || s[i+2] == '(') {
+				b.WriteString(s[i+1 : i+3])
+				i += 2
+				continue
+			}
+			b.WriteByte(s[i])
+			continue
+		case '{':
+			end = strings.IndexByte(s[i:], '}')
+			if end == -1 {
+				return "", false, errors.Errorf("unclosed '${' in '%s'", s)
+			}
+			end += i
+			v, err = lookupEnv(s[i+2 : end])
+		case '(':
+			end = closingParen(s, i+1)
+			if end == -1 {
+				return "", false, errors.Errorf("unclosed '$(' in '%s'", s)
+			}
+			v, err = runCommand(s[i+2 : end])
+		default:
+			b.WriteByte(s[i])
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		expanded = true
+		b.WriteString(v)
+		i = end
+	}
+	return b.String(), expanded, nil
+}
+
+func lookupEnv(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("empty environment variable name")
+	}
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.Errorf("environment variable '%s' is not set", name)
+	}
+	return v, nil
+}
+
+// closingParen returns the index of the parenthesis which closes the one at open.
+func closingParen(s string, open int) int {
+	var depth int
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func runCommand(command string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !allowCommand {
+		return "", errors.Errorf("command interpolation '$(%s)' is disabled. enable it by request.allowCommandInterpolation in the global config or --allow-command-interpolation", command)
+	}
+	if out, ok := commandCache[command]; ok {
+		return out, nil
+	}
+
+	out, err := RunCommand(command)
+	if err != nil {
+		return "", err
+	}
+	v := strings.TrimRight(string(out), "\r\n")
+	commandCache[command] = v
+	return v, nil
+}
+
+// RunCommand executes command by the shell, which is "sh -c" or "cmd /C" on Windows, and returns its stdout.
+// If the command fails, the returned error has its stderr.
+func RunCommand(command string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to execute '%s': %s", command, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Mask returns "****" if s is a secret. Otherwise, s is returned as it is.
+func Mask(s string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := secrets[s]; ok {
+		return Masked
+	}
+	return s
+}
+
+// maskWords replaces secrets in s with "****". Secrets are replaced only if they are separated from the
+// surrounding text, that is, the adjacent characters aren't letters, digits, '-', '_' or '.'.
+func maskWords(s string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(secrets) == 0 {
+		return s
+	}
+	// Replace longer secrets first because a secret may contain another one.
+	sorted := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		sorted = append(sorted, secret)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, secret := range sorted {
+		var b strings.Builder
+		for {
+			i := strings.Index(s, secret)
+			if i == -1 {
+				break
+			}
+			end := i + len(secret)
+			if (i > 0 && isWordByte(s[i-1])) || (end < len(s) && isWordByte(s[end])) {
+				b.WriteString(s[:end])
+			} else {
+				b.WriteString(s[:i])
+				b.WriteString(Masked)
+			}
+			s = s[end:]
+		}
+		b.WriteString(s)
+		s = b.String()
+	}
+	return s
+}
+
+func isWordByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.'
+}
+
+type maskWriter struct {
+	w io.Writer
+}
+
+// NewMaskWriter returns an io.Writer that masks secrets in written data before writing it to w.
+// Secrets which are a part of a longer word are not masked.
+// Each Write call must contain whole secrets, so it is suitable for line-oriented writers like log.Logger.
+func NewMaskWriter(w io.Writer) io.Writer {
+	return &maskWriter{w: w}
+}
+
+func (w *maskWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, maskWords(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
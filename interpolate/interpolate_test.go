@@ -0,0 +1,135 @@
+package interpolate_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/evans/interpolate"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("EVANS_TEST_TOKEN", "secret-token")
+	t.Setenv("EVANS_TEST_EMPTY", "")
+
+	cases := map[string]struct {
+		in           string
+		allowCommand bool
+
+		expected string
+		hasErr   bool
+	}{
+		"no interpolation":    {in: "Bearer abc", expected: "Bearer abc"},
+		"env":                 {in: "Bearer ${EVANS_TEST_TOKEN}", expected: "Bearer secret-token"},
+		"empty env":           {in: "${EVANS_TEST_EMPTY}", expected: ""},
+		"unset env":           {in: "${EVANS_TEST_UNSET}", hasErr: true},
+		"unclosed env":        {in: "${EVANS_TEST_TOKEN", hasErr: true},
+		"escape":              {in: "$${EVANS_TEST_TOKEN} $$(echo token)", expected: "${EVANS_TEST_TOKEN} $(echo token)"},
+		"other dollars":       {in: "$1 $a $", expected: "$1 $a $"},
+		"double dollars":      {in: "pa$$word $$", expected: "pa$$word $$"},
+		"command":             {in: "Bearer $(echo token)", allowCommand: true, expected: "Bearer token"},
+		"nested parens":       {in: "$(echo $(echo nested))", allowCommand: true, expected: "nested"},
+		"command not allowed": {in: "$(echo token)", hasErr: true},
+		"command failed":      {in: "$(exit 1)", allowCommand: true, hasErr: true},
+		"unclosed command":    {in: "$(echo token", allowCommand: true, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			interpolate.Reset()
+			defer interpolate.Reset()
+			interpolate.AllowCommand(c.allowCommand)
+
+			actual, err := interpolate.Expand(c.in)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("Expand must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expand must not return an error, but got '%s'", err)
+			}
+			if actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExpand_commandCache(t *testing.T) {
+	interpolate.Reset()
+	defer interpolate.Reset()
+	interpolate.AllowCommand(true)
+
+	f := t.TempDir() + "/count"
+	cmd := "$(echo x >> " + f + "; wc -l < " + f + " | tr -d ' ')"
+	for i := 0; i < 2; i++ {
+		actual, err := interpolate.Expand(cmd)
+		if err != nil {
+			t.Fatalf("Expand must not return an error, but got '%s'", err)
+		}
+		if actual != "1" {
+			t.Errorf("the command must be executed only once, but got '%s'", actual)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	out, err := interpolate.RunCommand("echo kumiko")
+	if err != nil {
+		t.Fatalf("RunCommand must not return an error, but got '%s'", err)
+	}
+	if string(out) != "kumiko\n" {
+		t.Errorf("expected the stdout as it is, but got '%s'", out)
+	}
+
+	_, err = interpolate.RunCommand("echo oumae >&2; exit 1")
+	if err == nil {
+		t.Fatal("RunCommand must return an error")
+	}
+	if !strings.Contains(err.Error(), "oumae") {
+		t.Errorf("the error must have the stderr, but got '%s'", err)
+	}
+}
+
+func TestMask(t *testing.T) {
+	interpolate.Reset()
+	defer interpolate.Reset()
+	t.Setenv("EVANS_TEST_TOKEN", "secret-token")
+	t.Setenv("EVANS_TEST_SHORT", "1")
+
+	if _, err := interpolate.Expand("Bearer ${EVANS_TEST_TOKEN}"); err != nil {
+		t.Fatalf("Expand must not return an error, but got '%s'", err)
+	}
+	if actual := interpolate.Mask("Bearer secret-token"); actual != "Bearer secret-token" {
+		t.Errorf("values expanded by Expand must not be masked, but got '%s'", actual)
+	}
+
+	for _, in := range []string{"Bearer ${EVANS_TEST_TOKEN}", "${EVANS_TEST_SHORT}", "literal"} {
+		if _, err := interpolate.ExpandSecret(in); err != nil {
+			t.Fatalf("ExpandSecret must not return an error, but got '%s'", err)
+		}
+	}
+	cases := map[string]string{
+		"Bearer secret-token": "****",
+		"1":                   "****",
+		"secret-token":        "secret-token",
+		"10":                  "10",
+		"literal":             "literal",
+	}
+	for in, expected := range cases {
+		if actual := interpolate.Mask(in); actual != expected {
+			t.Errorf("Mask(%s): expected '%s', but got '%s'", in, expected, actual)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := interpolate.NewMaskWriter(&buf)
+	if _, err := w.Write([]byte("authorization=Bearer secret-token, x-id=1, port=50051, v1.0\n")); err != nil {
+		t.Fatalf("Write must not return an error, but got '%s'", err)
+	}
+	if actual := buf.String(); actual != "authorization=****, x-id=****, port=50051, v1.0\n" {
+		t.Errorf("unexpected output: '%s'", actual)
+	}
+}
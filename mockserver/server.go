@@ -0,0 +1,311 @@
+// Package mockserver provides a gRPC server that answers all methods of loaded descriptors
+// with canned responses from stubs or generated placeholder messages.
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktr0731/evans/assertion"
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Server is a mock gRPC server.
+type Server struct {
+	server    *grpc.Server
+	stubs     *Stubs
+	services  map[string]protoreflect.ServiceDescriptor
+	files     *protoregistry.Files
+	resolver  proto.TypeResolver
+	accessLog io.Writer
+
+	mu sync.Mutex
+}
+
+// Option configures optional behaviors of Server.
+type Option func(*Server)
+
+// WithStubs sets stubs. Methods which have no matched stubs return placeholder messages.
+func WithStubs(stubs *Stubs) Option {
+	return func(s *Server) {
+		s.stubs = stubs
+	}
+}
+
+// WithReflection enables gRPC reflection. Loaded services are listed by the reflection service.
+func WithReflection() Option {
+	return func(s *Server) {
+		reflectionOpts := reflection.ServerOptions{
+			Services:           serviceInfoProvider{s},
+			DescriptorResolver: s.files,
+			ExtensionResolver:  new(protoregistry.Types),
+		}
+		reflectionv1.RegisterServerReflectionServer(s.server, reflection.NewServerV1(reflectionOpts))
+		reflectionv1alpha.RegisterServerReflectionServer(s.server, reflection.NewServer(reflectionOpts))
+	}
+}
+
+// WithAccessLog writes a line for each RPC to w.
+func WithAccessLog(w io.Writer) Option {
+	return func(s *Server) {
+		s.accessLog = w
+	}
+}
+
+// New returns a new Server that serves all services of descSource.
+func New(descSource proto.DescriptorSource, opts ...Option) (*Server, error) {
+	svcNames, err := descSource.ListServices()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list services")
+	}
+
+	s := &Server{
+		services: make(map[string]protoreflect.ServiceDescriptor, len(svcNames)),
+		files:    new(protoregistry.Files),
+		resolver: proto.NewTypeResolver(descSource),
+	}
+	for _, name := range svcNames {
+		d, err := descSource.FindSymbol(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find service '%s'", name)
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, errors.Errorf("'%s' is not a service", name)
+		}
+		s.services[name] = sd
+		if err := registerFile(s.files, sd.ParentFile()); err != nil {
+			return nil, err
+		}
+	}
+
+	s.server = grpc.NewServer(grpc.UnknownServiceHandler(s.handle))
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// registerFile registers fd and its imports to files.
+func registerFile(files *protoregistry.Files, fd protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return nil
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFile(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	if err := files.RegisterFile(fd); err != nil {
+		return errors.Wrapf(err, "failed to register '%s'", fd.Path())
+	}
+	return nil
+}
+
+// Services returns fully-qualified names of served services.
+func (s *Server) Services() []string {
+	names := make([]string, 0, len(s.services))
+	for name := range s.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Serve accepts connections on lis. It blocks until Stop is called.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Stop stops the server gracefully.
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+func (s *Server) handle(_ interface{}, stream grpc.ServerStream) error {
+	fullMethod, ok := grpc.MethodFromServerStream(stream)
+	if !ok {
+		return status.Error(codes.Internal, "failed to get the method name")
+	}
+	start := time.Now()
+	err := s.handleMethod(fullMethod, stream)
+	s.logAccess(fullMethod, start, err)
+	return err
+}
+
+func (s *Server) handleMethod(fullMethod string, stream grpc.ServerStream) error {
+	md, err := s.findMethod(fullMethod)
+	if err != nil {
+		return err
+	}
+	fqmn := string(md.FullName())
+
+	if md.IsStreamingClient() && md.IsStreamingServer() {
+		// The header can be sent only once per stream, so only the header of the stub of the first request is sent.
+		for sendHeader := true; ; sendHeader = false {
+			req, err := s.recv(stream, md)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			stub := s.stubs.find(fqmn, req)
+			if err := s.respond(stream, md, stub, sendHeader); err != nil {
+				return err
+			}
+			if err := stubStatus(stub); err != nil {
+				return err
+			}
+		}
+	}
+
+	var reqs []interface{}
+	for {
+		req, err := s.recv(stream, md)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		reqs = append(reqs, req)
+		if !md.IsStreamingClient() {
+			break
+		}
+	}
+	stub := s.stubs.find(fqmn, reqs...)
+	if err := s.respond(stream, md, stub, true); err != nil {
+		return err
+	}
+	return stubStatus(stub)
+}
+
+func (s *Server) findMethod(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	svcName, methodName := parseFullMethod(fullMethod)
+	sd, ok := s.services[svcName]
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "unknown service %s", svcName)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(methodName))
+	if md == nil {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s for service %s", methodName, svcName)
+	}
+	return md, nil
+}
+
+// parseFullMethod splits a method name like "/api.Example/Unary" into the service and method names.
+func parseFullMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	i := strings.LastIndex(fullMethod, "/")
+	if i == -1 {
+		return "", fullMethod
+	}
+	return fullMethod[:i], fullMethod[i+1:]
+}
+
+// recv receives a request and returns it in the JSON mapping.
+func (s *Server) recv(stream grpc.ServerStream, md protoreflect.MethodDescriptor) (interface{}, error) {
+	req := dynamicpb.NewMessage(md.Input())
+	if err := stream.RecvMsg(req); err != nil {
+		return nil, err
+	}
+	b, err := protojson.MarshalOptions{Resolver: s.resolver}.Marshal(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal the request: %s", err)
+	}
+	v, err := assertion.DecodeJSON(b)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decode the request: %s", err)
+	}
+	return v, nil
+}
+
+// respond sends the responses of stub, and the header of stub if sendHeader is true.
+// If stub is nil, respond sends a placeholder message.
+func (s *Server) respond(stream grpc.ServerStream, md protoreflect.MethodDescriptor, stub *Stub, sendHeader bool) error {
+	if stub == nil {
+		res := dynamicpb.NewMessage(md.Output())
+		if err := fill.NewPlaceholderFiller().Fill(res); err != nil {
+			return status.Errorf(codes.Internal, "failed to generate a placeholder message: %s", err)
+		}
+		return stream.SendMsg(res)
+	}
+
+	if sendHeader && len(stub.Header) != 0 {
+		if err := stream.SetHeader(metadata.New(stub.Header)); err != nil {
+			return err
+		}
+	}
+	if len(stub.Trailer) != 0 {
+		stream.SetTrailer(metadata.New(stub.Trailer))
+	}
+	messages := stub.Messages()
+	if !md.IsStreamingServer() && len(messages) > 1 {
+		return status.Errorf(codes.Internal, "the stub of %s has %d responses, but the method is not server streaming", md.FullName(), len(messages))
+	}
+	for i, m := range messages {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to encode responses[%d] of the stub: %s", i, err)
+		}
+		res := dynamicpb.NewMessage(md.Output())
+		if err := (protojson.UnmarshalOptions{Resolver: s.resolver}).Unmarshal(b, res); err != nil {
+			return status.Errorf(codes.Internal, "invalid responses[%d] of the stub: %s", i, err)
+		}
+		if err := stream.SendMsg(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stubStatus returns the status of stub as an error. It returns nil if the status is OK.
+func stubStatus(stub *Stub) error {
+	if stub == nil || stub.Status == nil {
+		return nil
+	}
+	return status.Error(stub.Status.code, stub.Status.Message)
+}
+
+func (s *Server) logAccess(fullMethod string, start time.Time, err error) {
+	if s.accessLog == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.accessLog, "%s %s %s (%s)\n", start.Format(time.RFC3339), fullMethod, status.Code(err), time.Since(start))
+}
+
+// serviceInfoProvider lists loaded services and registered services such as gRPC reflection.
+type serviceInfoProvider struct {
+	s *Server
+}
+
+func (p serviceInfoProvider) GetServiceInfo() map[string]grpc.ServiceInfo {
+	info := p.s.server.GetServiceInfo()
+	for name := range p.s.services {
+		info[name] = grpc.ServiceInfo{}
+	}
+	return info
+}
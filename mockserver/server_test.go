@@ -0,0 +1,206 @@
+package mockserver_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc/grpcreflection"
+	"github.com/ktr0731/evans/mockserver"
+	"github.com/ktr0731/evans/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func startServer(t *testing.T, opts ...mockserver.Option) (*grpc.ClientConn, protoreflect.ServiceDescriptor) {
+	t.Helper()
+
+	descSource, err := proto.NewDescriptorSourceFromFiles([]string{"testdata"}, []string{"test.proto"})
+	if err != nil {
+		t.Fatalf("failed to load the proto file: %s", err)
+	}
+	d, err := descSource.FindSymbol("api.Example")
+	if err != nil {
+		t.Fatalf("failed to find the service: %s", err)
+	}
+
+	srv, err := mockserver.New(descSource, opts...)
+	if err != nil {
+		t.Fatalf("New must not return an error, but got '%s'", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	go srv.Serve(lis) //nolint:errcheck
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, d.(protoreflect.ServiceDescriptor)
+}
+
+func newRequest(t *testing.T, sd protoreflect.ServiceDescriptor, name string, id int64) *dynamicpb.Message {
+	t.Helper()
+	md := sd.Methods().Get(0).Input()
+	req := dynamicpb.NewMessage(md)
+	req.Set(md.Fields().ByName("name"), protoreflect.ValueOfString(name))
+	req.Set(md.Fields().ByName("id"), protoreflect.ValueOfInt64(id))
+	return req
+}
+
+func responseMessage(res *dynamicpb.Message) string {
+	return res.Get(res.Descriptor().Fields().ByName("message")).String()
+}
+
+func TestServer_unary(t *testing.T) {
+	stubs, err := mockserver.LoadFile(filepath.Join("testdata", "stubs.yaml"))
+	if err != nil {
+		t.Fatalf("LoadFile must not return an error, but got '%s'", err)
+	}
+	conn, sd := startServer(t, mockserver.WithStubs(stubs))
+	output := sd.Methods().ByName("Unary").Output()
+
+	cases := map[string]struct {
+		name     string
+		id       int64
+		expected string
+		code     codes.Code
+		header   []string
+	}{
+		"matched":          {name: "oumae", expected: "hello oumae", header: []string{"oumae"}},
+		"matched by int64": {name: "kousaka", id: 404, code: codes.NotFound},
+		"placeholder":      {name: "kato", expected: "message"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var header metadata.MD
+			res := dynamicpb.NewMessage(output)
+			err := conn.Invoke(context.Background(), "/api.Example/Unary", newRequest(t, sd, c.name, c.id), res, grpc.Header(&header))
+			if code := status.Code(err); code != c.code {
+				t.Fatalf("expected code %s, but got %s (%v)", c.code, code, err)
+			}
+			if c.code != codes.OK {
+				return
+			}
+			if actual := responseMessage(res); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+			if diff := cmp.Diff(c.header, header.Get("x-stub")); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServer_streaming(t *testing.T) {
+	stubs, err := mockserver.LoadFile(filepath.Join("testdata", "stubs.yaml"))
+	if err != nil {
+		t.Fatalf("LoadFile must not return an error, but got '%s'", err)
+	}
+	conn, sd := startServer(t, mockserver.WithStubs(stubs))
+
+	call := func(t *testing.T, method string, clientStreams, serverStreams bool, names ...string) ([]string, grpc.ClientStream) {
+		t.Helper()
+		md := sd.Methods().ByName(protoreflect.Name(method))
+		desc := &grpc.StreamDesc{ClientStreams: clientStreams, ServerStreams: serverStreams}
+		stream, err := conn.NewStream(context.Background(), desc, "/api.Example/"+method)
+		if err != nil {
+			t.Fatalf("failed to create a stream: %s", err)
+		}
+		for _, name := range names {
+			if err := stream.SendMsg(newRequest(t, sd, name, 0)); err != nil {
+				t.Fatalf("failed to send a request: %s", err)
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			t.Fatalf("failed to close the stream: %s", err)
+		}
+		var messages []string
+		for {
+			res := dynamicpb.NewMessage(md.Output())
+			err := stream.RecvMsg(res)
+			if errors.Is(err, io.EOF) {
+				return messages, stream
+			}
+			if err != nil {
+				t.Fatalf("failed to receive a response: %s", err)
+			}
+			messages = append(messages, responseMessage(res))
+		}
+	}
+
+	t.Run("client streaming", func(t *testing.T) {
+		messages, _ := call(t, "ClientStreaming", true, false, "oumae", "kousaka")
+		if diff := cmp.Diff([]string{"hello kousaka"}, messages); diff != "" {
+			t.Errorf("(-want, +got)\n%s", diff)
+		}
+	})
+	t.Run("server streaming", func(t *testing.T) {
+		messages, stream := call(t, "ServerStreaming", false, true, "oumae")
+		if diff := cmp.Diff([]string{"hello 1", "hello 2"}, messages); diff != "" {
+			t.Errorf("(-want, +got)\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"streaming"}, stream.Trailer().Get("x-stub")); diff != "" {
+			t.Errorf("(-want, +got)\n%s", diff)
+		}
+	})
+	t.Run("bidi streaming", func(t *testing.T) {
+		messages, stream := call(t, "BidiStreaming", true, true, "kato", "oumae", "kousaka")
+		if diff := cmp.Diff([]string{"hello kato", "hello oumae", "message"}, messages); diff != "" {
+			t.Errorf("(-want, +got)\n%s", diff)
+		}
+		// Only the header of the stub of the first request is sent.
+		header, err := stream.Header()
+		if err != nil {
+			t.Fatalf("Header must not return an error, but got '%s'", err)
+		}
+		if diff := cmp.Diff([]string{"kato"}, header.Get("x-stub")); diff != "" {
+			t.Errorf("(-want, +got)\n%s", diff)
+		}
+	})
+}
+
+func TestServer_reflection(t *testing.T) {
+	conn, _ := startServer(t, mockserver.WithReflection())
+
+	client := grpcreflection.NewClient(conn, nil)
+	defer client.Reset()
+
+	svcs, err := client.ListServices()
+	if err != nil {
+		t.Fatalf("ListServices must not return an error, but got '%s'", err)
+	}
+	if !contains(svcs, "api.Example") {
+		t.Errorf("ListServices must return api.Example, but got %v", svcs)
+	}
+	d, err := client.FindSymbol("api.Example.Unary")
+	if err != nil {
+		t.Fatalf("FindSymbol must not return an error, but got '%s'", err)
+	}
+	if _, ok := d.(protoreflect.MethodDescriptor); !ok {
+		t.Errorf("FindSymbol must return a method descriptor, but got %T", d)
+	}
+}
+
+func contains(s []string, e string) bool {
+	for _, v := range s {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package mockserver
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ktr0731/evans/assertion"
+	"github.com/ktr0731/evans/script"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Stubs is the content of a stub file.
+type Stubs struct {
+	Stubs []*Stub `json:"stubs"`
+}
+
+// Stub maps requests of a method to a canned result.
+type Stub struct {
+	// Method is the fully-qualified method name like "api.Example.Unary".
+	Method string `json:"method"`
+	// Match is a part of the request message in the JSON mapping of Protocol Buffers.
+	// The stub is used if the request has all fields of Match. If it is nil, the stub matches any requests.
+	Match interface{} `json:"match"`
+	// Header is the response header.
+	Header map[string]string `json:"header"`
+	// Response is the response message in the JSON mapping of Protocol Buffers.
+	Response interface{} `json:"response"`
+	// Responses are response messages for server streaming and bidi streaming methods.
+	Responses []interface{} `json:"responses"`
+	// Status is the status of the RPC. If it is nil, the status is OK.
+	Status *Status `json:"status"`
+	// Trailer is the response trailer.
+	Trailer map[string]string `json:"trailer"`
+}
+
+// Status is the status of an RPC.
+type Status struct {
+	// Code is a code name like "NotFound" or "NOT_FOUND", or its number.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	code codes.Code
+}
+
+// Messages returns response messages of s. If the status is not OK, Messages returns nil.
+// If neither of Response and Responses is specified, Messages returns an empty message.
+func (s *Stub) Messages() []interface{} {
+	if s.Status != nil && s.Status.code != codes.OK {
+		return nil
+	}
+	if s.Responses != nil {
+		return s.Responses
+	}
+	if s.Response != nil {
+		return []interface{}{s.Response}
+	}
+	return []interface{}{map[string]interface{}{}}
+}
+
+// Matches reports whether req satisfies s.Match. req is a request message in the JSON mapping
+// decoded with json.Decoder.UseNumber.
+func (s *Stub) Matches(req interface{}) bool {
+	if s.Match == nil {
+		return true
+	}
+	return assertion.Match(s.Match, req)
+}
+
+// LoadFile loads the stub file. The file is decoded as YAML. Note that YAML is a superset of JSON.
+func LoadFile(path string) (*Stubs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the stub file")
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load decodes stubs as YAML from r, and validates them.
+// Numbers are decoded as json.Number in the same way as requests.
+func Load(r io.Reader) (*Stubs, error) {
+	var s Stubs
+	if err := script.Decode(r, "yaml", &s); err != nil {
+		return nil, errors.Wrap(err, "invalid stub file")
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Stubs) validate() error {
+	for i, stub := range s.Stubs {
+		if stub == nil {
+			return errors.Errorf("stubs[%d] is empty", i)
+		}
+		stub.Method = strings.TrimPrefix(strings.ReplaceAll(stub.Method, "/", "."), ".")
+		if stub.Method == "" {
+			return errors.Errorf("stubs[%d]: method is required", i)
+		}
+		if stub.Response != nil && stub.Responses != nil {
+			return errors.Errorf("stubs[%d]: cannot specify both of response and responses", i)
+		}
+		if stub.Status != nil {
+			code, err := assertion.ParseCode(stub.Status.Code)
+			if err != nil {
+				return errors.Wrapf(err, "stubs[%d]: invalid status", i)
+			}
+			stub.Status.code = code
+		}
+	}
+	return nil
+}
+
+// find returns the first stub of method that matches one of reqs. It returns nil if there is no such stub.
+// If reqs is empty, it is regarded as an empty request.
+func (s *Stubs) find(method string, reqs ...interface{}) *Stub {
+	if s == nil {
+		return nil
+	}
+	if len(reqs) == 0 {
+		reqs = []interface{}{map[string]interface{}{}}
+	}
+	for _, stub := range s.Stubs {
+		if stub.Method != method {
+			continue
+		}
+		for _, req := range reqs {
+			if stub.Matches(req) {
+				return stub
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package mockserver_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/evans/mockserver"
+)
+
+func TestLoad(t *testing.T) {
+	stubs, err := mockserver.Load(strings.NewReader(`
+stubs:
+  - method: /api.Example/Unary
+    match: {id: 1}
+    response: {message: hello}
+`))
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	stub := stubs.Stubs[0]
+	if stub.Method != "api.Example.Unary" {
+		t.Errorf("the method name must be normalized, but got '%s'", stub.Method)
+	}
+	if !stub.Matches(map[string]interface{}{"id": "1", "name": "oumae"}) {
+		t.Error("the stub must match the request")
+	}
+	if stub.Matches(map[string]interface{}{"id": "2"}) {
+		t.Error("the stub must not match the request")
+	}
+}
+
+func TestLoad_nonStringKeys(t *testing.T) {
+	stubs, err := mockserver.Load(strings.NewReader(`
+stubs:
+  - method: api.Example.Unary
+    match: {labels: {1: one}}
+`))
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	if !stubs.Stubs[0].Matches(map[string]interface{}{"labels": map[string]interface{}{"1": "one"}}) {
+		t.Error("the stub must match the request")
+	}
+}
+
+func TestLoad_error(t *testing.T) {
+	cases := map[string]string{
+		"method is missing":     "stubs: [{response: {}}]",
+		"both of responses":     "stubs: [{method: api.Example.Unary, response: {}, responses: [{}]}]",
+		"unknown status code":   "stubs: [{method: api.Example.Unary, status: {code: Foo}}]",
+		"invalid YAML":          "stubs: [",
+		"stubs is not an array": "stubs: foo",
+	}
+	for name, in := range cases {
+		in := in
+		t.Run(name, func(t *testing.T) {
+			if _, err := mockserver.Load(strings.NewReader(in)); err == nil {
+				t.Error("Load must return an error, but got nil")
+			}
+		})
+	}
+}
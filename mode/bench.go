@@ -0,0 +1,149 @@
+package mode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/present/json"
+	"github.com/ktr0731/evans/usecase"
+	"github.com/pkg/errors"
+)
+
+// benchHistogramWidth is the max width of bars of the histogram.
+const benchHistogramWidth = 40
+
+type BenchCLIInvokerOption struct {
+	Headers  config.Header
+	FilePath string // If empty, the invoker tries to read input from stdin.
+	// InputFormat is the format of the input. If empty, it is detected from the extension of FilePath.
+	InputFormat string
+	// OutputFormat is the format of the report. One of "text" or "json".
+	OutputFormat string
+
+	usecase.BenchOption
+}
+
+// NewBenchCLIInvoker returns an CLIInvoker implementation for benchmarking RPCs.
+func NewBenchCLIInvoker(ui cui.UI, methodName string, opt *BenchCLIInvokerOption) (CLIInvoker, error) {
+	if methodName == "" {
+		return nil, errors.New("method is required")
+	}
+	if opt.OutputFormat != "text" && opt.OutputFormat != "json" {
+		return nil, errors.Errorf("unknown output format '%s'", opt.OutputFormat)
+	}
+	return func(ctx context.Context) error {
+		in := DefaultCLIReader
+		if opt.FilePath != "" {
+			f, err := os.Open(opt.FilePath)
+			if err != nil {
+				return errors.Wrap(err, "failed to open the script file")
+			}
+			defer f.Close()
+			in = f
+		}
+		filler, err := newFiller(in, opt.InputFormat, opt.FilePath)
+		if err != nil {
+			return err
+		}
+		usecase.InjectPartially(usecase.Dependencies{Filler: filler})
+
+		for k, v := range opt.Headers {
+			for _, vv := range v {
+				usecase.AddHeader(k, vv)
+			}
+		}
+
+		methodName, err := useMethod(methodName)
+		if err != nil {
+			return err
+		}
+
+		res, err := usecase.Bench(ctx, methodName, opt.BenchOption)
+		if err != nil {
+			return errors.Wrapf(err, "failed to benchmark RPC '%s'", methodName)
+		}
+
+		if opt.OutputFormat == "json" {
+			out, err := json.NewPresenter("  ").Format(res)
+			if err != nil {
+				return err
+			}
+			ui.Output(out)
+			return nil
+		}
+		return writeBenchResult(ui.Writer(), res)
+	}, nil
+}
+
+// writeBenchResult writes res in the human-readable text format.
+func writeBenchResult(w io.Writer, res *usecase.BenchResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+
+	fmt.Fprintln(tw, "Summary:")
+	fmt.Fprintf(tw, "  Method:\t%s\n", res.Method)
+	fmt.Fprintf(tw, "  Requests:\t%d\n", res.Requests)
+	fmt.Fprintf(tw, "  Elapsed:\t%s\n", res.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(tw, "  Requests/sec:\t%.2f\n", res.RequestsPerSecond)
+	if res.ServerStreaming {
+		fmt.Fprintf(tw, "  Messages:\t%d\n", res.Messages)
+		fmt.Fprintf(tw, "  Messages/sec:\t%.2f\n", res.MessagesPerSecond)
+	}
+	fmt.Fprintln(tw)
+
+	if res.ServerStreaming {
+		fmt.Fprintln(tw, "Time to first message:")
+	} else {
+		fmt.Fprintln(tw, "Latency:")
+	}
+	for _, l := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"Min", res.Latency.Min},
+		{"Mean", res.Latency.Mean},
+		{"P50", res.Latency.P50},
+		{"P90", res.Latency.P90},
+		{"P95", res.Latency.P95},
+		{"P99", res.Latency.P99},
+		{"Max", res.Latency.Max},
+	} {
+		fmt.Fprintf(tw, "  %s:\t%s\n", l.name, l.d)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "Histogram:")
+	var maxCount int
+	for _, b := range res.Histogram {
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+	for _, b := range res.Histogram {
+		var bar string
+		if maxCount > 0 {
+			bar = strings.Repeat("■", b.Count*benchHistogramWidth/maxCount)
+		}
+		fmt.Fprintf(tw, "  %s\t[%d]\t|%s\n", b.Upper, b.Count, bar)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "Status codes:")
+	codes := make([]string, 0, len(res.StatusCodes))
+	for code := range res.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(tw, "  %s:\t%d\n", code, res.StatusCodes[code])
+	}
+
+	return tw.Flush()
+}
@@ -4,14 +4,21 @@ import (
 	"context"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/ktr0731/evans/assertion"
 	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/cui"
 	"github.com/ktr0731/evans/fill"
 	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/format/binary"
 	"github.com/ktr0731/evans/format/curl"
 	fmtjson "github.com/ktr0731/evans/format/json"
+	"github.com/ktr0731/evans/format/ndjson"
+	"github.com/ktr0731/evans/format/textproto"
+	"github.com/ktr0731/evans/format/yaml"
 	"github.com/ktr0731/evans/present"
 	"github.com/ktr0731/evans/present/json"
 	"github.com/ktr0731/evans/present/name"
@@ -20,6 +27,7 @@ import (
 	"github.com/ktr0731/go-multierror"
 	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
 )
 
 // DefaultCLIReader is the reader that is read for inputting request values. It is exported for E2E testing.
@@ -34,6 +42,13 @@ type CallCLIInvokerOption struct {
 	EmitDefaults bool
 	FilePath     string // If empty, the invoker tries to read input from stdin.
 	FormatType   string
+	// InputFormat is the format of the input. If empty, it is detected from the extension of FilePath.
+	InputFormat string
+	// Timeout is the deadline of the RPC. Zero means no deadline.
+	Timeout time.Duration
+	// Assertion is checked against the result of the RPC if it is not nil.
+	// The RPC is regarded as succeeded even if its status is not OK as long as the assertion is satisfied.
+	Assertion *assertion.Assertion
 }
 
 // NewCallCLIInvoker returns an CLIInvoker implementation for calling RPCs.
@@ -41,6 +56,10 @@ func NewCallCLIInvoker(ui cui.UI, methodName string, opt *CallCLIInvokerOption)
 	if methodName == "" {
 		return nil, errors.New("method is required")
 	}
+	// Check the format type before connecting to the server.
+	if _, err := newResponseFormatter(ui.Writer(), opt.FormatType, opt.EmitDefaults, false); err != nil {
+		return nil, err
+	}
 	return func(ctx context.Context) error {
 		in := DefaultCLIReader
 		if opt.FilePath != "" {
@@ -51,18 +70,26 @@ func NewCallCLIInvoker(ui cui.UI, methodName string, opt *CallCLIInvokerOption)
 			defer f.Close()
 			in = f
 		}
-		filler := fill.NewSilentFiller(in)
-		var rfi format.ResponseFormatterInterface
-		switch opt.FormatType {
-		case "curl":
-			rfi = curl.NewResponseFormatter(ui.Writer(), opt.EmitDefaults)
-		case "json":
-			rfi = fmtjson.NewResponseFormatter(ui.Writer(), opt.EmitDefaults)
-		default:
-			rfi = curl.NewResponseFormatter(ui.Writer(), opt.EmitDefaults)
+		filler, err := newFiller(in, opt.InputFormat, opt.FilePath)
+		if err != nil {
+			return err
+		}
+		methodName, err := useMethod(methodName)
+		if err != nil {
+			return err
+		}
+		rfi, err := newResponseFormatter(ui.Writer(), opt.FormatType, opt.EmitDefaults, isServerStreaming(methodName))
+		if err != nil {
+			return err
+		}
+		var rec *responseRecorder
+		formatter := format.NewResponseFormatter(rfi, opt.Enrich)
+		if opt.Assertion != nil {
+			rec = newResponseRecorder(rfi, opt.Enrich)
+			formatter = format.NewResponseFormatter(rec, true)
 		}
 		usecase.InjectPartially(usecase.Dependencies{
-			ResponseFormatter: format.NewResponseFormatter(rfi, opt.Enrich),
+			ResponseFormatter: formatter,
 			Filler:            filler,
 		})
 
@@ -72,26 +99,125 @@ func NewCallCLIInvoker(ui cui.UI, methodName string, opt *CallCLIInvokerOption)
 			}
 		}
 
-		// Try to parse methodName as a fully-qualified method name.
-		// If it is valid, use its fully-qualified service.
-		fqsn, mtd, err := usecase.ParseFullyQualifiedMethodName(methodName)
-		if err == nil {
-			pkg, svc := proto.ParseFullyQualifiedServiceName(fqsn)
-			if err := usecase.UsePackage(pkg); err != nil {
-				return errors.Wrapf(err, "failed to use package '%s'", pkg)
-			}
-			if err := usecase.UseService(svc); err != nil {
-				return errors.Wrapf(err, "failed to use service '%s'", svc)
+		err = usecase.CallRPC(usecase.WithTimeout(ctx, opt.Timeout), ui.Writer(), methodName)
+		if opt.Assertion == nil {
+			if err != nil {
+				return errors.Wrapf(err, "failed to call RPC '%s'", methodName)
 			}
-			methodName = mtd
+			return nil
 		}
+		return assertResult(opt.Assertion, rec, methodName, err)
+	}, nil
+}
 
-		err = usecase.CallRPC(ctx, ui.Writer(), methodName)
-		if err != nil {
-			return errors.Wrapf(err, "failed to call RPC '%s'", methodName)
+// assertResult checks the result recorded by rec with a. callErr is the error returned from usecase.CallRPC.
+// gRPC errors are checked by the assertion, so they are not returned as they are.
+func assertResult(a *assertion.Assertion, rec *responseRecorder, methodName string, callErr error) error {
+	code := codes.OK
+	var gerr interface {
+		Code() usecase.ErrorCode
+		Message() string
+	}
+	if errors.As(callErr, &gerr) {
+		code = codes.Code(gerr.Code())
+	} else if callErr != nil {
+		return errors.Wrapf(callErr, "failed to call RPC '%s'", methodName)
+	}
+	if rec.status != nil {
+		code = rec.status.Code()
+	}
+	if rec.err != nil {
+		return rec.err
+	}
+
+	var msg string
+	if rec.status != nil {
+		msg = rec.status.Message()
+	} else if gerr != nil {
+		msg = gerr.Message()
+	}
+	return a.Assert(code, msg, rec.result.Responses)
+}
+
+// useMethod tries to parse methodName as a fully-qualified method name.
+// If it is valid, useMethod uses its fully-qualified service and returns the method name.
+// Otherwise, methodName is returned as it is.
+func useMethod(methodName string) (string, error) {
+	fqsn, mtd, err := usecase.ParseFullyQualifiedMethodName(methodName)
+	if err != nil {
+		return methodName, nil
+	}
+	pkg, svc := proto.ParseFullyQualifiedServiceName(fqsn)
+	if err := usecase.UsePackage(pkg); err != nil {
+		return "", errors.Wrapf(err, "failed to use package '%s'", pkg)
+	}
+	if err := usecase.UseService(svc); err != nil {
+		return "", errors.Wrapf(err, "failed to use service '%s'", svc)
+	}
+	return mtd, nil
+}
+
+// isServerStreaming reports whether the RPC named methodName of the selected service returns a stream.
+// It returns false if the RPC is not found because usecase.CallRPC reports it.
+func isServerStreaming(methodName string) bool {
+	rpcs, err := usecase.ListRPCs("")
+	if err != nil {
+		return false
+	}
+	for _, rpc := range rpcs {
+		if rpc.Name == methodName {
+			return rpc.IsServerStreaming
 		}
-		return nil
-	}, nil
+	}
+	return false
+}
+
+// newResponseFormatter returns a ResponseFormatterInterface corresponding to formatType.
+// If formatType is empty, the curl-like format is used. serverStreaming is true if the RPC returns a stream,
+// and it is used to separate messages in the wire format.
+func newResponseFormatter(w io.Writer, formatType string, emitDefaults, serverStreaming bool) (format.ResponseFormatterInterface, error) {
+	resolver := usecase.GetTypeResolver()
+	switch formatType {
+	case "", "curl":
+		return curl.NewResponseFormatter(w, emitDefaults, resolver), nil
+	case "json":
+		return fmtjson.NewResponseFormatter(w, emitDefaults, resolver), nil
+	case "ndjson", "jsonl":
+		return ndjson.NewResponseFormatter(w, emitDefaults, resolver), nil
+	case "textproto", "text":
+		return textproto.NewResponseFormatter(w, resolver), nil
+	case "yaml":
+		return yaml.NewResponseFormatter(w, emitDefaults, resolver), nil
+	case "binary":
+		return binary.NewResponseFormatter(w, serverStreaming), nil
+	default:
+		return nil, errors.Errorf("unknown output format '%s'", formatType)
+	}
+}
+
+// newFiller returns a Filler which reads in as inputFormat.
+// If inputFormat is empty, the format is detected from the extension of filePath. JSON is used by default.
+func newFiller(in io.Reader, inputFormat, filePath string) (fill.Filler, error) {
+	if inputFormat == "" {
+		switch strings.ToLower(filepath.Ext(filePath)) {
+		case ".txtpb", ".textproto", ".textpb", ".pbtxt", ".prototxt":
+			inputFormat = "textproto"
+		case ".yaml", ".yml":
+			inputFormat = "yaml"
+		default:
+			inputFormat = "json"
+		}
+	}
+	switch inputFormat {
+	case "json":
+		return fill.NewSilentFiller(in, usecase.GetTypeResolver()), nil
+	case "textproto", "text":
+		return fill.NewTextFiller(in, usecase.GetTypeResolver()), nil
+	case "yaml":
+		return fill.NewYAMLFiller(in, usecase.GetTypeResolver()), nil
+	default:
+		return nil, errors.Errorf("unknown input format '%s'", inputFormat)
+	}
 }
 
 func NewListCLIInvoker(ui cui.UI, fqn, format string) CLIInvoker {
@@ -178,10 +304,35 @@ func NewDescribeCLIInvoker(ui cui.UI, fqn string) CLIInvoker {
 	}
 }
 
-// RunAsCLIMode starts Evans as CLI mode.
-func RunAsCLIMode(cfg *config.Config, invoker CLIInvoker) error {
+func NewSkeletonCLIInvoker(ui cui.UI, symbol, format string) CLIInvoker {
+	return func(context.Context) error {
+		out, err := usecase.Skeleton(symbol, format)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate the skeleton")
+		}
+		// The skeleton already ends with a newline.
+		ui.Output(strings.TrimSuffix(out, "\n"))
+		return nil
+	}
+}
+
+func NewExportCLIInvoker(ui cui.UI, dir, format string) CLIInvoker {
+	return func(context.Context) error {
+		names, err := usecase.ExportDescriptors(dir, format)
+		if err != nil {
+			return errors.Wrap(err, "failed to export descriptors")
+		}
+		for _, name := range names {
+			ui.Output(filepath.Join(dir, name))
+		}
+		return nil
+	}
+}
+
+// RunAsCLIMode starts Evans as CLI mode. Warnings about the connection are written to ui.
+func RunAsCLIMode(cfg *config.Config, ui cui.UI, invoker CLIInvoker) error {
 	var injectResult error
-	gRPCClient, err := newGRPCClient(cfg)
+	gRPCClient, err := newGRPCClient(cfg, ui)
 	if err != nil {
 		injectResult = multierror.Append(injectResult, err)
 	} else {
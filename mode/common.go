@@ -2,21 +2,65 @@ package mode
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/ktr0731/evans/auth"
+	"github.com/ktr0731/evans/cache"
 	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
 	"github.com/ktr0731/evans/grpc"
 	"github.com/ktr0731/evans/grpc/grpcreflection"
+	"github.com/ktr0731/evans/logger"
 	"github.com/ktr0731/evans/proto"
 	"github.com/ktr0731/evans/usecase"
 	"github.com/pkg/errors"
 )
 
-func newGRPCClient(cfg *config.Config) (grpc.Client, error) {
+func newGRPCClient(cfg *config.Config, ui cui.UI) (grpc.Client, error) {
+	var opts []grpc.ClientOption
+	if a := cfg.Request.Auth; a != nil {
+		creds, err := auth.New(&auth.Config{
+			TokenFile:      a.TokenFile,
+			TokenURL:       a.TokenURL,
+			ClientID:       a.ClientID,
+			ClientSecret:   a.ClientSecret,
+			Scopes:         a.Scopes,
+			Command:        a.Command,
+			AllowPlaintext: a.AllowPlaintext,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate per-RPC credentials")
+		}
+		if creds != nil {
+			opts = append(opts, grpc.WithPerRPCCredentials(creds))
+		}
+	}
+
+	if cfg.Server.TLS {
+		tlsOpts, err := newTLSClientOptions(cfg.Request, ui)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tlsOpts...)
+	}
+
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 	if cfg.Request.Web {
-		//TODO: remove second arg
-		return grpc.NewWebClient(addr, cfg.Server.Reflection, false, "", "", "", grpc.Headers(cfg.Request.Header)), nil
+		client, err := grpc.NewWebClient(
+			addr,
+			cfg.Server.Name,
+			cfg.Server.Reflection,
+			cfg.Server.TLS,
+			cfg.Request.CACertFile,
+			cfg.Request.CertFile,
+			cfg.Request.CertKeyFile,
+			grpc.Headers(cfg.Request.Header),
+			opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate a gRPC-Web client")
+		}
+		return client, nil
 	}
 	client, err := grpc.NewClient(
 		addr,
@@ -26,22 +70,54 @@ func newGRPCClient(cfg *config.Config) (grpc.Client, error) {
 		cfg.Request.CACertFile,
 		cfg.Request.CertFile,
 		cfg.Request.CertKeyFile,
-		cfg.Request.Header)
+		cfg.Request.Header,
+		opts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to instantiate a gRPC client")
 	}
 	return client, nil
 }
 
+// newTLSClientOptions returns the client options for TLS configured by req.
+// If req.KeyLogFile is empty, the key log file is specified by SSLKEYLOGFILE.
+// If the verification of the server certificate is disabled, a warning is written to ui.
+func newTLSClientOptions(req *config.Request, ui cui.UI) ([]grpc.ClientOption, error) {
+	var opts []grpc.ClientOption
+	if req.Insecure {
+		ui.Warn("WARNING: the verification of the server certificate is disabled by --insecure. the connection is vulnerable to man-in-the-middle attacks.")
+		opts = append(opts, grpc.WithInsecureSkipVerify())
+	}
+	if req.TLSMinVersion != "" {
+		v, err := grpc.TLSVersion(req.TLSMinVersion)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTLSMinVersion(v))
+	}
+	if len(req.CipherSuites) != 0 {
+		ids, err := grpc.CipherSuites(req.CipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithCipherSuites(ids))
+	}
+	keyLogFile := req.KeyLogFile
+	if keyLogFile == "" {
+		keyLogFile = os.Getenv("SSLKEYLOGFILE")
+	}
+	if keyLogFile != "" {
+		logger.Printf("TLS master secrets are written to %s", keyLogFile)
+		opts = append(opts, grpc.WithKeyLogFile(keyLogFile))
+	}
+	return opts, nil
+}
+
 func gRPCReflectionPackageFilteredPackages(pkgNames []string) []string {
 	pkgs := make([]string, len(pkgNames))
 	copy(pkgs, pkgNames)
 
-	n := grpcreflection.ServiceName[:strings.LastIndex(grpcreflection.ServiceName, ".")]
-	for i := range pkgs {
-		if n == pkgs[i] {
-			return append(pkgs[:i], pkgs[i+1:]...)
-		}
+	for _, svc := range []string{grpcreflection.ServiceNameV1, grpcreflection.ServiceNameV1Alpha} {
+		pkgs = dropString(pkgs, svc[:strings.LastIndex(svc, ".")])
 	}
 	return pkgs
 }
@@ -83,8 +159,9 @@ func setDefault(cfg *config.Config) error {
 			return err
 		}
 
-		// Ignore server reflection name because it's provided imply when reflection is enabled.
-		svcNames = dropString(svcNames, "grpc.reflection.v1alpha.ServerReflection")
+		// Ignore server reflection names because these are provided implicitly when reflection is enabled.
+		svcNames = dropString(svcNames, grpcreflection.ServiceNameV1)
+		svcNames = dropString(svcNames, grpcreflection.ServiceNameV1Alpha)
 
 		if len(svcNames) != 1 {
 			return nil
@@ -102,11 +179,25 @@ func setDefault(cfg *config.Config) error {
 	return nil
 }
 
-func newDescSource(cfg *config.Config, grpcClient grpcreflection.Client) (descSource proto.DescriptorSource, err error) {
+// newDescSource returns a DescriptorSource from gRPC reflection, protoset files or proto files.
+// If both of gRPC reflection and files are specified, these are combined in the order of
+// cfg.Default.SourcePriority so that the latter complements symbols missing in the former.
+func newDescSource(cfg *config.Config, grpcClient grpcreflection.Client) (proto.DescriptorSource, error) {
+	var reflectionSource, filesSource proto.DescriptorSource
 	if cfg.Server.Reflection {
-		descSource = proto.NewDescriptorSourceFromReflection(grpcClient)
-	} else {
-		descSource, err = proto.NewDescriptorSourceFromFiles(cfg.Default.ProtoPath, cfg.Default.ProtoFile)
+		var opts []proto.ReflectionOption
+		if descCache := newDescriptorCache(cfg); descCache != nil {
+			opts = append(opts, proto.WithDescriptorCache(descCache))
+		}
+		reflectionSource = proto.NewDescriptorSourceFromReflection(grpcClient, opts...)
+	}
+
+	var err error
+	switch {
+	case len(cfg.Default.Protoset) != 0:
+		filesSource, err = proto.NewDescriptorSourceFromProtosets(cfg.Default.Protoset)
+	case len(cfg.Default.ProtoFile) != 0 || reflectionSource == nil:
+		filesSource, err = proto.NewDescriptorSourceFromFiles(cfg.Default.ProtoPath, cfg.Default.ProtoFile)
 	}
 	if errors.Is(err, grpcreflection.ErrTLSHandshakeFailed) {
 		return nil, errors.New("TLS handshake failed. check whether client or server is misconfigured")
@@ -114,7 +205,16 @@ func newDescSource(cfg *config.Config, grpcClient grpcreflection.Client) (descSo
 		return nil, errors.Wrap(err, "failed to instantiate the spec")
 	}
 
-	return
+	switch {
+	case reflectionSource == nil:
+		return filesSource, nil
+	case filesSource == nil:
+		return reflectionSource, nil
+	case cfg.Default.SourcePriority == config.SourcePriorityFiles:
+		return proto.NewCompositeDescriptorSource(filesSource, reflectionSource), nil
+	default:
+		return proto.NewCompositeDescriptorSource(reflectionSource, filesSource), nil
+	}
 }
 
 func dropString(slice []string, s string) []string {
@@ -127,3 +227,21 @@ func dropString(slice []string, s string) []string {
 
 	return newSlice
 }
+
+// newDescriptorCache returns the descriptor cache of the server. It returns nil if the cache is disabled or unavailable.
+func newDescriptorCache(cfg *config.Config) *cache.Descriptors {
+	if cfg.Server.ReflectionCacheTTL <= 0 {
+		return nil
+	}
+
+	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
+	descCache, err := cache.GetDescriptors(cache.DescriptorsKey(addr, cfg.Request.Header), cfg.Server.ReflectionCacheTTL)
+	if err != nil {
+		logger.Printf("failed to load the descriptor cache, disable it: %s", err)
+		return nil
+	}
+	if cfg.Server.RefreshReflection {
+		descCache.Reset(nil)
+	}
+	return descCache
+}
@@ -0,0 +1,36 @@
+package mode
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_gRPCReflectionPackageFilteredPackages(t *testing.T) {
+	cases := map[string]struct {
+		pkgs     []string
+		expected []string
+	}{
+		"no reflection packages": {
+			pkgs:     []string{"api"},
+			expected: []string{"api"},
+		},
+		"v1alpha": {
+			pkgs:     []string{"api", "grpc.reflection.v1alpha"},
+			expected: []string{"api"},
+		},
+		"v1 and v1alpha": {
+			pkgs:     []string{"grpc.reflection.v1", "api", "grpc.reflection.v1alpha"},
+			expected: []string{"api"},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual := gRPCReflectionPackageFilteredPackages(c.pkgs)
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package mode
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	fillproto "github.com/ktr0731/evans/fill/proto"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/logger"
+	"github.com/ktr0731/evans/prompt"
+	"github.com/ktr0731/evans/proto"
+	"github.com/ktr0731/evans/usecase"
+	"github.com/pkg/errors"
+)
+
+// replConnection holds the connection of REPL mode. It is switched by the connect command.
+type replConnection struct {
+	cfg        *config.Config
+	ui         cui.UI
+	client     grpc.Client
+	descSource proto.DescriptorSource
+}
+
+// connect switches the connection to the server described by newCfg.
+// Headers added in the session are inherited, and headers in newCfg are applied if they differ from the current ones.
+// The selected package and service are also inherited if the new server has them. Otherwise, the default ones
+// of the new server are selected.
+// If connect returns an error, the current connection is kept. Otherwise, the current client is closed.
+func (c *replConnection) connect(newCfg *config.Config) error {
+	newClient, err := newGRPCClient(newCfg, c.ui)
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate a new gRPC client")
+	}
+	descSource, err := newDescSource(newCfg, newClient)
+	if err != nil {
+		newClient.Close(context.Background())
+		return errors.Wrap(err, "failed to instantiate a desc source")
+	}
+
+	headers := usecase.ListHeaders()
+	pkg, svc := usecase.SelectedPackage(), usecase.SelectedService()
+
+	// InjectPartially keeps the state such as previous requests.
+	c.inject(newCfg, newClient, descSource)
+
+	for k, v := range headers {
+		for _, vv := range v {
+			usecase.AddHeader(k, vv)
+		}
+	}
+	for k, v := range newCfg.Request.Header {
+		if reflect.DeepEqual(c.cfg.Request.Header[k], v) {
+			continue
+		}
+		usecase.RemoveHeader(k)
+		for _, vv := range v {
+			usecase.AddHeader(k, vv)
+		}
+	}
+
+	usecase.ClearSelection()
+	if err := useSelection(pkg, svc); err != nil {
+		logger.Printf("use the default package and service of the new server: %s", err)
+		usecase.ClearSelection()
+		if err := setDefault(newCfg); err != nil {
+			// Roll back to the current connection.
+			c.inject(c.cfg, c.client, c.descSource)
+			usecase.ClearSelection()
+			_ = useSelection(pkg, svc)
+			newClient.Close(context.Background())
+			return err
+		}
+	}
+
+	c.client.Close(context.Background())
+	c.cfg, c.client, c.descSource = newCfg, newClient, descSource
+	return nil
+}
+
+func (c *replConnection) inject(cfg *config.Config, client grpc.Client, descSource proto.DescriptorSource) {
+	usecase.InjectPartially(usecase.Dependencies{
+		InteractiveFiller: fillproto.NewInteractiveFillerWithDescriptorSource(prompt.New(), cfg.REPL.InputPromptFormat, descSource),
+		GRPCClient:        client,
+		DescSource:        descSource,
+	})
+}
+
+// useSelection selects pkg and svc. It returns an error if either of them is not found.
+func useSelection(pkg, svc string) error {
+	if pkg == "" && svc == "" {
+		return errors.New("nothing is selected")
+	}
+	if pkg != "" {
+		if err := usecase.UsePackage(pkg); err != nil {
+			return err
+		}
+	}
+	if svc != "" {
+		if err := usecase.UseService(svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package mode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/proto"
+	"github.com/ktr0731/evans/proxy"
+	"github.com/pkg/errors"
+)
+
+// ProxyOption is the option for RunAsProxyMode.
+type ProxyOption struct {
+	// Listen is the address that the proxy listens on.
+	Listen string
+	// Upstream is the address of the upstream server. If it is empty, cfg.Server.Host and cfg.Server.Port are used.
+	Upstream string
+	// RecordFile is the session file that records are written to in addition to the UI.
+	RecordFile string
+	// ReplayFile is the session file to replay. If it is specified, the proxy doesn't connect to the upstream.
+	ReplayFile string
+}
+
+// RunAsProxyMode starts a gRPC proxy that forwards RPCs to the upstream and writes a JSON line for each RPC to the UI.
+// If opt.ReplayFile is specified, the proxy answers RPCs with the records of the file instead of forwarding them.
+// It blocks until the process receives SIGINT or SIGTERM.
+func RunAsProxyMode(cfg *config.Config, ui cui.UI, opt *ProxyOption) error {
+	if opt.Listen == "" {
+		return errors.New("--listen is required")
+	}
+
+	log := ui.Writer()
+	if opt.RecordFile != "" {
+		f, err := os.Create(opt.RecordFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to create the session file")
+		}
+		defer f.Close()
+		log = io.MultiWriter(log, f)
+	}
+	opts := []proxy.Option{proxy.WithLog(log)}
+
+	var (
+		descSource proto.DescriptorSource
+		target     string
+	)
+	if opt.ReplayFile != "" {
+		records, err := proxy.LoadRecordsFile(opt.ReplayFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to load the session file")
+		}
+		if len(records) == 0 {
+			return errors.Errorf("the session file '%s' has no records", opt.ReplayFile)
+		}
+		switch {
+		case len(cfg.Default.Protoset) != 0:
+			descSource, err = proto.NewDescriptorSourceFromProtosets(cfg.Default.Protoset)
+		case len(cfg.Default.ProtoFile) != 0:
+			descSource, err = proto.NewDescriptorSourceFromFiles(cfg.Default.ProtoPath, cfg.Default.ProtoFile)
+		default:
+			return errors.New("replay requires one or more proto files or protoset files")
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to instantiate the spec")
+		}
+		opts = append(opts, proxy.WithReplay(records))
+		target = opt.ReplayFile
+	} else {
+		if opt.Upstream != "" {
+			host, port, err := net.SplitHostPort(opt.Upstream)
+			if err != nil {
+				return errors.Wrap(err, "invalid upstream address")
+			}
+			cfg.Server.Host, cfg.Server.Port = host, port
+		}
+		gRPCClient, err := newGRPCClient(cfg, ui)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			gRPCClient.Close(ctx)
+		}()
+		descSource, err = newDescSource(cfg, gRPCClient)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, proxy.WithUpstream(gRPCClient))
+		target = net.JoinHostPort(cfg.Server.Host, cfg.Server.Port)
+	}
+
+	srv, err := proxy.New(descSource, opts...)
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate the proxy")
+	}
+
+	lis, err := net.Listen("tcp", opt.Listen)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.Stop()
+	}()
+
+	// Records are written to the UI writer as JSON lines, so the notice is written to stderr by Warn.
+	ui.Warn(fmt.Sprintf("proxying %s to %s", lis.Addr(), target))
+	if err := srv.Serve(lis); err != nil {
+		return errors.Wrap(err, "failed to serve")
+	}
+	return nil
+}
@@ -17,16 +17,21 @@ import (
 )
 
 func RunAsREPLMode(cfg *config.Config, ui cui.UI, cache *cache.Cache) error {
-	gRPCClient, err := newGRPCClient(cfg)
+	gRPCClient, err := newGRPCClient(cfg, ui)
 	if err != nil {
 		return errors.Wrap(err, "failed to instantiate a new gRPC client")
 	}
-	defer gRPCClient.Close(context.Background())
+	conn := &replConnection{cfg: cfg, ui: ui, client: gRPCClient}
+	defer func() {
+		// The client may be replaced by the connect command.
+		conn.client.Close(context.Background())
+	}()
 
 	descSource, err := newDescSource(cfg, gRPCClient)
 	if err != nil {
 		return errors.Wrap(err, "failed to instantiate a desc source")
 	}
+	conn.descSource = descSource
 
 	usecase.Inject(
 		usecase.Dependencies{
@@ -61,7 +66,7 @@ func RunAsREPLMode(cfg *config.Config, ui cui.UI, cache *cache.Cache) error {
 		}
 	}()
 
-	repl, err := repl.New(cfg, replPrompt, ui, cfg.Default.Package, cfg.Default.Service)
+	repl, err := repl.New(cfg, replPrompt, ui, cfg.Default.Package, cfg.Default.Service, repl.WithConnector(conn.connect))
 	if err != nil {
 		return errors.Wrap(err, "failed to launch a new REPL")
 	}
@@ -0,0 +1,208 @@
+package mode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/script"
+	"github.com/ktr0731/evans/usecase"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type RunCLIInvokerOption struct {
+	Headers      config.Header
+	Enrich       bool
+	EmitDefaults bool
+	FormatType   string
+	// Timeout is the deadline of each step. Zero means no deadline.
+	Timeout time.Duration
+}
+
+// NewRunCLIInvoker returns an CLIInvoker implementation for running the script which is located at scriptPath.
+// Steps are executed in order, and the invoker returns an error as soon as a step fails.
+func NewRunCLIInvoker(ui cui.UI, scriptPath string, opt *RunCLIInvokerOption) (CLIInvoker, error) {
+	if scriptPath == "" {
+		return nil, errors.New("script is required")
+	}
+	s, err := script.LoadFile(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	// Check the format type before connecting to the server.
+	if _, err := newResponseFormatter(ui.Writer(), opt.FormatType, opt.EmitDefaults, false); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		for k, v := range opt.Headers {
+			for _, vv := range v {
+				usecase.AddHeader(k, vv)
+			}
+		}
+
+		results := make(script.Results, len(s.Steps))
+		for i, step := range s.Steps {
+			// The banner is written to stderr, so that the output can be passed to other tools as it is.
+			if i != 0 {
+				ui.Warn("")
+			}
+			ui.Warn(fmt.Sprintf("=== %s: %s", step.Name, step.Method))
+
+			res, err := runStep(ctx, ui, step, results, opt)
+			if err != nil {
+				return errors.Wrapf(err, "step '%s' failed", step.Name)
+			}
+			results[step.Name] = res
+		}
+		return nil
+	}, nil
+}
+
+// runStep renders step with results of the previous steps, and calls the RPC.
+func runStep(ctx context.Context, ui cui.UI, step *script.Step, results script.Results, opt *RunCLIInvokerOption) (*script.Result, error) {
+	step, err := step.Render(results)
+	if err != nil {
+		return nil, err
+	}
+
+	var in bytes.Buffer
+	enc := json.NewEncoder(&in)
+	for _, msg := range step.Messages() {
+		if err := enc.Encode(msg); err != nil {
+			return nil, errors.Wrap(err, "failed to encode the request")
+		}
+	}
+
+	// Responses of all steps are written to the same output, so messages in the wire format are always delimited.
+	rfi, err := newResponseFormatter(ui.Writer(), opt.FormatType, opt.EmitDefaults, true)
+	if err != nil {
+		return nil, err
+	}
+	rec := newResponseRecorder(rfi, opt.Enrich)
+	usecase.InjectPartially(usecase.Dependencies{
+		ResponseFormatter: format.NewResponseFormatter(rec, true),
+		Filler:            fill.NewSilentFiller(&in, usecase.GetTypeResolver()),
+	})
+
+	restore := useStepHeaders(step.Header)
+	defer restore()
+
+	methodName, err := useMethod(step.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := usecase.CallRPC(usecase.WithTimeout(ctx, opt.Timeout), ui.Writer(), methodName); err != nil {
+		return nil, errors.Wrapf(err, "failed to call RPC '%s'", methodName)
+	}
+	if rec.err != nil {
+		return nil, rec.err
+	}
+	return rec.result, nil
+}
+
+// useStepHeaders replaces headers which have the same keys as header with header.
+// The returned function restores the replaced headers.
+func useStepHeaders(header map[string]string) func() {
+	current := usecase.ListHeaders()
+	prev := make(map[string][]string, len(header))
+	for k := range header {
+		prev[k] = append([]string(nil), current[k]...)
+	}
+	for k, v := range header {
+		usecase.RemoveHeader(k)
+		usecase.AddHeader(k, v)
+	}
+	return func() {
+		for k, v := range prev {
+			usecase.RemoveHeader(k)
+			for _, vv := range v {
+				usecase.AddHeader(k, vv)
+			}
+		}
+	}
+}
+
+// responseRecorder is a format.ResponseFormatterInterface which records the result of an RPC call
+// in addition to formatting it by the underlying formatter.
+// Header, trailer and status are formatted only if enrich is true.
+type responseRecorder struct {
+	format.ResponseFormatterInterface
+
+	enrich    bool
+	marshaler protojson.MarshalOptions
+	result    *script.Result
+	status    *status.Status
+	err       error
+}
+
+// newResponseRecorder returns a responseRecorder. Response messages are recorded with all fields
+// including ones that have default values.
+func newResponseRecorder(rfi format.ResponseFormatterInterface, enrich bool) *responseRecorder {
+	return &responseRecorder{
+		ResponseFormatterInterface: rfi,
+		enrich:                     enrich,
+		marshaler:                  protojson.MarshalOptions{EmitUnpopulated: true, Resolver: usecase.GetTypeResolver()},
+		result:                     &script.Result{},
+	}
+}
+
+func (r *responseRecorder) FormatHeader(header metadata.MD) {
+	r.result.Header = header
+	if r.enrich {
+		r.ResponseFormatterInterface.FormatHeader(header)
+	}
+}
+
+func (r *responseRecorder) FormatMessage(v interface{}) error {
+	if err := r.record(v); err != nil && r.err == nil {
+		r.err = errors.Wrap(err, "failed to record the response")
+	}
+	return r.ResponseFormatterInterface.FormatMessage(v)
+}
+
+func (r *responseRecorder) record(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("unexpected response type %T", v)
+	}
+	b, err := r.marshaler.Marshal(m)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var res interface{}
+	if err := dec.Decode(&res); err != nil {
+		return err
+	}
+	r.result.Responses = append(r.result.Responses, res)
+	return nil
+}
+
+func (r *responseRecorder) FormatTrailer(trailer metadata.MD) {
+	r.result.Trailer = trailer
+	if r.enrich {
+		r.ResponseFormatterInterface.FormatTrailer(trailer)
+	}
+}
+
+func (r *responseRecorder) FormatStatus(s *status.Status) error {
+	r.status = s
+	r.result.Code = s.Code().String()
+	r.result.Message = s.Message()
+	if r.enrich {
+		return r.ResponseFormatterInterface.FormatStatus(s)
+	}
+	return nil
+}
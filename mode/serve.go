@@ -0,0 +1,83 @@
+package mode
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/mockserver"
+	"github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+)
+
+// ServeOption is the option for RunAsServeMode.
+type ServeOption struct {
+	// StubFile is the stub file. If it is empty, all methods return placeholder messages.
+	StubFile string
+	// AccessLog writes a line for each RPC to the UI if it is true.
+	AccessLog bool
+}
+
+// RunAsServeMode starts a mock gRPC server that serves services of proto or protoset files.
+// The server listens on cfg.Server.Host and cfg.Server.Port, and enables gRPC reflection if cfg.Server.Reflection is true.
+// It blocks until the process receives SIGINT or SIGTERM.
+func RunAsServeMode(cfg *config.Config, ui cui.UI, opt *ServeOption) error {
+	var (
+		descSource proto.DescriptorSource
+		err        error
+	)
+	switch {
+	case len(cfg.Default.Protoset) != 0:
+		descSource, err = proto.NewDescriptorSourceFromProtosets(cfg.Default.Protoset)
+	case len(cfg.Default.ProtoFile) != 0:
+		descSource, err = proto.NewDescriptorSourceFromFiles(cfg.Default.ProtoPath, cfg.Default.ProtoFile)
+	default:
+		return errors.New("serve requires one or more proto files or protoset files")
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate the spec")
+	}
+
+	var opts []mockserver.Option
+	if opt.StubFile != "" {
+		stubs, err := mockserver.LoadFile(opt.StubFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to load the stub file")
+		}
+		opts = append(opts, mockserver.WithStubs(stubs))
+	}
+	if cfg.Server.Reflection {
+		opts = append(opts, mockserver.WithReflection())
+	}
+	if opt.AccessLog {
+		opts = append(opts, mockserver.WithAccessLog(ui.Writer()))
+	}
+	srv, err := mockserver.New(descSource, opts...)
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate the mock server")
+	}
+
+	lis, err := net.Listen("tcp", net.JoinHostPort(cfg.Server.Host, cfg.Server.Port))
+	if err != nil {
+		return errors.Wrap(err, "failed to listen")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.Stop()
+	}()
+
+	ui.Info(fmt.Sprintf("serving %s on %s", strings.Join(srv.Services(), ", "), lis.Addr()))
+	if err := srv.Serve(lis); err != nil {
+		return errors.Wrap(err, "failed to serve")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package proto
+
+import (
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type composite struct {
+	sources []DescriptorSource
+}
+
+// NewCompositeDescriptorSource returns a DescriptorSource which combines sources.
+// ListServices merges services of sources, and ignores failed ones unless all sources fail.
+// FindSymbol looks up the symbol from sources in order, and returns the first one found. So the preceding source
+// takes priority over the following ones. However, descriptors which refer to placeholders, for example, resolved by
+// gRPC reflection which lacks imports, are returned only if no other sources have the complete one.
+func NewCompositeDescriptorSource(sources ...DescriptorSource) DescriptorSource {
+	return &composite{sources: sources}
+}
+
+func (c *composite) ListServices() ([]string, error) {
+	var (
+		services []string
+		firstErr error
+		failed   int
+	)
+	encountered := make(map[string]struct{})
+	for _, s := range c.sources {
+		svcs, err := s.ListServices()
+		if err != nil {
+			logger.Printf("failed to list services of a descriptor source: %s", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		for _, svc := range svcs {
+			if _, found := encountered[svc]; found {
+				continue
+			}
+			services = append(services, svc)
+			encountered[svc] = struct{}{}
+		}
+	}
+	if len(c.sources) != 0 && failed == len(c.sources) {
+		return nil, firstErr
+	}
+
+	return services, nil
+}
+
+func (c *composite) FindSymbol(name string) (protoreflect.Descriptor, error) {
+	var (
+		firstErr   error
+		incomplete protoreflect.Descriptor
+	)
+	for _, s := range c.sources {
+		d, err := s.FindSymbol(name)
+		if err == nil {
+			if !hasPlaceholder(d, map[protoreflect.FullName]struct{}{}) {
+				return d, nil
+			}
+			logger.Printf("%s refers to placeholders, look up the other sources", name)
+			if incomplete == nil {
+				incomplete = d
+			}
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if incomplete != nil {
+		return incomplete, nil
+	}
+	if firstErr == nil {
+		return nil, errors.Wrapf(errSymbolNotFound, "symbol %s", name)
+	}
+
+	return nil, firstErr
+}
+
+func (c *composite) GetAllMessages() ([]string, error) {
+	var messages []string
+	encountered := make(map[string]struct{})
+	for _, s := range c.sources {
+		fs, ok := s.(DescriptorSourceWithFallback)
+		if !ok {
+			continue
+		}
+		msgs, err := fs.GetAllMessages()
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			if _, found := encountered[msg]; found {
+				continue
+			}
+			messages = append(messages, msg)
+			encountered[msg] = struct{}{}
+		}
+	}
+
+	return messages, nil
+}
+
+// hasPlaceholder reports whether d or descriptors which d refers to are placeholders.
+// visited has the full names of messages which are already checked.
+func hasPlaceholder(d protoreflect.Descriptor, visited map[protoreflect.FullName]struct{}) bool {
+	if d.IsPlaceholder() || d.ParentFile() == nil || d.ParentFile().IsPlaceholder() {
+		return true
+	}
+	switch d := d.(type) {
+	case protoreflect.ServiceDescriptor:
+		for i := 0; i < d.Methods().Len(); i++ {
+			if hasPlaceholder(d.Methods().Get(i), visited) {
+				return true
+			}
+		}
+	case protoreflect.MethodDescriptor:
+		return hasPlaceholder(d.Input(), visited) || hasPlaceholder(d.Output(), visited)
+	case protoreflect.MessageDescriptor:
+		if _, found := visited[d.FullName()]; found {
+			return false
+		}
+		visited[d.FullName()] = struct{}{}
+		for i := 0; i < d.Fields().Len(); i++ {
+			if hasPlaceholder(d.Fields().Get(i), visited) {
+				return true
+			}
+		}
+	case protoreflect.FieldDescriptor:
+		if md := d.Message(); md != nil {
+			return hasPlaceholder(md, visited)
+		}
+		if ed := d.Enum(); ed != nil {
+			return hasPlaceholder(ed, visited)
+		}
+	}
+	return false
+}
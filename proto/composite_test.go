@@ -0,0 +1,122 @@
+package proto_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCompositeDescriptorSource(t *testing.T) {
+	newSource := func(svcs []string, descs ...protoreflect.Descriptor) proto.DescriptorSource {
+		return &proto.DescriptorSourceMock{
+			ListServicesFunc: func() ([]string, error) { return svcs, nil },
+			FindSymbolFunc: func(name string) (protoreflect.Descriptor, error) {
+				for _, d := range descs {
+					if string(d.FullName()) == name {
+						return d, nil
+					}
+				}
+				return nil, errors.New("not found")
+			},
+		}
+	}
+	empty := (&emptypb.Empty{}).ProtoReflect().Descriptor()
+	timestamp := (&timestamppb.Timestamp{}).ProtoReflect().Descriptor()
+
+	s := proto.NewCompositeDescriptorSource(
+		newSource([]string{"api.Example", "api.Foo"}, empty),
+		newSource([]string{"api.Foo", "api.Bar"}, timestamp),
+	)
+
+	svcs, err := s.ListServices()
+	if err != nil {
+		t.Fatalf("ListServices must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"api.Example", "api.Foo", "api.Bar"}, svcs); diff != "" {
+		t.Errorf("unexpected services (-want, +got):\n%s", diff)
+	}
+
+	for _, expected := range []protoreflect.Descriptor{empty, timestamp} {
+		d, err := s.FindSymbol(string(expected.FullName()))
+		if err != nil {
+			t.Fatalf("FindSymbol must not return an error, but got '%s'", err)
+		}
+		if d != expected {
+			t.Errorf("expected %s, but got %s", expected.FullName(), d.FullName())
+		}
+	}
+
+	if _, err := s.FindSymbol("api.Unknown"); err == nil {
+		t.Errorf("FindSymbol must return an error if no sources have the symbol")
+	}
+}
+
+func TestCompositeDescriptorSource_placeholder(t *testing.T) {
+	newSource := func(fd protoreflect.FileDescriptor) proto.DescriptorSource {
+		return &proto.DescriptorSourceMock{
+			FindSymbolFunc: func(name string) (protoreflect.Descriptor, error) {
+				return fd.Services().ByName("Example").Methods().ByName("Unary"), nil
+			},
+		}
+	}
+	complete := compileTestAPI(t)
+	// Imports of api.proto are missing like gRPC reflection which cannot resolve them.
+	incomplete, err := protodesc.FileOptions{AllowUnresolvable: true}.New(protodesc.ToFileDescriptorProto(complete), new(protoregistry.Files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		sources  []proto.DescriptorSource
+		expected protoreflect.FileDescriptor
+	}{
+		"complete one is preferred": {
+			sources:  []proto.DescriptorSource{newSource(incomplete), newSource(complete)},
+			expected: complete,
+		},
+		"incomplete one is returned if no other sources have it": {
+			sources:  []proto.DescriptorSource{newSource(incomplete)},
+			expected: incomplete,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			d, err := proto.NewCompositeDescriptorSource(c.sources...).FindSymbol("api.Example.Unary")
+			if err != nil {
+				t.Fatalf("FindSymbol must not return an error, but got '%s'", err)
+			}
+			if d.ParentFile() != c.expected {
+				t.Errorf("the descriptor must belong to the expected file")
+			}
+		})
+	}
+}
+
+func TestCompositeDescriptorSource_ListServices(t *testing.T) {
+	ok := &proto.DescriptorSourceMock{
+		ListServicesFunc: func() ([]string, error) { return []string{"api.Example"}, nil },
+	}
+	ng := &proto.DescriptorSourceMock{
+		ListServicesFunc: func() ([]string, error) { return nil, errors.New("unavailable") },
+	}
+
+	svcs, err := proto.NewCompositeDescriptorSource(ng, ok).ListServices()
+	if err != nil {
+		t.Fatalf("ListServices must not return an error if some sources succeed, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"api.Example"}, svcs); diff != "" {
+		t.Errorf("unexpected services (-want, +got):\n%s", diff)
+	}
+
+	if _, err := proto.NewCompositeDescriptorSource(ng, ng).ListServices(); err == nil {
+		t.Error("ListServices must return an error if all sources fail")
+	}
+}
@@ -2,11 +2,19 @@ package proto
 
 import (
 	"context"
+	"sort"
+	"sync"
 
 	"github.com/bufbuild/protocompile"
 	"github.com/bufbuild/protocompile/linker"
+	"github.com/ktr0731/evans/cache"
+	"github.com/ktr0731/evans/logger"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 //go:generate moq -out mock.go . DescriptorSource
@@ -26,21 +34,174 @@ type reflection struct {
 		ListServices() ([]string, error)
 		FindSymbol(name string) (protoreflect.Descriptor, error)
 	}
+
+	mu sync.Mutex
+	// cache is nil if the descriptor cache is disabled.
+	cache       *cache.Descriptors
+	cacheLoaded bool
+	// files has the descriptors loaded from the cache. It belongs to this descriptor source, so descriptors of
+	// other servers are never mixed.
+	files *protoregistry.Files
+}
+
+// ReflectionOption is an option for NewDescriptorSourceFromReflection.
+type ReflectionOption func(*reflection)
+
+// WithDescriptorCache enables the persistent descriptor cache.
+// Cached files are loaded into the registry of the descriptor source, which is looked up before the gRPC
+// reflection client, so that symbols contained in them are resolved without reflection round-trips.
+// If the service list of the server is changed, the cache is invalidated.
+func WithDescriptorCache(c *cache.Descriptors) ReflectionOption {
+	return func(r *reflection) {
+		r.cache = c
+	}
 }
 
 func NewDescriptorSourceFromReflection(c interface {
 	ListServices() ([]string, error)
 	FindSymbol(name string) (protoreflect.Descriptor, error)
-}) DescriptorSource {
-	return &reflection{c}
+}, opts ...ReflectionOption) DescriptorSource {
+	r := &reflection{client: c, files: new(protoregistry.Files)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *reflection) ListServices() ([]string, error) {
-	return r.client.ListServices()
+	svcs, err := r.client.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	if r.cache != nil {
+		r.mu.Lock()
+		r.validateCache(svcs)
+		r.mu.Unlock()
+	}
+	return svcs, nil
 }
 
 func (r *reflection) FindSymbol(name string) (protoreflect.Descriptor, error) {
-	return r.client.FindSymbol(name)
+	if r.cache == nil {
+		return r.client.FindSymbol(name)
+	}
+
+	r.mu.Lock()
+	loaded := r.cacheLoaded
+	r.mu.Unlock()
+	if !loaded {
+		// The cache must be validated by the service list before using it.
+		if _, err := r.ListServices(); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	d, err := r.files.FindDescriptorByName(protoreflect.FullName(name))
+	r.mu.Unlock()
+	if err == nil {
+		return d, nil
+	}
+
+	d, err = r.client.FindSymbol(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.cache.Files)
+	r.addCachedFile(d.ParentFile())
+	if len(r.cache.Files) != n {
+		if err := r.cache.Save(); err != nil {
+			logger.Printf("failed to save the descriptor cache: %s", err)
+		}
+	}
+
+	return d, nil
+}
+
+// validateCache compares svcs with the service list of the cache. If these are different, validateCache
+// clears the cache. Otherwise, cached files are registered at the first call.
+func (r *reflection) validateCache(svcs []string) {
+	if !sameServices(r.cache.Services, svcs) {
+		logger.Println("the service list is changed, clear the descriptor cache")
+		r.cache.Reset(svcs)
+		r.cacheLoaded = true
+		r.files = new(protoregistry.Files)
+		if err := r.cache.Save(); err != nil {
+			logger.Printf("failed to save the descriptor cache: %s", err)
+		}
+		return
+	}
+	if r.cacheLoaded {
+		return
+	}
+	r.cacheLoaded = true
+
+	if err := r.registerCachedFiles(); err != nil {
+		logger.Printf("failed to load the descriptor cache, clear it: %s", err)
+		r.cache.Reset(svcs)
+		r.files = new(protoregistry.Files)
+	}
+}
+
+// registerCachedFiles registers cached files to r.files. Imports must precede files importing them.
+func (r *reflection) registerCachedFiles() error {
+	contents, err := r.cache.FileContents()
+	if err != nil {
+		return err
+	}
+	for _, b := range contents {
+		var fdp descriptorpb.FileDescriptorProto
+		if err := proto.Unmarshal(b, &fdp); err != nil {
+			return errors.Wrap(err, "failed to unmarshal the cached file descriptor")
+		}
+		if _, err := r.files.FindFileByPath(fdp.GetName()); err == nil {
+			continue
+		}
+		fd, err := protodesc.FileOptions{AllowUnresolvable: true}.New(&fdp, r.files)
+		if err != nil {
+			return errors.Wrapf(err, "failed to build the cached file descriptor %s", fdp.GetName())
+		}
+		if err := r.files.RegisterFile(fd); err != nil {
+			return errors.Wrapf(err, "failed to register the cached file descriptor %s", fdp.GetName())
+		}
+	}
+	return nil
+}
+
+// addCachedFile adds fd and its imports to the cache. Imports are added first.
+func (r *reflection) addCachedFile(fd protoreflect.FileDescriptor) {
+	if fd.IsPlaceholder() || r.cache.HasFile(fd.Path()) {
+		return
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		r.addCachedFile(imports.Get(i).FileDescriptor)
+	}
+	b, err := proto.Marshal(protodesc.ToFileDescriptorProto(fd))
+	if err != nil {
+		logger.Printf("failed to marshal %s: %s", fd.Path(), err)
+		return
+	}
+	r.cache.AddFile(fd.Path(), b)
+}
+
+// sameServices reports whether a and b have the same services regardless of the order.
+func sameServices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sa, sb := append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(sa)
+	sort.Strings(sb)
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+	return true
 }
 
 func (r *reflection) GetAllMessages() ([]string, error) {
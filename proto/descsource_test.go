@@ -0,0 +1,82 @@
+package proto_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ktr0731/evans/cache"
+	"github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// fakeReflectionClient resolves symbols from fd and its imports.
+type fakeReflectionClient struct {
+	svcs []string
+	// fd is nil if the server has no descriptors.
+	fd    protoreflect.FileDescriptor
+	calls int
+}
+
+func (c *fakeReflectionClient) ListServices() ([]string, error) {
+	return c.svcs, nil
+}
+
+func (c *fakeReflectionClient) FindSymbol(name string) (protoreflect.Descriptor, error) {
+	c.calls++
+	if c.fd == nil {
+		return nil, errors.Wrapf(protoregistry.NotFound, "symbol %s", name)
+	}
+	files := new(protoregistry.Files)
+	for i := 0; i < c.fd.Imports().Len(); i++ {
+		if err := files.RegisterFile(c.fd.Imports().Get(i).FileDescriptor); err != nil {
+			return nil, err
+		}
+	}
+	if err := files.RegisterFile(c.fd); err != nil {
+		return nil, err
+	}
+	return files.FindDescriptorByName(protoreflect.FullName(name))
+}
+
+func TestReflection_descriptorCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	newSource := func(t *testing.T, c *fakeReflectionClient) proto.DescriptorSource {
+		t.Helper()
+		descCache, err := cache.GetDescriptors("key", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return proto.NewDescriptorSourceFromReflection(c, proto.WithDescriptorCache(descCache))
+	}
+
+	svcs := []string{"api.Example"}
+	if _, err := newSource(t, &fakeReflectionClient{svcs: svcs, fd: compileTestAPI(t)}).FindSymbol("api.Example"); err != nil {
+		t.Fatalf("FindSymbol must not return an error, but got '%s'", err)
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		c := &fakeReflectionClient{svcs: svcs}
+		s := newSource(t, c)
+		for _, symbol := range []string{"api.Example", "api.Example.Unary", "dep.Request"} {
+			if _, err := s.FindSymbol(symbol); err != nil {
+				t.Errorf("FindSymbol(%s) must not return an error, but got '%s'", symbol, err)
+			}
+		}
+		if c.calls != 0 {
+			t.Errorf("cached symbols must not be resolved by the client, but it is called %d times", c.calls)
+		}
+		if _, err := protoregistry.GlobalFiles.FindFileByPath("api.proto"); err == nil {
+			t.Error("cached files must not be registered to protoregistry.GlobalFiles")
+		}
+	})
+
+	t.Run("service list is changed", func(t *testing.T) {
+		s := newSource(t, &fakeReflectionClient{svcs: []string{"api.Another"}})
+		if _, err := s.FindSymbol("api.Example"); !errors.Is(err, protoregistry.NotFound) {
+			t.Errorf("FindSymbol must return protoregistry.NotFound after the cache is cleared, but got '%v'", err)
+		}
+	})
+}
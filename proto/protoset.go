@@ -0,0 +1,193 @@
+package proto
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type protoset struct {
+	// fds holds the top-level file descriptors in the order of the passed protoset files.
+	fds   []protoreflect.FileDescriptor
+	files *protoregistry.Files
+}
+
+// NewDescriptorSourceFromProtosets returns a DescriptorSource which loads serialized FileDescriptorSets
+// such as the output of `protoc -o` or `buf build -o`.
+// Each file may be encoded in the binary format or the JSON format.
+// Dependencies that are not contained in the sets (e.g. well-known types) are resolved from
+// protoregistry.GlobalFiles.
+func NewDescriptorSourceFromProtosets(fnames []string) (DescriptorSource, error) {
+	var (
+		order []string
+		fdps  = make(map[string]*descriptorpb.FileDescriptorProto)
+	)
+	for _, fname := range fnames {
+		set, err := readProtoset(fname)
+		if err != nil {
+			return nil, err
+		}
+		for _, fdp := range set.GetFile() {
+			// The same file may be contained in several sets.
+			if _, ok := fdps[fdp.GetName()]; ok {
+				continue
+			}
+			fdps[fdp.GetName()] = fdp
+			order = append(order, fdp.GetName())
+		}
+	}
+
+	s := &protoset{files: &protoregistry.Files{}}
+	for _, name := range order {
+		if _, err := s.register(name, fdps, nil); err != nil {
+			return nil, err
+		}
+	}
+	// Like the proto files source, only services of the top-level files are listed. Files imported by another file
+	// are dependencies that sets built with --include_imports contain.
+	imported := make(map[string]bool)
+	for _, fdp := range fdps {
+		for _, dep := range fdp.GetDependency() {
+			imported[dep] = true
+		}
+	}
+	for _, name := range order {
+		if imported[name] {
+			continue
+		}
+		fd, err := s.files.FindFileByPath(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "proto: failed to find file %s", name)
+		}
+		s.fds = append(s.fds, fd)
+	}
+
+	return s, nil
+}
+
+func readProtoset(fname string) (*descriptorpb.FileDescriptorSet, error) {
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "proto: failed to read protoset file %s", fname)
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if filepath.Ext(fname) == ".json" || bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		err = protojson.Unmarshal(b, &set)
+	} else {
+		err = proto.Unmarshal(b, &set)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "proto: failed to decode protoset file %s", fname)
+	}
+
+	return &set, nil
+}
+
+// register registers the file named name and its dependencies to s.files recursively.
+// visiting is used to detect import cycles.
+func (s *protoset) register(
+	name string,
+	fdps map[string]*descriptorpb.FileDescriptorProto,
+	visiting map[string]bool,
+) (protoreflect.FileDescriptor, error) {
+	if fd, err := s.files.FindFileByPath(name); err == nil {
+		return fd, nil
+	}
+	fdp, ok := fdps[name]
+	if !ok {
+		// The dependency is not contained in protosets.
+		fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+		if err != nil {
+			return nil, errors.Errorf("proto: dependency %s is not found. protoset files must include all imports", name)
+		}
+		return fd, nil
+	}
+	if visiting == nil {
+		visiting = make(map[string]bool)
+	}
+	if visiting[name] {
+		return nil, errors.Errorf("proto: import cycle detected in %s", name)
+	}
+	visiting[name] = true
+
+	for _, dep := range fdp.GetDependency() {
+		if _, err := s.register(dep, fdps, visiting); err != nil {
+			return nil, err
+		}
+	}
+
+	fd, err := protodesc.NewFile(fdp, &protosetResolver{files: s.files})
+	if err != nil {
+		return nil, errors.Wrapf(err, "proto: failed to build file descriptor %s", name)
+	}
+	if err := s.files.RegisterFile(fd); err != nil {
+		return nil, errors.Wrapf(err, "proto: failed to register file descriptor %s", name)
+	}
+
+	return fd, nil
+}
+
+func (s *protoset) ListServices() ([]string, error) {
+	var services []string
+	for _, fd := range s.fds {
+		for i := 0; i < fd.Services().Len(); i++ {
+			services = append(services, string(fd.Services().Get(i).FullName()))
+		}
+	}
+
+	return services, nil
+}
+
+func (s *protoset) FindSymbol(name string) (protoreflect.Descriptor, error) {
+	d, err := s.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, errors.Wrapf(errSymbolNotFound, "symbol %s", name)
+	}
+
+	return d, nil
+}
+
+func (s *protoset) GetAllMessages() ([]string, error) {
+	var messages []string
+	encountered := make(map[string]struct{})
+
+	for _, fd := range s.fds {
+		for i := 0; i < fd.Messages().Len(); i++ {
+			msgName := string(fd.Messages().Get(i).Name())
+			if _, found := encountered[msgName]; !found {
+				messages = append(messages, msgName)
+				encountered[msgName] = struct{}{}
+			}
+		}
+	}
+
+	return messages, nil
+}
+
+// protosetResolver resolves dependencies from loaded protosets first, then protoregistry.GlobalFiles.
+type protosetResolver struct {
+	files *protoregistry.Files
+}
+
+func (r *protosetResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r *protosetResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
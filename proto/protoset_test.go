@@ -0,0 +1,142 @@
+package proto_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	protosetTestAPI = `syntax = "proto3";
+package api;
+import "dep.proto";
+import "google/protobuf/empty.proto";
+service Example {
+  rpc Unary(dep.Request) returns (google.protobuf.Empty);
+}`
+	protosetTestDep = `syntax = "proto3";
+package dep;
+service Dep {
+  rpc Unary(Request) returns (Request);
+}
+message Request {
+  string name = 1;
+}`
+)
+
+// compileTestAPI compiles api.proto which imports dep.proto and google/protobuf/empty.proto.
+func compileTestAPI(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+
+	c := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{
+				"api.proto": protosetTestAPI,
+				"dep.proto": protosetTestDep,
+			}),
+		}),
+	}
+	compiled, err := c.Compile(context.TODO(), "api.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compiled[0]
+}
+
+// writeProtoset writes a FileDescriptorSet that contains api.proto and dep.proto like --include_imports.
+// Well-known types are not contained. If json is true, the set is encoded in JSON.
+func writeProtoset(t *testing.T, json bool) string {
+	t.Helper()
+
+	api := compileTestAPI(t)
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(api.Imports().Get(0).FileDescriptor),
+		protodesc.ToFileDescriptorProto(api),
+	}}
+
+	var (
+		b    []byte
+		err  error
+		name = "descriptors.protoset"
+	)
+	if json {
+		b, err = protojson.Marshal(set)
+		name = "descriptors.json"
+	} else {
+		b, err = gproto.Marshal(set)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProtosetDescriptorSource(t *testing.T) {
+	for name, json := range map[string]bool{"binary": false, "json": true} {
+		json := json
+		t.Run(name, func(t *testing.T) {
+			s, err := proto.NewDescriptorSourceFromProtosets([]string{writeProtoset(t, json)})
+			if err != nil {
+				t.Fatalf("NewDescriptorSourceFromProtosets must not return an error, but got '%s'", err)
+			}
+
+			svcs, err := s.ListServices()
+			if err != nil {
+				t.Fatalf("ListServices must not return an error, but got '%s'", err)
+			}
+			// dep.Dep is contained only as a dependency.
+			if diff := cmp.Diff([]string{"api.Example"}, svcs); diff != "" {
+				t.Errorf("unexpected services (-want, +got):\n%s", diff)
+			}
+
+			for _, symbol := range []string{"api.Example.Unary", "dep.Request", "dep.Dep"} {
+				if _, err := s.FindSymbol(symbol); err != nil {
+					t.Errorf("FindSymbol(%s) must not return an error, but got '%s'", symbol, err)
+				}
+			}
+			if _, err := s.FindSymbol("api.Unknown"); err == nil {
+				t.Error("FindSymbol must return an error for an unknown symbol")
+			}
+		})
+	}
+}
+
+func TestProtosetDescriptorSource_error(t *testing.T) {
+	dir := t.TempDir()
+	missingDep, err := gproto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:       gproto.String("api.proto"),
+		Dependency: []string{"missing.proto"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"missing_dep.protoset": missingDep,
+		"invalid.protoset":     []byte("foo"),
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"missing_dep.protoset", "invalid.protoset", "not_exist.protoset"} {
+		if _, err := proto.NewDescriptorSourceFromProtosets([]string{filepath.Join(dir, name)}); err == nil {
+			t.Errorf("%s: NewDescriptorSourceFromProtosets must return an error", name)
+		}
+	}
+}
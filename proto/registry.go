@@ -3,57 +3,109 @@ package proto
 import (
 	"strings"
 
+	"github.com/ktr0731/evans/logger"
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
 
-type anyResolver struct {
+// TypeResolver resolves message and extension types. It is compatible with *protoregistry.Types, so it can be
+// passed to protojson, prototext and proto options as a resolver for google.protobuf.Any and extensions.
+type TypeResolver interface {
+	protoregistry.MessageTypeResolver
 	protoregistry.ExtensionTypeResolver
+}
+
+type typeResolver struct {
 	descSource DescriptorSource
 }
 
-func NewAnyResolver(descSource DescriptorSource) interface {
-	protoregistry.ExtensionTypeResolver
-	protoregistry.MessageTypeResolver
-} {
-	return &anyResolver{
+// NewTypeResolver returns a TypeResolver that resolves types with descSource lazily.
+// If descSource doesn't know the type, the resolver falls back to protoregistry.GlobalTypes.
+// Other errors of descSource such as network errors are returned as they are.
+// descSource may be nil. In that case, the resolver behaves as protoregistry.GlobalTypes.
+func NewTypeResolver(descSource DescriptorSource) TypeResolver {
+	return &typeResolver{
 		descSource: descSource,
 	}
 }
 
-func (r *anyResolver) FindMessageByName(m protoreflect.FullName) (protoreflect.MessageType, error) {
-	d, err := r.descSource.FindSymbol(string(m))
+func (r *typeResolver) FindMessageByName(m protoreflect.FullName) (protoreflect.MessageType, error) {
+	d, err := r.findSymbol(m)
+	if isNotFound(err) {
+		return protoregistry.GlobalTypes.FindMessageByName(m)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if errors.Is(err, errSymbolNotFound) {
-		// Fallback to protoregistry.GlobalTypes.
-		return protoregistry.GlobalTypes.FindMessageByName(m)
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a message", m)
 	}
+	return dynamicpb.NewMessageType(md), nil
+}
 
-	md := d.(protoreflect.MessageDescriptor) // TODO: handle "ok".
+func (r *typeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	if n := strings.LastIndex(url, "/"); n != -1 {
+		name = url[n+1:]
+	}
 
+	d, err := r.findSymbol(protoreflect.FullName(name))
+	if isNotFound(err) {
+		return protoregistry.GlobalTypes.FindMessageByURL(url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a message", name)
+	}
 	return dynamicpb.NewMessageType(md), nil
 }
 
-func (r *anyResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
-	n := strings.LastIndex(url, "/")
-	if n != -1 {
-		url = url[n+1:]
+func (r *typeResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	d, err := r.findSymbol(field)
+	if isNotFound(err) {
+		return protoregistry.GlobalTypes.FindExtensionByName(field)
 	}
-
-	d, err := r.descSource.FindSymbol(url)
-	if err != nil && !errors.Is(err, errSymbolNotFound) {
+	if err != nil {
 		return nil, err
 	}
-	if errors.Is(err, errSymbolNotFound) {
-		// Fallback to protoregistry.GlobalTypes.
-		return protoregistry.GlobalTypes.FindMessageByURL(url)
+	xd, ok := d.(protoreflect.ExtensionDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not an extension", field)
 	}
+	if xtd, ok := xd.(protoreflect.ExtensionTypeDescriptor); ok {
+		return xtd.Type(), nil
+	}
+	return dynamicpb.NewExtensionType(xd), nil
+}
 
-	md := d.(protoreflect.MessageDescriptor) // TODO: handle "ok".
+// FindExtensionByNumber always uses protoregistry.GlobalTypes because DescriptorSource cannot find
+// extensions by the extended message and the field number.
+func (r *typeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
 
-	return dynamicpb.NewMessageType(md), nil
+func (r *typeResolver) findSymbol(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if r.descSource == nil {
+		return nil, protoregistry.NotFound
+	}
+	d, err := r.descSource.FindSymbol(string(name))
+	if isNotFound(err) {
+		logger.Printf("%s is not found in the descriptor source, fallback to the global registry: %s", name, err)
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find %s from the descriptor source", name)
+	}
+	return d, nil
+}
+
+// isNotFound reports whether err means that a descriptor source doesn't have the symbol.
+func isNotFound(err error) bool {
+	return errors.Is(err, errSymbolNotFound) || errors.Is(err, protoregistry.NotFound)
 }
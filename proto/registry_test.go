@@ -0,0 +1,42 @@
+package proto_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ktr0731/evans/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func TestTypeResolver(t *testing.T) {
+	errNetwork := errors.New("connection refused")
+	cases := map[string]struct {
+		err    error
+		hasErr bool
+	}{
+		"not found falls back to the global registry": {err: protoregistry.NotFound},
+		"other errors are returned":                   {err: errNetwork, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			r := proto.NewTypeResolver(&proto.DescriptorSourceMock{
+				FindSymbolFunc: func(string) (protoreflect.Descriptor, error) { return nil, c.err },
+			})
+			mt, err := r.FindMessageByURL("type.googleapis.com/google.protobuf.Empty")
+			if c.hasErr {
+				if !errors.Is(err, c.err) {
+					t.Errorf("FindMessageByURL must return '%s', but got '%v'", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindMessageByURL must not return an error, but got '%s'", err)
+			}
+			if n := mt.Descriptor().FullName(); n != "google.protobuf.Empty" {
+				t.Errorf("expected google.protobuf.Empty, but got %s", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+// Package proxy provides a gRPC server that forwards all RPCs to an upstream server and logs them as JSON lines.
+// The logged records can be replayed by the same server without the upstream.
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktr0731/evans/format"
+	formatjson "github.com/ktr0731/evans/format/json"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Server is a gRPC server that forwards RPCs to the upstream, or replays recorded RPCs.
+type Server struct {
+	server     *gogrpc.Server
+	descSource proto.DescriptorSource
+	resolver   proto.TypeResolver
+	upstream   grpc.Client
+	records    []*Record
+	log        io.Writer
+
+	mu sync.Mutex
+}
+
+// Option configures optional behaviors of Server.
+type Option func(*Server)
+
+// WithUpstream forwards all RPCs to upstream.
+func WithUpstream(upstream grpc.Client) Option {
+	return func(s *Server) {
+		s.upstream = upstream
+	}
+}
+
+// WithReplay answers RPCs with records instead of forwarding them.
+// A record is used if the method and request messages match the RPC. If some records match, the first one is used.
+// Request messages of a bidi streaming RPC are matched only by the first one.
+func WithReplay(records []*Record) Option {
+	return func(s *Server) {
+		s.records = records
+	}
+}
+
+// WithLog writes a record for each RPC to w as a line of JSON.
+func WithLog(w io.Writer) Option {
+	return func(s *Server) {
+		s.log = w
+	}
+}
+
+// New returns a new Server. descSource is used to decode messages.
+// Either of WithUpstream or WithReplay is required.
+func New(descSource proto.DescriptorSource, opts ...Option) (*Server, error) {
+	s := &Server{
+		descSource: descSource,
+		resolver:   proto.NewTypeResolver(descSource),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.upstream == nil && s.records == nil {
+		return nil, errors.New("either of the upstream or records is required")
+	}
+	s.server = gogrpc.NewServer(gogrpc.UnknownServiceHandler(s.handle))
+	return s, nil
+}
+
+// Serve accepts connections on lis. It blocks until Stop is called.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Stop stops the server gracefully.
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+func (s *Server) handle(_ interface{}, stream gogrpc.ServerStream) error {
+	fullMethod, ok := gogrpc.MethodFromServerStream(stream)
+	if !ok {
+		return status.Error(codes.Internal, "failed to get the method name")
+	}
+	rec := &recorder{
+		Record: &Record{
+			Time:   time.Now(),
+			Method: strings.TrimPrefix(strings.ReplaceAll(fullMethod, "/", "."), "."),
+			Header: requestHeader(stream.Context()),
+		},
+		s:  s,
+		md: s.findMethod(fullMethod),
+	}
+
+	var err error
+	if s.records != nil {
+		err = s.replay(stream, rec)
+	} else {
+		err = s.forward(stream, rec)
+	}
+	s.writeRecord(rec, err)
+	return err
+}
+
+// findMethod returns the method descriptor of fullMethod. It returns nil if the method is not found.
+func (s *Server) findMethod(fullMethod string) protoreflect.MethodDescriptor {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	i := strings.LastIndex(fullMethod, "/")
+	if i == -1 {
+		return nil
+	}
+	d, err := s.descSource.FindSymbol(fullMethod[:i])
+	if err != nil {
+		return nil
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	return sd.Methods().ByName(protoreflect.Name(fullMethod[i+1:]))
+}
+
+// requestHeader returns the incoming header of ctx.
+func requestHeader(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return filterHeader(md)
+}
+
+// filterHeader returns in except for pseudo headers and headers reserved by gRPC.
+func filterHeader(in metadata.MD) metadata.MD {
+	md := metadata.MD{}
+	for k, v := range in {
+		if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") || k == "content-type" || k == "user-agent" || k == "te" {
+			continue
+		}
+		md[k] = v
+	}
+	return md
+}
+
+func (s *Server) forward(stream gogrpc.ServerStream, rec *recorder) error {
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(stream.Context(), rec.Header))
+	defer cancel()
+
+	if rec.md != nil && !rec.md.IsStreamingClient() && !rec.md.IsStreamingServer() {
+		req := rec.newMessage(MessageTypeRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		rec.add(MessageTypeRequest, req)
+		res := rec.newMessage(MessageTypeResponse)
+		header, trailer, err := s.upstream.Invoke(ctx, rec.Method, req, res)
+		header = filterHeader(header)
+		rec.ResponseHeader, rec.Trailer = header, trailer
+		if len(header) != 0 {
+			if err := stream.SetHeader(header); err != nil {
+				return err
+			}
+		}
+		stream.SetTrailer(trailer)
+		if err != nil {
+			return err
+		}
+		rec.add(MessageTypeResponse, res)
+		return stream.SendMsg(res)
+	}
+
+	// Methods not found in descriptors are forwarded as bidi streaming methods.
+	desc := &gogrpc.StreamDesc{ClientStreams: true, ServerStreams: true}
+	if rec.md != nil {
+		desc = &gogrpc.StreamDesc{ClientStreams: rec.md.IsStreamingClient(), ServerStreams: rec.md.IsStreamingServer()}
+	}
+	up, err := s.upstream.NewBidiStream(ctx, desc, rec.Method)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			req := rec.newMessage(MessageTypeRequest)
+			err := stream.RecvMsg(req)
+			if errors.Is(err, io.EOF) {
+				up.CloseSend() //nolint:errcheck
+				return
+			}
+			if err != nil {
+				cancel()
+				return
+			}
+			rec.add(MessageTypeRequest, req)
+			if err := up.Send(req); err != nil {
+				// The error is returned by Receive.
+				return
+			}
+		}
+	}()
+
+	headerSent := false
+	sendHeader := func() error {
+		if headerSent {
+			return nil
+		}
+		headerSent = true
+		header, err := up.Header()
+		header = filterHeader(header)
+		if err != nil || len(header) == 0 {
+			return nil
+		}
+		rec.ResponseHeader = header
+		return stream.SendHeader(header)
+	}
+	for {
+		res := rec.newMessage(MessageTypeResponse)
+		err := up.Receive(res)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if err := sendHeader(); err != nil {
+				return err
+			}
+			rec.Trailer = up.Trailer()
+			stream.SetTrailer(rec.Trailer)
+			return err
+		}
+		if err := sendHeader(); err != nil {
+			return err
+		}
+		rec.add(MessageTypeResponse, res)
+		if err := stream.SendMsg(res); err != nil {
+			return err
+		}
+	}
+	if err := sendHeader(); err != nil {
+		return err
+	}
+	rec.Trailer = up.Trailer()
+	stream.SetTrailer(rec.Trailer)
+	return nil
+}
+
+func (s *Server) writeRecord(rec *recorder, err error) {
+	rec.Duration = time.Since(rec.Time).String()
+	rec.Status = s.convertStatus(status.Convert(err))
+	if s.log == nil {
+		return
+	}
+	// Messages may be still added by the goroutine which receives requests.
+	rec.mu.Lock()
+	b, err := json.Marshal(rec.Record)
+	rec.mu.Unlock()
+	if err != nil {
+		// Unreachable because all fields are marshalable.
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.Write(append(b, '\n')) //nolint:errcheck
+}
+
+func (s *Server) convertStatus(st *status.Status) *Status {
+	var details []json.RawMessage
+	for _, d := range format.StatusDetails(st, s.resolver) {
+		b, err := s.marshal(d)
+		if err != nil {
+			continue
+		}
+		details = append(details, b)
+	}
+	return &Status{
+		Code:    st.Code().String(),
+		Number:  uint32(st.Code()),
+		Message: st.Message(),
+		Details: details,
+	}
+}
+
+func (s *Server) marshal(m protov2.Message) (json.RawMessage, error) {
+	v, err := formatjson.ConvertMessage(m, false, s.resolver)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// recorder records messages of an RPC.
+type recorder struct {
+	*Record
+
+	s  *Server
+	md protoreflect.MethodDescriptor
+	mu sync.Mutex
+}
+
+// newMessage returns a new message of typ. If the method is not found in descriptors,
+// newMessage returns an empty message which keeps all fields as unknown fields.
+func (r *recorder) newMessage(typ string) protov2.Message {
+	switch {
+	case r.md == nil:
+		return &emptypb.Empty{}
+	case typ == MessageTypeRequest:
+		return dynamicpb.NewMessage(r.md.Input())
+	default:
+		return dynamicpb.NewMessage(r.md.Output())
+	}
+}
+
+func (r *recorder) add(typ string, m protov2.Message) {
+	msg := &Message{Type: typ, Elapsed: time.Since(r.Time).String(), Body: json.RawMessage("null")}
+	if r.md != nil {
+		if b, err := r.s.marshal(m); err == nil {
+			msg.Body = b
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Messages = append(r.Messages, msg)
+}
@@ -0,0 +1,309 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/mockserver"
+	"github.com/ktr0731/evans/proto"
+	"github.com/ktr0731/evans/proxy"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const stubs = `
+stubs:
+  - method: api.Example.Unary
+    match:
+      name: oumae
+    header:
+      x-stub: oumae
+    response:
+      message: hello oumae
+  - method: api.Example.Unary
+    match:
+      name: kousaka
+    status:
+      code: NotFound
+      message: not found
+  - method: api.Example.ServerStreaming
+    responses:
+      - message: hello 1
+      - message: hello 2
+    trailer:
+      x-stub: streaming
+  - method: api.Example.BidiStreaming
+    match:
+      name: kato
+    response:
+      message: hello kato
+`
+
+func newDescSource(t *testing.T) proto.DescriptorSource {
+	t.Helper()
+	descSource, err := proto.NewDescriptorSourceFromFiles([]string{"testdata"}, []string{"test.proto"})
+	if err != nil {
+		t.Fatalf("failed to load the proto file: %s", err)
+	}
+	return descSource
+}
+
+type server interface {
+	Serve(net.Listener) error
+	Stop()
+}
+
+func serve(t *testing.T, srv server) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	go srv.Serve(lis) //nolint:errcheck
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func startUpstream(t *testing.T) grpc.Client {
+	t.Helper()
+	s, err := mockserver.Load(strings.NewReader(stubs))
+	if err != nil {
+		t.Fatalf("failed to load stubs: %s", err)
+	}
+	srv, err := mockserver.New(newDescSource(t), mockserver.WithStubs(s))
+	if err != nil {
+		t.Fatalf("failed to instantiate the upstream: %s", err)
+	}
+	client, err := grpc.NewClient(serve(t, srv), "", false, false, "", "", "", nil)
+	if err != nil {
+		t.Fatalf("failed to dial to the upstream: %s", err)
+	}
+	t.Cleanup(func() { client.Close(context.Background()) })
+	return client
+}
+
+func startProxy(t *testing.T, opts ...proxy.Option) *gogrpc.ClientConn {
+	t.Helper()
+	srv, err := proxy.New(newDescSource(t), opts...)
+	if err != nil {
+		t.Fatalf("New must not return an error, but got '%s'", err)
+	}
+	conn, err := gogrpc.NewClient(serve(t, srv), gogrpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+type result struct {
+	messages []string
+	header   []string
+	trailer  []string
+	code     codes.Code
+}
+
+// call calls method of conn with requests which have names, and returns x-stub headers and trailers.
+func call(t *testing.T, conn *gogrpc.ClientConn, method string, names ...string) *result {
+	t.Helper()
+	md := newDescSource(t)
+	d, err := md.FindSymbol("api.Example." + method)
+	if err != nil {
+		t.Fatalf("failed to find the method: %s", err)
+	}
+	m := d.(protoreflect.MethodDescriptor)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request", "foo")
+	desc := &gogrpc.StreamDesc{ClientStreams: m.IsStreamingClient(), ServerStreams: m.IsStreamingServer()}
+	stream, err := conn.NewStream(ctx, desc, "/api.Example/"+method)
+	if err != nil {
+		t.Fatalf("failed to create a stream: %s", err)
+	}
+	for _, name := range names {
+		req := dynamicpb.NewMessage(m.Input())
+		req.Set(m.Input().Fields().ByName("name"), protoreflect.ValueOfString(name))
+		if err := stream.SendMsg(req); err != nil {
+			t.Fatalf("failed to send a request: %s", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("failed to close the stream: %s", err)
+	}
+
+	var r result
+	for {
+		res := dynamicpb.NewMessage(m.Output())
+		err := stream.RecvMsg(res)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			r.code = status.Code(err)
+			break
+		}
+		r.messages = append(r.messages, res.Get(m.Output().Fields().ByName("message")).String())
+	}
+	header, _ := stream.Header()
+	r.header = header.Get("x-stub")
+	r.trailer = stream.Trailer().Get("x-stub")
+	return &r
+}
+
+func TestServer(t *testing.T) {
+	cases := map[string]struct {
+		method   string
+		names    []string
+		expected *result
+	}{
+		"unary": {
+			method:   "Unary",
+			names:    []string{"oumae"},
+			expected: &result{messages: []string{"hello oumae"}, header: []string{"oumae"}},
+		},
+		"unary with an error": {
+			method:   "Unary",
+			names:    []string{"kousaka"},
+			expected: &result{code: codes.NotFound},
+		},
+		"server streaming": {
+			method:   "ServerStreaming",
+			names:    []string{"oumae"},
+			expected: &result{messages: []string{"hello 1", "hello 2"}, trailer: []string{"streaming"}},
+		},
+		"bidi streaming": {
+			method:   "BidiStreaming",
+			names:    []string{"kato", "oumae"},
+			expected: &result{messages: []string{"hello kato", "message"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	conn := startProxy(t, proxy.WithUpstream(startUpstream(t)), proxy.WithLog(&buf))
+	order := []string{"unary", "unary with an error", "server streaming", "bidi streaming"}
+	for _, name := range order {
+		c := cases[name]
+		t.Run("forward/"+name, func(t *testing.T) {
+			if diff := cmp.Diff(c.expected, call(t, conn, c.method, c.names...), cmp.AllowUnexported(result{})); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+
+	records, err := proxy.LoadRecords(&buf)
+	if err != nil {
+		t.Fatalf("LoadRecords must not return an error, but got '%s'", err)
+	}
+	if len(records) != len(order) {
+		t.Fatalf("expected %d records, but got %d", len(order), len(records))
+	}
+	for i, name := range order {
+		c, r := cases[name], records[i]
+		if r.Method != "api.Example."+c.method {
+			t.Errorf("%s: expected method api.Example.%s, but got %s", name, c.method, r.Method)
+		}
+		if r.Status.Code != c.expected.code.String() {
+			t.Errorf("%s: expected status %s, but got %s", name, c.expected.code, r.Status.Code)
+		}
+		if diff := cmp.Diff([]string{"foo"}, r.Header.Get("x-request")); diff != "" {
+			t.Errorf("%s: (-want, +got)\n%s", name, diff)
+		}
+		if n := len(r.Messages); n != len(c.names)+len(c.expected.messages) {
+			t.Errorf("%s: expected %d messages, but got %d", name, len(c.names)+len(c.expected.messages), n)
+		}
+	}
+
+	conn = startProxy(t, proxy.WithReplay(records))
+	for _, name := range order {
+		c := cases[name]
+		t.Run("replay/"+name, func(t *testing.T) {
+			if diff := cmp.Diff(c.expected, call(t, conn, c.method, c.names...), cmp.AllowUnexported(result{})); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+	t.Run("replay/unmatched", func(t *testing.T) {
+		if r := call(t, conn, "Unary", "kato"); r.code != codes.Unimplemented {
+			t.Errorf("expected code %s, but got %s", codes.Unimplemented, r.code)
+		}
+	})
+}
+
+func TestServer_replayExactMatch(t *testing.T) {
+	// The first record has an empty request, which is a subset of any requests.
+	// The second one is a subset of the third one.
+	in := strings.Join([]string{
+		`{"method":"api.Example.Unary","messages":[{"type":"request","body":{}},{"type":"response","body":{"message":"empty"}}],"status":{"code":"OK"}}`,
+		`{"method":"api.Example.Unary","messages":[{"type":"request","body":{"name":"oumae"}},{"type":"response","body":{"message":"name"}}],"status":{"code":"OK"}}`,
+		`{"method":"api.Example.Unary","messages":[{"type":"request","body":{"name":"oumae","id":"1"}},{"type":"response","body":{"message":"name and id"}}],"status":{"code":"OK"}}`,
+	}, "\n")
+	records, err := proxy.LoadRecords(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("LoadRecords must not return an error, but got '%s'", err)
+	}
+	conn := startProxy(t, proxy.WithReplay(records))
+
+	d, err := newDescSource(t).FindSymbol("api.Example.Unary")
+	if err != nil {
+		t.Fatalf("failed to find the method: %s", err)
+	}
+	m := d.(protoreflect.MethodDescriptor)
+	fields := m.Input().Fields()
+
+	cases := map[string]struct {
+		name     string
+		id       int64
+		expected string
+		code     codes.Code
+	}{
+		"empty request":         {expected: "empty"},
+		"subset of the other":   {name: "oumae", expected: "name"},
+		"superset of the other": {name: "oumae", id: 1, expected: "name and id"},
+		"unmatched":             {name: "oumae", id: 2, code: codes.Unimplemented},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			req := dynamicpb.NewMessage(m.Input())
+			req.Set(fields.ByName("name"), protoreflect.ValueOfString(c.name))
+			req.Set(fields.ByName("id"), protoreflect.ValueOfInt64(c.id))
+			res := dynamicpb.NewMessage(m.Output())
+			err := conn.Invoke(context.Background(), "/api.Example/Unary", req, res)
+			if code := status.Code(err); code != c.code {
+				t.Fatalf("expected code %s, but got '%v'", c.code, err)
+			}
+			if actual := res.Get(m.Output().Fields().ByName("message")).String(); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadRecords_error(t *testing.T) {
+	cases := map[string]string{
+		"invalid JSON":           `{`,
+		"no method":              `{"status":{"code":"OK"}}`,
+		"no status":              `{"method":"api.Example.Unary"}`,
+		"unknown type":           `{"method":"api.Example.Unary","status":{"code":"OK"},"messages":[{"type":"foo"}]}`,
+		"second line is invalid": "{\"method\":\"api.Example.Unary\",\"status\":{\"code\":\"OK\"}}\n{",
+	}
+	for name, in := range cases {
+		in := in
+		t.Run(name, func(t *testing.T) {
+			if _, err := proxy.LoadRecords(strings.NewReader(in)); err == nil {
+				t.Error("LoadRecords must return an error")
+			}
+		})
+	}
+}
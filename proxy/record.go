@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// Message types of Message.
+const (
+	MessageTypeRequest  = "request"
+	MessageTypeResponse = "response"
+)
+
+// Record is a log entry of an RPC. Server writes each record as a line of JSON.
+type Record struct {
+	// Time is the time when the RPC started.
+	Time time.Time `json:"time"`
+	// Method is the fully-qualified method name like "api.Example.Unary".
+	Method string `json:"method"`
+	// Header is the request header.
+	Header metadata.MD `json:"header,omitempty"`
+	// Messages are request and response messages in the order of forwarding.
+	Messages []*Message `json:"messages"`
+	// ResponseHeader is the response header.
+	ResponseHeader metadata.MD `json:"responseHeader,omitempty"`
+	// Trailer is the response trailer.
+	Trailer metadata.MD `json:"trailer,omitempty"`
+	// Status is the status of the RPC.
+	Status *Status `json:"status"`
+	// Duration is the time taken by the RPC like "1.5ms".
+	Duration string `json:"duration"`
+}
+
+// Message is a request or response message of an RPC.
+type Message struct {
+	// Type is MessageTypeRequest or MessageTypeResponse.
+	Type string `json:"type"`
+	// Elapsed is the elapsed time from the start of the RPC like "1.5ms".
+	Elapsed string `json:"elapsed"`
+	// Body is the message in the JSON mapping of Protocol Buffers.
+	// It is null if the method is not found in descriptors.
+	Body json.RawMessage `json:"body"`
+}
+
+// Status is the status of an RPC.
+type Status struct {
+	Code    string `json:"code"`
+	Number  uint32 `json:"number"`
+	Message string `json:"message,omitempty"`
+	// Details are google.protobuf.Any messages in the JSON mapping of Protocol Buffers.
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+// messages returns bodies of messages which have typ.
+func (r *Record) messages(typ string) []json.RawMessage {
+	var bodies []json.RawMessage
+	for _, m := range r.Messages {
+		if m.Type == typ {
+			bodies = append(bodies, m.Body)
+		}
+	}
+	return bodies
+}
+
+// LoadRecordsFile loads records from a file written by Server.
+func LoadRecordsFile(path string) ([]*Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the session file")
+	}
+	defer f.Close()
+	return LoadRecords(f)
+}
+
+// LoadRecords decodes records from r. Each line of r is a record. Empty lines are ignored.
+func LoadRecords(r io.Reader) ([]*Record, error) {
+	var records []*Record
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, errors.Wrapf(err, "line %d: invalid record", n)
+		}
+		if rec.Method == "" {
+			return nil, errors.Errorf("line %d: method is required", n)
+		}
+		if rec.Status == nil {
+			return nil, errors.Errorf("line %d: status is required", n)
+		}
+		for i, m := range rec.Messages {
+			if m == nil || (m.Type != MessageTypeRequest && m.Type != MessageTypeResponse) {
+				return nil, errors.Errorf("line %d: messages[%d] must have type %s or %s", n, i, MessageTypeRequest, MessageTypeResponse)
+			}
+		}
+		records = append(records, &rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read records")
+	}
+	return records, nil
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/ktr0731/evans/assertion"
+	"github.com/pkg/errors"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func (s *Server) replay(stream gogrpc.ServerStream, rec *recorder) error {
+	if rec.md == nil {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", rec.Method)
+	}
+
+	var reqs []protov2.Message
+	recv := func() error {
+		req := rec.newMessage(MessageTypeRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		rec.add(MessageTypeRequest, req)
+		reqs = append(reqs, req)
+		return nil
+	}
+
+	bidi := rec.md.IsStreamingClient() && rec.md.IsStreamingServer()
+	for {
+		err := recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !rec.md.IsStreamingClient() || bidi {
+			break
+		}
+	}
+
+	recorded := s.findRecord(rec, reqs, bidi)
+	if recorded == nil {
+		return status.Errorf(codes.Unimplemented, "no recorded RPCs of %s match the request", rec.Method)
+	}
+
+	if len(recorded.ResponseHeader) != 0 {
+		rec.ResponseHeader = recorded.ResponseHeader
+		if err := stream.SetHeader(recorded.ResponseHeader); err != nil {
+			return err
+		}
+	}
+	if len(recorded.Trailer) != 0 {
+		rec.Trailer = recorded.Trailer
+		stream.SetTrailer(recorded.Trailer)
+	}
+
+	// Responses are sent in the recorded order. In bidi streaming, the server waits for a request
+	// before sending the responses following it.
+	skip := len(reqs)
+	for i, m := range recorded.Messages {
+		if m.Type == MessageTypeRequest {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if !bidi {
+				continue
+			}
+			if err := recv(); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			continue
+		}
+		res := rec.newMessage(MessageTypeResponse)
+		if err := (protojson.UnmarshalOptions{Resolver: s.resolver}).Unmarshal(m.Body, res); err != nil {
+			return status.Errorf(codes.Internal, "invalid messages[%d] of the record: %s", i, err)
+		}
+		rec.add(MessageTypeResponse, res)
+		if err := stream.SendMsg(res); err != nil {
+			return err
+		}
+	}
+	return s.recordedStatus(recorded.Status)
+}
+
+// findRecord returns the first record of the method of rec which has the same requests as reqs.
+// It returns nil if there is no such record. If prefix is true, reqs are matched only with the leading requests
+// of records.
+//
+// Requests are compared exactly as messages, so a recorded request never matches a request which has more or
+// less fields than it. Note that fields which have default values are not distinguished from omitted ones.
+func (s *Server) findRecord(rec *recorder, reqs []protov2.Message, prefix bool) *Record {
+	for _, r := range s.records {
+		if r.Method != rec.Method {
+			continue
+		}
+		recorded := r.messages(MessageTypeRequest)
+		if len(recorded) < len(reqs) || (!prefix && len(recorded) != len(reqs)) {
+			continue
+		}
+		if s.equalAll(rec, recorded, reqs) {
+			return r
+		}
+	}
+	return nil
+}
+
+// equalAll reports whether each of reqs is equal to the recorded request at the same index.
+func (s *Server) equalAll(rec *recorder, recorded []json.RawMessage, reqs []protov2.Message) bool {
+	for i, req := range reqs {
+		expected := rec.newMessage(MessageTypeRequest)
+		if err := (protojson.UnmarshalOptions{Resolver: s.resolver}).Unmarshal(recorded[i], expected); err != nil {
+			return false
+		}
+		if !protov2.Equal(expected, req) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordedStatus returns st as an error. It returns nil if st is OK.
+func (s *Server) recordedStatus(st *Status) error {
+	code := codes.Code(st.Number)
+	if st.Code != "" {
+		c, err := assertion.ParseCode(st.Code)
+		if err != nil {
+			return status.Errorf(codes.Internal, "invalid status of the record: %s", err)
+		}
+		code = c
+	}
+	if code == codes.OK {
+		return nil
+	}
+
+	p := &spb.Status{Code: int32(code), Message: st.Message}
+	for i, d := range st.Details {
+		var detail anypb.Any
+		if err := (protojson.UnmarshalOptions{Resolver: s.resolver}).Unmarshal(d, &detail); err != nil {
+			return status.Errorf(codes.Internal, "invalid status.details[%d] of the record: %s", i, err)
+		}
+		p.Details = append(p.Details, &detail)
+	}
+	return status.FromProto(p).Err()
+}
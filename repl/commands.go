@@ -5,15 +5,29 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
+	"os"
+	"os/signal"
 	"strings"
+	"time"
 	"unicode"
 
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/fill"
 	"github.com/ktr0731/evans/format"
 	"github.com/ktr0731/evans/format/curl"
+	fmtjson "github.com/ktr0731/evans/format/json"
+	"github.com/ktr0731/evans/format/ndjson"
+	"github.com/ktr0731/evans/format/textproto"
+	"github.com/ktr0731/evans/format/yaml"
 	"github.com/ktr0731/evans/idl"
+	"github.com/ktr0731/evans/interpolate"
+	"github.com/ktr0731/evans/prompt"
 	"github.com/ktr0731/evans/usecase"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -155,7 +169,18 @@ func (c *showCommand) Run(w io.Writer, args []string) error {
 }
 
 type callCommand struct {
-	enrich, digManually, bytesAsBase64, bytesAsQuotedLiterals, bytesFromFile, emitDefaults, repeatCall, addRepeatedManually bool
+	enrich, digManually, bytesAsBase64, bytesAsQuotedLiterals, bytesFromFile, emitDefaults, repeatCall, addRepeatedManually, session, edit bool
+
+	output string
+
+	timeout time.Duration
+
+	// requestCfg is used to load the default value of --timeout from request.timeout.
+	// It is replaced by the connect command when the server is switched.
+	requestCfg *config.Request
+
+	// interrupt returns a context which is canceled by an interrupt. If it is nil, cancelOnInterrupt is used.
+	interrupt func(context.Context) (context.Context, context.CancelFunc)
 }
 
 func (c *callCommand) FlagSet() (*pflag.FlagSet, bool) {
@@ -169,6 +194,14 @@ func (c *callCommand) FlagSet() (*pflag.FlagSet, bool) {
 	fs.BoolVar(&c.emitDefaults, "emit-defaults", false, "render fields with default values")
 	fs.BoolVarP(&c.repeatCall, "repeat", "r", false, "repeat previous unary or server streaming request (if exists)")
 	fs.BoolVar(&c.addRepeatedManually, "add-repeated-manually", false, "prompt asks whether to add a value if it encountered to a repeated field")
+	fs.BoolVar(&c.session, "session", false, "open the session view for a bidi streaming RPC. responses are shown above the input line with timestamps")
+	fs.BoolVar(&c.edit, "edit", false, "input requests by editing a JSON template with $EDITOR. for client streaming RPCs, save an empty file to finish inputting")
+	fs.StringVarP(&c.output, "output", "o", "curl", `output format. one of "json", "ndjson" (alias "jsonl"), "textproto", "yaml" or "curl"`)
+	var defaultTimeout time.Duration
+	if c.requestCfg != nil {
+		defaultTimeout = c.requestCfg.Timeout
+	}
+	fs.DurationVar(&c.timeout, "timeout", defaultTimeout, "the deadline of the RPC (e.g. 500ms, 3s). zero means no deadline")
 	return fs, true
 }
 
@@ -195,9 +228,28 @@ func (c *callCommand) Validate(args []string) error {
 }
 
 func (c *callCommand) Run(w io.Writer, args []string) error {
+	var rfi format.ResponseFormatterInterface
+	resolver := usecase.GetTypeResolver()
+	switch c.output {
+	case "curl":
+		rfi = curl.NewResponseFormatter(w, c.emitDefaults, resolver)
+	case "json":
+		rfi = fmtjson.NewResponseFormatter(w, c.emitDefaults, resolver)
+	case "ndjson", "jsonl":
+		rfi = ndjson.NewResponseFormatter(w, c.emitDefaults, resolver)
+	case "textproto", "text":
+		rfi = textproto.NewResponseFormatter(w, resolver)
+	case "yaml":
+		rfi = yaml.NewResponseFormatter(w, c.emitDefaults, resolver)
+	case "binary":
+		// The wire format is not readable in the terminal.
+		return errors.New(`output format "binary" is available only in CLI mode`)
+	default:
+		return errors.Errorf("unknown output format '%s'", c.output)
+	}
 	usecase.InjectPartially(
 		usecase.Dependencies{
-			ResponseFormatter: format.NewResponseFormatter(curl.NewResponseFormatter(w, c.emitDefaults), c.enrich),
+			ResponseFormatter: format.NewResponseFormatter(rfi, c.enrich),
 		},
 	)
 
@@ -212,13 +264,57 @@ func (c *callCommand) Run(w io.Writer, args []string) error {
 
 	// here we create the request context
 	// we also add the call command flags here
-	err := usecase.CallRPCInteractively(context.Background(), w, args[0], c.digManually, c.bytesAsBase64, c.bytesAsQuotedLiterals, c.bytesFromFile, c.repeatCall, c.addRepeatedManually)
+	interrupt := c.interrupt
+	if interrupt == nil {
+		interrupt = cancelOnInterrupt
+	}
+	ctx, stop := interrupt(context.Background())
+	defer stop()
+	// The deadline starts after the request is inputted.
+	ctx = usecase.WithTimeout(ctx, c.timeout)
+	if c.session {
+		// The session view shows messages in its own format, so output options are not available.
+		if c.repeatCall || c.edit || c.enrich || c.emitDefaults || c.output != "curl" {
+			return errors.New("--session cannot be used with --repeat, --edit, --enrich, --emit-defaults or --output")
+		}
+		return usecase.CallBidiStreamingRPCInSession(ctx, w, args[0], prompt.New(), fill.InteractiveFillerOpts{
+			DigManually:           c.digManually,
+			BytesAsBase64:         c.bytesAsBase64,
+			BytesAsQuotedLiterals: c.bytesAsQuotedLiterals,
+			BytesFromFile:         c.bytesFromFile,
+			AddRepeatedManually:   c.addRepeatedManually,
+		})
+	}
+	var err error
+	if c.edit {
+		if c.repeatCall {
+			return errors.New("--edit cannot be used with --repeat")
+		}
+		editor := config.Editor()
+		if editor == "" {
+			return errors.New("--edit requires one of $EDITOR value or Vim")
+		}
+		err = usecase.CallRPCWithEditor(ctx, w, args[0], editor)
+	} else {
+		err = usecase.CallRPCInteractively(ctx, w, args[0], c.digManually, c.bytesAsBase64, c.bytesAsQuotedLiterals, c.bytesFromFile, c.repeatCall, c.addRepeatedManually)
+	}
 	if errors.Is(err, io.EOF) {
 		return errors.New("inputting canceled")
 	}
+	// The enriched output already contains the trailer and the status CANCELLED.
+	if errors.Is(ctx.Err(), context.Canceled) && c.enrich && status.Code(err) == codes.Canceled {
+		return nil
+	}
 	return err
 }
 
+// cancelOnInterrupt returns a context which is canceled when SIGINT is received.
+// The prompt reads ctrl+c as a key input, so SIGINT is received only while the RPC is waiting for responses.
+// The returned stop function must be called to restore the default behavior of SIGINT.
+func cancelOnInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt)
+}
+
 type headerCommand struct {
 	raw bool
 }
@@ -241,6 +337,10 @@ func (c *headerCommand) Help() string {
 	fs.PrintDefaults()
 	return fmt.Sprintf(`usage: header [options ...] <key>=<value>[, <key>=<value>...]
 
+Values can refer to environment variables by ${NAME}, and the output of commands by $(command)
+if request.allowCommandInterpolation is enabled. These values are masked in 'show header'.
+Write $${ or $$( for a literal ${ or $(.
+
 Options:
 %s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
 }
@@ -264,21 +364,136 @@ func (c *headerCommand) Run(_ io.Writer, args []string) error {
 		}
 
 		if c.raw {
-			if err := headers.Add(sp[0], sp[1]); err != nil {
-				return errors.Wrapf(err, "failed to add a header '%s=%s'", sp[0], sp[1])
+			v, err := interpolate.ExpandSecret(sp[1])
+			if err != nil {
+				return errors.Wrapf(err, "failed to expand the header '%s'", sp[0])
+			}
+			if err := headers.Add(sp[0], v); err != nil {
+				return errors.Wrapf(err, "failed to add a header '%s=%s'", sp[0], interpolate.Mask(v))
 			}
 			return nil
 		}
 
 		for _, v := range strings.Split(sp[1], ",") {
+			v, err := interpolate.ExpandSecret(v)
+			if err != nil {
+				return errors.Wrapf(err, "failed to expand the header '%s'", sp[0])
+			}
 			if err := headers.Add(sp[0], v); err != nil {
-				return errors.Wrapf(err, "failed to add a header '%s=%s'", sp[0], v)
+				return errors.Wrapf(err, "failed to add a header '%s=%s'", sp[0], interpolate.Mask(v))
 			}
 		}
 	}
 	return nil
 }
 
+type connectCommand struct {
+	tls, web, reflection         bool
+	cacert, cert, certKey, sname string
+
+	fs *pflag.FlagSet
+
+	// cfg is the config of the current connection. It is replaced when the server is switched.
+	cfg       *config.Config
+	connector Connector
+	// switched is called with the config of the new connection after the server is switched. It may be nil.
+	switched func(cfg *config.Config)
+}
+
+func (c *connectCommand) FlagSet() (*pflag.FlagSet, bool) {
+	fs := pflag.NewFlagSet("connect", pflag.ContinueOnError)
+	fs.Usage = func() {} // Disable help output when an error occurred.
+	fs.BoolVar(&c.tls, "tls", false, "use a secure TLS connection")
+	fs.BoolVar(&c.web, "web", false, "use gRPC-Web protocol")
+	fs.BoolVarP(&c.reflection, "reflection", "r", false, "use gRPC reflection")
+	fs.StringVar(&c.cacert, "cacert", "", "the CA certificate file for verifying the server")
+	fs.StringVar(&c.cert, "cert", "", "the certificate file for mutual TLS auth. it must be provided with --certkey.")
+	fs.StringVar(&c.certKey, "certkey", "", "the private key file for mutual TLS auth. it must be provided with --cert.")
+	fs.StringVar(&c.sname, "servername", "", "override the server name used to verify the hostname (ignored if --tls is disabled)")
+	c.fs = fs
+	return fs, true
+}
+
+func (c *connectCommand) Synopsis() string {
+	return "connect to another server by an address or a profile"
+}
+
+func (c *connectCommand) Help() string {
+	var buf bytes.Buffer
+	fs, _ := c.FlagSet()
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	return fmt.Sprintf(`usage: connect [options ...] <host:port | profile>
+
+Headers and the selected package/service are kept if the new server has them.
+Options not specified are inherited from the current connection.
+
+Options:
+%s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
+}
+
+func (c *connectCommand) Validate(args []string) error {
+	if len(args) < 1 {
+		return errArgumentRequired
+	}
+	return nil
+}
+
+func (c *connectCommand) Run(w io.Writer, args []string) error {
+	if c.connector == nil {
+		return errors.New("connect is not supported in this mode")
+	}
+
+	cfg := c.cfg.Clone()
+	// The default package and service are decided by the new server.
+	cfg.Default.Package, cfg.Default.Service = "", ""
+	if host, port, err := net.SplitHostPort(args[0]); err == nil {
+		if host != "" {
+			cfg.Server.Host = host
+		}
+		cfg.Server.Port = port
+	} else {
+		cfg, err = config.ApplyProfile(cfg, args[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.fs != nil {
+		c.fs.Visit(func(f *pflag.Flag) {
+			switch f.Name {
+			case "tls":
+				cfg.Server.TLS = c.tls
+			case "web":
+				cfg.Request.Web = c.web
+			case "reflection":
+				cfg.Server.Reflection = c.reflection
+			case "cacert":
+				cfg.Request.CACertFile = c.cacert
+			case "cert":
+				cfg.Request.CertFile = c.cert
+			case "certkey":
+				cfg.Request.CertKeyFile = c.certKey
+			case "servername":
+				cfg.Server.Name = c.sname
+			}
+		})
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.connector(cfg); err != nil {
+		return errors.Wrapf(err, "failed to connect to %s:%s", cfg.Server.Host, cfg.Server.Port)
+	}
+	c.cfg = cfg
+	if c.switched != nil {
+		c.switched(cfg)
+	}
+	fmt.Fprintf(w, "connected to %s:%s\n", cfg.Server.Host, cfg.Server.Port)
+	return nil
+}
+
 type exitCommand struct{}
 
 func (c *exitCommand) Synopsis() string {
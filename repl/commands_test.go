@@ -1,6 +1,23 @@
 package repl
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/proto"
+	"github.com/ktr0731/evans/usecase"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
 
 func TestValidate(t *testing.T) {
 	type testCase struct {
@@ -21,6 +38,13 @@ func TestValidate(t *testing.T) {
 				{args: []string{}, hasErr: true},
 			},
 		},
+		"export": cmdTestCase{
+			cmd: &exportCommand{},
+			testCases: []testCase{
+				{args: []string{"out"}},
+				{args: []string{}, hasErr: true},
+			},
+		},
 		"package": cmdTestCase{
 			cmd: &packageCommand{},
 			testCases: []testCase{
@@ -56,6 +80,13 @@ func TestValidate(t *testing.T) {
 				{args: []string{}, hasErr: true},
 			},
 		},
+		"connect": cmdTestCase{
+			cmd: &connectCommand{},
+			testCases: []testCase{
+				{args: []string{"localhost:50051"}},
+				{args: []string{}, hasErr: true},
+			},
+		},
 		"exit": cmdTestCase{
 			cmd: &exitCommand{},
 			testCases: []testCase{
@@ -80,3 +111,189 @@ func TestValidate(t *testing.T) {
 		}
 	}
 }
+
+// blockingClient blocks Invoke until ctx is canceled.
+type blockingClient struct {
+	grpc.Client
+
+	invoked chan struct{}
+}
+
+func (c *blockingClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (header, trailer metadata.MD, _ error) {
+	close(c.invoked)
+	<-ctx.Done()
+	return metadata.Pairs("k", "v"), metadata.Pairs("trailer-key", "trailer-value"), status.FromContextError(ctx.Err()).Err()
+}
+
+func (c *blockingClient) Header() grpc.Headers { return grpc.Headers{} }
+
+type nopInteractiveFiller struct{}
+
+func (nopInteractiveFiller) Fill(*dynamicpb.Message, fill.InteractiveFillerOpts) error { return nil }
+
+func TestCallCommand_Run_interrupt(t *testing.T) {
+	descSource, err := proto.NewDescriptorSourceFromFiles([]string{"testdata"}, []string{"test.proto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		enrich bool
+	}{
+		"enrich":     {enrich: true},
+		"not enrich": {},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			client := &blockingClient{invoked: make(chan struct{})}
+			usecase.Inject(usecase.Dependencies{
+				DescSource:        descSource,
+				InteractiveFiller: nopInteractiveFiller{},
+				GRPCClient:        client,
+			})
+			defer usecase.Clear()
+			if err := usecase.UsePackage("api"); err != nil {
+				t.Fatal(err)
+			}
+			if err := usecase.UseService("Example"); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := &callCommand{
+				enrich: c.enrich,
+				output: "json",
+				// Interrupt while the RPC is waiting for the response.
+				interrupt: func(ctx context.Context) (context.Context, context.CancelFunc) {
+					ctx, cancel := context.WithCancel(ctx)
+					go func() {
+						<-client.invoked
+						cancel()
+					}()
+					return ctx, cancel
+				},
+			}
+
+			var w bytes.Buffer
+			err := cmd.Run(&w, []string{"RPC"})
+			if !c.enrich {
+				if status.Code(errors.Cause(err)) != codes.Canceled {
+					t.Errorf("Run must return the status Canceled, but got '%v'", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run must not return an error if --enrich is set, but got '%s'", err)
+			}
+			for _, s := range []string{`"trailer-key"`, `"code": "Canceled"`} {
+				if !strings.Contains(w.String(), s) {
+					t.Errorf("the output must contain %s, but got:\n%s", s, w.String())
+				}
+			}
+		})
+	}
+}
+
+func TestConnectCommand_Run(t *testing.T) {
+	newCfg := func() *config.Config {
+		return &config.Config{
+			Default: &config.Default{ProtoFile: []string{"api.proto"}, Package: "api", Service: "Example", SourcePriority: config.SourcePriorityReflection},
+			Server:  &config.Server{Host: "127.0.0.1", Port: "50051"},
+			Request: &config.Request{Header: config.Header{"grpc-client": {"evans"}}},
+		}
+	}
+
+	cases := map[string]struct {
+		args       []string
+		connectErr error
+
+		expectedServer  *config.Server
+		expectedRequest *config.Request
+		hasErr          bool
+	}{
+		"address": {
+			args:            []string{"example.com:443"},
+			expectedServer:  &config.Server{Host: "example.com", Port: "443"},
+			expectedRequest: &config.Request{Header: config.Header{"grpc-client": {"evans"}}},
+		},
+		"only port": {
+			args:            []string{":50052"},
+			expectedServer:  &config.Server{Host: "127.0.0.1", Port: "50052"},
+			expectedRequest: &config.Request{Header: config.Header{"grpc-client": {"evans"}}},
+		},
+		"flags": {
+			args:            []string{"--tls", "--servername", "foo", "--cacert", "ca.pem", "example.com:443"},
+			expectedServer:  &config.Server{Host: "example.com", Port: "443", TLS: true, Name: "foo"},
+			expectedRequest: &config.Request{Header: config.Header{"grpc-client": {"evans"}}, CACertFile: "ca.pem"},
+		},
+		"invalid config": {
+			args:   []string{"--cert", "cert.pem", "example.com:443"},
+			hasErr: true,
+		},
+		"connection failed": {
+			args:       []string{"example.com:443"},
+			connectErr: errors.New("an error"),
+			hasErr:     true,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			cfg := newCfg()
+			var connected, switched *config.Config
+			cmd := &connectCommand{
+				cfg: cfg,
+				connector: func(cfg *config.Config) error {
+					connected = cfg
+					return c.connectErr
+				},
+				switched: func(cfg *config.Config) {
+					switched = cfg
+				},
+			}
+			fs, _ := cmd.FlagSet()
+			if err := fs.Parse(c.args); err != nil {
+				t.Fatalf("failed to parse args: %s", err)
+			}
+
+			var w bytes.Buffer
+			err := cmd.Run(&w, fs.Args())
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("Run must return an error, but got nil")
+				}
+				if cmd.cfg != cfg || switched != nil {
+					t.Errorf("the current config must be kept")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run must not return an error, but got '%s'", err)
+			}
+
+			if cmd.cfg != connected || switched != connected {
+				t.Errorf("the config of the new connection must be passed to the command and switched")
+			}
+			if diff := cmp.Diff(newCfg(), cfg); diff != "" {
+				t.Errorf("the previous config must not be modified (-want, +got):\n%s", diff)
+			}
+
+			if connected.Default.Package != "" || connected.Default.Service != "" {
+				t.Errorf("the default package and service must be reset, but got '%s' and '%s'", connected.Default.Package, connected.Default.Service)
+			}
+			if diff := cmp.Diff(c.expectedServer, cmd.cfg.Server); diff != "" {
+				t.Errorf("unexpected server config (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(c.expectedRequest, cmd.cfg.Request); diff != "" {
+				t.Errorf("unexpected request config (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectCommand_Run_unsupported(t *testing.T) {
+	cmd := &connectCommand{cfg: &config.Config{}}
+	if err := cmd.Run(&bytes.Buffer{}, []string{"localhost:50051"}); err == nil {
+		t.Errorf("Run must return an error if the connector is not set")
+	}
+}
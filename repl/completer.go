@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/prompt"
 	"github.com/ktr0731/evans/usecase"
 	"github.com/spf13/pflag"
@@ -117,6 +118,18 @@ func newCompleter(cmds map[string]commander) *completer {
 				}
 				return s
 			},
+			"connect": func(args []string) (s []*prompt.Suggest) {
+				if len(args) == 1 {
+					profiles, err := config.Profiles()
+					if err != nil {
+						return nil
+					}
+					for name := range profiles {
+						s = append(s, prompt.NewSuggestion(name, "profile"))
+					}
+				}
+				return s
+			},
 			"call": func(args []string) (s []*prompt.Suggest) {
 				if len(args) == 1 {
 					rpcs, err := usecase.ListRPCs("")
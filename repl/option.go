@@ -0,0 +1,20 @@
+package repl
+
+import "github.com/ktr0731/evans/config"
+
+// Connector connects to the server which is described by cfg, and replaces the current connection with it.
+// If Connector returns an error, the current connection must be kept.
+type Connector func(cfg *config.Config) error
+
+type opt struct {
+	connector Connector
+}
+
+type Option func(*opt)
+
+// WithConnector enables the connect command which switches the server by c.
+func WithConnector(c Connector) Option {
+	return func(o *opt) {
+		o.connector = c
+	}
+}
@@ -1,10 +1,14 @@
 package repl
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/ktr0731/evans/usecase"
 	"github.com/olekukonko/tablewriter"
@@ -80,3 +84,47 @@ func presentTypeName(f protoreflect.FieldDescriptor) string {
 	}
 	return typeName
 }
+
+type exportCommand struct {
+	output string
+}
+
+func (c *exportCommand) FlagSet() (*pflag.FlagSet, bool) {
+	fs := pflag.NewFlagSet("export", pflag.ContinueOnError)
+	fs.Usage = func() {} // Disable help output when an error occurred.
+	fs.StringVarP(&c.output, "output", "o", usecase.ExportFormatProto, `output format. one of "proto" or "protoset"`)
+	return fs, true
+}
+
+func (c *exportCommand) Synopsis() string {
+	return "export the descriptors of loaded services to a directory"
+}
+
+func (c *exportCommand) Help() string {
+	var buf bytes.Buffer
+	fs, _ := c.FlagSet()
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	return fmt.Sprintf(`usage: export <output dir>
+
+Options:
+%s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
+}
+
+func (c *exportCommand) Validate(args []string) error {
+	if len(args) < 1 {
+		return errArgumentRequired
+	}
+	return nil
+}
+
+func (c *exportCommand) Run(w io.Writer, args []string) error {
+	names, err := usecase.ExportDescriptors(args[0], c.output)
+	if err != nil {
+		return errors.Wrap(err, "failed to export descriptors")
+	}
+	for _, name := range names {
+		fmt.Fprintln(w, filepath.Join(args[0], name))
+	}
+	return nil
+}
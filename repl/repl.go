@@ -34,6 +34,7 @@ type REPL struct {
 
 var commands = map[string]commander{
 	"call":    &callCommand{},
+	"connect": &connectCommand{},
 	"service": &serviceCommand{},
 	"header":  &headerCommand{},
 	"package": &packageCommand{},
@@ -41,12 +42,18 @@ var commands = map[string]commander{
 	"exit":    &exitCommand{},
 
 	// Depends to Protocol Buffers.
-	"desc": &descCommand{},
+	"desc":   &descCommand{},
+	"export": &exportCommand{},
 }
 
 // New instantiates a new REPL instance. New always calls p.SetPrefix for display the server addr.
 // New may return an error if some of passed arguments are invalid.
-func New(cfg *config.Config, p prompt.Prompt, ui cui.UI, pkgName, svcName string) (*REPL, error) {
+func New(cfg *config.Config, p prompt.Prompt, ui cui.UI, pkgName, svcName string, opts ...Option) (*REPL, error) {
+	var opt opt
+	for _, o := range opts {
+		o(&opt)
+	}
+
 	cmds := commands
 	// Each value must be a key of cmds.
 	aliases := map[string]string{
@@ -78,6 +85,21 @@ func New(cfg *config.Config, p prompt.Prompt, ui cui.UI, pkgName, svcName string
 		aliases:   aliases,
 	}
 
+	callCmd, _ := cmds["call"].(*callCommand)
+	if callCmd != nil {
+		callCmd.requestCfg = cfg.Request
+	}
+	if c, ok := cmds["connect"].(*connectCommand); ok {
+		c.cfg = cfg
+		c.connector = opt.connector
+		c.switched = func(cfg *config.Config) {
+			r.serverCfg = cfg.Server
+			if callCmd != nil {
+				callCmd.requestCfg = cfg.Request
+			}
+		}
+	}
+
 	return r, nil
 }
 
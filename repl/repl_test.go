@@ -135,8 +135,10 @@ func TestREPL_makePrefix(t *testing.T) {
 var expectedHelpText = `
 Available commands:
   call       call a RPC
+  connect    connect to another server by an address or a profile
   desc       describe the structure of selected message
   exit       exit current REPL
+  export     export the descriptors of loaded services to a directory
   header     set/unset headers to each request. if header value is empty, the header is removed.
   package    set a package as the currently selected package
   service    set the service as the current selected service
@@ -0,0 +1,304 @@
+// Package script provides the script format that describes a sequence of RPC calls.
+// Later steps can refer to the results of earlier steps by Go templates.
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/ktr0731/evans/fill"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Script represents a sequence of steps. Steps are executed in order.
+type Script struct {
+	Steps []*Step `json:"steps"`
+}
+
+// Step represents an RPC call.
+type Step struct {
+	// Name is the name that is used to refer to the result from later steps.
+	// If it is empty, "step<N>" is used, where N is the 1-origin index of the step.
+	Name string `json:"name"`
+	// Method is the fully-qualified method name.
+	Method string `json:"method"`
+	// Header is the headers which are sent with the request in addition to the common headers.
+	Header map[string]string `json:"header"`
+	// Request is the request message in the JSON mapping of Protocol Buffers.
+	Request interface{} `json:"request"`
+	// Requests are request messages for client streaming and bidi streaming RPCs.
+	Requests []interface{} `json:"requests"`
+}
+
+// Messages returns request messages of s. If neither of Request and Requests is specified,
+// Messages returns an empty message.
+func (s *Step) Messages() []interface{} {
+	if s.Requests != nil {
+		return s.Requests
+	}
+	if s.Request != nil {
+		return []interface{}{s.Request}
+	}
+	return []interface{}{map[string]interface{}{}}
+}
+
+// Result represents the result of an executed step.
+type Result struct {
+	Header  map[string][]string
+	Trailer map[string][]string
+	// Responses are response messages in the JSON mapping of Protocol Buffers.
+	Responses []interface{}
+	// Code and Message are the status of the RPC.
+	Code    string
+	Message string
+}
+
+// Results holds results of executed steps by step names.
+type Results map[string]*Result
+
+// templateData returns the data which is passed to templates.
+// Each result is accessible as .steps.<name>, and has these keys:
+//
+//   - response: the last response message.
+//   - responses: all response messages.
+//   - header, trailer: metadata. Multiple values are joined with ", ".
+//   - status: the status that has "code" and "message".
+func (r Results) templateData() map[string]interface{} {
+	steps := make(map[string]interface{}, len(r))
+	for name, res := range r {
+		var last interface{}
+		if len(res.Responses) > 0 {
+			last = res.Responses[len(res.Responses)-1]
+		}
+		steps[name] = map[string]interface{}{
+			"response":  last,
+			"responses": res.Responses,
+			"header":    joinMetadata(res.Header),
+			"trailer":   joinMetadata(res.Trailer),
+			"status": map[string]interface{}{
+				"code":    res.Code,
+				"message": res.Message,
+			},
+		}
+	}
+	return map[string]interface{}{"steps": steps}
+}
+
+func joinMetadata(md map[string][]string) map[string]interface{} {
+	m := make(map[string]interface{}, len(md))
+	for k, v := range md {
+		m[k] = strings.Join(v, ", ")
+	}
+	return m
+}
+
+// Render returns a copy of s which has header values and string values of request messages rendered
+// as Go templates with results. Referring to an unknown key is an error.
+//
+// If a string value of a request message consists of only one action such as "{{ .steps.a.response.ok }}",
+// it is replaced with the value of the action as it is, so booleans, numbers and messages keep their types.
+// Otherwise, the rendered value is a string.
+func (s *Step) Render(results Results) (*Step, error) {
+	data := results.templateData()
+	render := func(text string) (string, error) {
+		if !strings.Contains(text, "{{") {
+			return text, nil
+		}
+		tmpl, err := template.New(s.Name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse the template '%s'", text)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", errors.Wrapf(err, "failed to render the template '%s'", text)
+		}
+		return buf.String(), nil
+	}
+	renderTyped := func(text string) (interface{}, error) {
+		pipeline, ok := singleAction(text)
+		if !ok {
+			return render(text)
+		}
+		var v interface{}
+		capture := func(e interface{}) string {
+			v = e
+			return ""
+		}
+		tmpl, err := template.New(s.Name).
+			Option("missingkey=error").
+			Funcs(template.FuncMap{"capture": capture}).
+			Parse("{{ capture (" + pipeline + ") }}")
+		if err != nil {
+			// The action is not a pipeline, such as a variable declaration.
+			return render(text)
+		}
+		if err := tmpl.Execute(io.Discard, data); err != nil {
+			return nil, errors.Wrapf(err, "failed to render the template '%s'", text)
+		}
+		return v, nil
+	}
+
+	rendered := &Step{Name: s.Name, Method: s.Method}
+	if s.Header != nil {
+		rendered.Header = make(map[string]string, len(s.Header))
+		for k, v := range s.Header {
+			rv, err := render(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "header '%s'", k)
+			}
+			rendered.Header[k] = rv
+		}
+	}
+	if s.Request != nil {
+		req, err := renderValue(s.Request, renderTyped)
+		if err != nil {
+			return nil, errors.Wrap(err, "request")
+		}
+		rendered.Request = req
+	}
+	if s.Requests != nil {
+		rendered.Requests = make([]interface{}, 0, len(s.Requests))
+		for i, r := range s.Requests {
+			req, err := renderValue(r, renderTyped)
+			if err != nil {
+				return nil, errors.Wrapf(err, "requests[%d]", i)
+			}
+			rendered.Requests = append(rendered.Requests, req)
+		}
+	}
+	return rendered, nil
+}
+
+// renderValue renders all strings in v by render recursively.
+func renderValue(v interface{}, render func(string) (interface{}, error)) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return render(v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			r, err := renderValue(e, render)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = r
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			r, err := renderValue(e, render)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, r)
+		}
+		return s, nil
+	default:
+		return v, nil
+	}
+}
+
+// singleAction returns the pipeline of text if text consists of only one action.
+func singleAction(text string) (string, bool) {
+	if !strings.HasPrefix(text, "{{") || !strings.HasSuffix(text, "}}") || strings.Count(text, "{{") != 1 {
+		return "", false
+	}
+	// Trim markers are "{{- " and " -}}".
+	pipeline := strings.TrimSuffix(strings.TrimPrefix(text[2:len(text)-2], "- "), " -")
+	if strings.TrimSpace(pipeline) == "" {
+		return "", false
+	}
+	return pipeline, true
+}
+
+// LoadFile loads the script file. If the extension of path is ".toml", the file is decoded as TOML.
+// Otherwise, it is decoded as YAML. Note that YAML is a superset of JSON.
+func LoadFile(path string) (*Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the script file")
+	}
+	defer f.Close()
+
+	format := "yaml"
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		format = "toml"
+	}
+	return Load(f, format)
+}
+
+// Load decodes a script from r as format, and validates it. format is one of "yaml" or "toml".
+func Load(r io.Reader, format string) (*Script, error) {
+	var s Script
+	if err := Decode(r, format, &s); err != nil {
+		return nil, errors.Wrap(err, "invalid script")
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Decode decodes r as format into v, which is decoded by encoding/json. format is one of "yaml" or "toml".
+// The decoded value is converted via JSON to handle both of formats in the same way, and numbers in
+// interface{} values of v are decoded as json.Number.
+func Decode(r io.Reader, format string, v interface{}) error {
+	var in interface{}
+	switch format {
+	case "yaml":
+		if err := yaml.NewDecoder(r).Decode(&in); err != nil && err != io.EOF {
+			return errors.Wrap(err, "failed to decode as YAML")
+		}
+	case "toml":
+		tree, err := toml.LoadReader(r)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode as TOML")
+		}
+		in = tree.ToMap()
+	default:
+		return errors.Errorf("unknown format '%s'", format)
+	}
+
+	b, err := json.Marshal(fill.ToJSONCompatible(in))
+	if err != nil {
+		return errors.Wrap(err, "failed to convert to JSON")
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func (s *Script) validate() error {
+	if len(s.Steps) == 0 {
+		return errors.New("script must have at least one step")
+	}
+	names := make(map[string]struct{}, len(s.Steps))
+	for i, step := range s.Steps {
+		if step == nil {
+			return errors.Errorf("steps[%d] is empty", i)
+		}
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step%d", i+1)
+		}
+		if _, found := names[step.Name]; found {
+			return errors.Errorf("duplicated step name '%s'", step.Name)
+		}
+		names[step.Name] = struct{}{}
+		if step.Method == "" {
+			return errors.Errorf("step '%s': method is required", step.Name)
+		}
+		if step.Request != nil && step.Requests != nil {
+			return errors.Errorf("step '%s': cannot specify both of request and requests", step.Name)
+		}
+	}
+	return nil
+}
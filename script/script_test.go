@@ -0,0 +1,191 @@
+package script_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/script"
+)
+
+func TestLoad(t *testing.T) {
+	yamlScript := `
+steps:
+  - name: login
+    method: api.Auth.Login
+    request:
+      user: ktr
+      age: 10000000000
+  - method: api.Example.ClientStreaming
+    header:
+      authorization: "Bearer {{ .steps.login.response.token }}"
+    requests:
+      - name: a
+      - name: b
+`
+	tomlScript := `
+[[steps]]
+name = "login"
+method = "api.Auth.Login"
+[steps.request]
+user = "ktr"
+age = 10000000000
+
+[[steps]]
+method = "api.Example.ClientStreaming"
+requests = [{ name = "a" }, { name = "b" }]
+[steps.header]
+authorization = "Bearer {{ .steps.login.response.token }}"
+`
+	expected := &script.Script{
+		Steps: []*script.Step{
+			{
+				Name:    "login",
+				Method:  "api.Auth.Login",
+				Request: map[string]interface{}{"user": "ktr", "age": json.Number("10000000000")},
+			},
+			{
+				Name:     "step2",
+				Method:   "api.Example.ClientStreaming",
+				Header:   map[string]string{"authorization": "Bearer {{ .steps.login.response.token }}"},
+				Requests: []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		in     string
+		format string
+	}{
+		"yaml": {in: yamlScript, format: "yaml"},
+		"toml": {in: tomlScript, format: "toml"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			s, err := script.Load(strings.NewReader(c.in), c.format)
+			if err != nil {
+				t.Fatalf("Load must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(expected, s); diff != "" {
+				t.Errorf("unexpected script (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoad_error(t *testing.T) {
+	cases := map[string]struct {
+		in     string
+		format string
+	}{
+		"no steps":             {in: "steps: []", format: "yaml"},
+		"empty":                {in: "", format: "yaml"},
+		"no method":            {in: "steps: [{name: a}]", format: "yaml"},
+		"duplicated names":     {in: "steps: [{name: a, method: m}, {name: a, method: m}]", format: "yaml"},
+		"request and requests": {in: "steps: [{method: m, request: {}, requests: [{}]}]", format: "yaml"},
+		"unknown format":       {in: "steps: [{method: m}]", format: "json"},
+		"invalid toml":         {in: "[[steps]", format: "toml"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if _, err := script.Load(strings.NewReader(c.in), c.format); err == nil {
+				t.Errorf("Load must return an error")
+			}
+		})
+	}
+}
+
+func TestStep_Render(t *testing.T) {
+	results := script.Results{
+		"login": {
+			Header:  map[string][]string{"x-id": {"a", "b"}},
+			Trailer: map[string][]string{"x-token": {"secret"}},
+			Responses: []interface{}{
+				map[string]interface{}{"token": "first"},
+				map[string]interface{}{
+					"token": "last",
+					"ok":    true,
+					"count": json.Number("3"),
+					"user":  map[string]interface{}{"name": "kumiko"},
+				},
+			},
+			Code: "OK",
+		},
+	}
+	step := &script.Step{
+		Name:   "next",
+		Method: "api.Example.Unary",
+		Header: map[string]string{"authorization": `Bearer {{ index .steps.login.trailer "x-token" }}`},
+		Request: map[string]interface{}{
+			"token":  "{{ .steps.login.response.token }}",
+			"first":  "{{ (index .steps.login.responses 0).token }}",
+			"ids":    []interface{}{`{{ index .steps.login.header "x-id" }}`, json.Number("1")},
+			"status": "{{ .steps.login.status.code }}",
+			"ok":     "{{ .steps.login.response.ok }}",
+			"count":  "{{- .steps.login.response.count -}}",
+			"user":   "{{ .steps.login.response.user }}",
+			"label":  "{{ .steps.login.response.ok }}/{{ .steps.login.response.count }}",
+			"name":   `{{ .steps.login.response.user.name | printf "%s!" }}`,
+		},
+	}
+
+	rendered, err := step.Render(results)
+	if err != nil {
+		t.Fatalf("Render must not return an error, but got '%s'", err)
+	}
+	expected := &script.Step{
+		Name:   "next",
+		Method: "api.Example.Unary",
+		Header: map[string]string{"authorization": "Bearer secret"},
+		Request: map[string]interface{}{
+			"token":  "last",
+			"first":  "first",
+			"ids":    []interface{}{"a, b", json.Number("1")},
+			"status": "OK",
+			"ok":     true,
+			"count":  json.Number("3"),
+			"user":   map[string]interface{}{"name": "kumiko"},
+			"label":  "true/3",
+			"name":   "kumiko!",
+		},
+	}
+	if diff := cmp.Diff(expected, rendered); diff != "" {
+		t.Errorf("unexpected step (-want, +got):\n%s", diff)
+	}
+	if step.Request.(map[string]interface{})["token"] != "{{ .steps.login.response.token }}" {
+		t.Errorf("Render must not modify the receiver")
+	}
+
+	for name, tmpl := range map[string]string{
+		"unknown step":  "{{ .steps.unknown.response.token }}",
+		"unknown field": "{{ .steps.login.response.unknown }}",
+		"invalid":       "{{ .steps.login",
+	} {
+		step := &script.Step{Name: "next", Method: "m", Request: map[string]interface{}{"v": tmpl}}
+		if _, err := step.Render(results); err == nil {
+			t.Errorf("%s: Render must return an error", name)
+		}
+	}
+}
+
+func TestStep_Messages(t *testing.T) {
+	cases := map[string]struct {
+		step     *script.Step
+		expected []interface{}
+	}{
+		"empty":    {step: &script.Step{}, expected: []interface{}{map[string]interface{}{}}},
+		"request":  {step: &script.Step{Request: "a"}, expected: []interface{}{"a"}},
+		"requests": {step: &script.Step{Requests: []interface{}{"a", "b"}}, expected: []interface{}{"a", "b"}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(c.expected, c.step.Messages()); diff != "" {
+				t.Errorf("unexpected messages (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
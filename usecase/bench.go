@@ -0,0 +1,331 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// benchHistogramBuckets is the number of buckets of BenchResult.Histogram.
+const benchHistogramBuckets = 10
+
+// minBenchRate and maxBenchRate are the range of BenchOption.Rate. The interval between requests must be
+// representable by time.Duration, that is, from 1ns to about 292 years.
+const (
+	minBenchRate = float64(time.Second) / math.MaxInt64
+	maxBenchRate = float64(time.Second)
+)
+
+// BenchOption represents options for Bench.
+type BenchOption struct {
+	// Requests is the total number of requests. It is ignored if Duration is specified.
+	Requests int
+	// Duration is the duration to keep sending requests. Requests which are in-flight when it elapses are canceled
+	// and are not counted.
+	Duration time.Duration
+	// Concurrency is the number of workers that send requests concurrently.
+	Concurrency int
+	// Rate is the maximum number of requests per second in total. Zero means unlimited.
+	// Otherwise, it must be between about 1.1e-10 (minBenchRate) and 1e9 (maxBenchRate).
+	Rate float64
+	// Timeout is the deadline of each request. Zero means no deadline.
+	Timeout time.Duration
+}
+
+// BenchResult represents the result of Bench. All durations are encoded to JSON in nanoseconds.
+type BenchResult struct {
+	Method string `json:"method"`
+	// ServerStreaming reports whether the method is a server streaming RPC.
+	// If true, Latency and Histogram represent the time to the first message.
+	ServerStreaming   bool                   `json:"serverStreaming"`
+	Requests          int                    `json:"requests"`
+	Elapsed           time.Duration          `json:"elapsed"`
+	RequestsPerSecond float64                `json:"requestsPerSecond"`
+	Latency           BenchLatency           `json:"latency"`
+	Histogram         []BenchHistogramBucket `json:"histogram"`
+	// StatusCodes is the number of requests for each status code name.
+	StatusCodes map[string]int `json:"statusCodes"`
+	// Messages and MessagesPerSecond are set only for server streaming RPCs.
+	Messages          int     `json:"messages,omitempty"`
+	MessagesPerSecond float64 `json:"messagesPerSecond,omitempty"`
+}
+
+// BenchLatency represents latency statistics.
+type BenchLatency struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// BenchHistogramBucket represents the number of requests whose latency is less than or equal to Upper
+// and greater than the previous bucket.
+type BenchHistogramBucket struct {
+	Upper time.Duration `json:"upper"`
+	Count int           `json:"count"`
+}
+
+// benchSample is the result of a request.
+type benchSample struct {
+	latency  time.Duration
+	code     codes.Code
+	messages int
+}
+
+// Bench sends the request which is read by the filler to the RPC repeatedly and returns its statistics.
+// Only unary and server streaming RPCs are supported.
+func Bench(ctx context.Context, rpcName string, opt BenchOption) (*BenchResult, error) {
+	return dm.Bench(ctx, rpcName, opt)
+}
+func (m *dependencyManager) Bench(ctx context.Context, rpcName string, opt BenchOption) (*BenchResult, error) {
+	fqsn := pb.FullyQualifiedServiceName(m.state.selectedPackage, m.state.selectedService)
+	d, err := m.descSource.FindSymbol(fmt.Sprintf("%s.%s", fqsn, rpcName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the RPC descriptor for: %s", rpcName)
+	}
+	rpc, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a method", rpcName)
+	}
+	if rpc.IsStreamingClient() {
+		return nil, errors.New("client streaming and bidi streaming RPCs are not supported")
+	}
+	if opt.Concurrency < 1 {
+		return nil, errors.New("concurrency must be greater than 0")
+	}
+	if opt.Duration <= 0 && opt.Requests < 1 {
+		return nil, errors.New("the number of requests must be greater than 0")
+	}
+	if opt.Rate != 0 && (opt.Rate < minBenchRate || opt.Rate > maxBenchRate) {
+		return nil, errors.Errorf("rate must be zero (unlimited) or between %g and %g", minBenchRate, maxBenchRate)
+	}
+
+	// All requests have the same content. An empty input means an empty message.
+	req := dynamicpb.NewMessage(rpc.Input())
+	if err := m.filler.Fill(req); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrap(err, "failed to fill the request")
+	}
+
+	// Each request has its own context which has the headers, and whose deadline is grpc-timeout or opt.Timeout.
+	// The headers are validated here before sending requests.
+	_, cancel, err := m.enhanceContext(ctx)
+	cancel()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to enhance context with metadata")
+	}
+	call := func(ctx context.Context) benchSample {
+		ctx, cancel, err := m.enhanceContext(WithTimeout(ctx, opt.Timeout))
+		if err != nil {
+			return benchSample{code: codes.Unknown}
+		}
+		defer cancel()
+		if rpc.IsStreamingServer() {
+			return m.benchServerStreaming(ctx, rpc, req)
+		}
+		return m.benchUnary(ctx, rpc, req)
+	}
+
+	// next reports whether a worker can send one more request.
+	var next func() bool
+	if opt.Duration > 0 {
+		// Requests are derived from ctx, so that in-flight ones are canceled when the duration elapses.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opt.Duration)
+		defer cancel()
+		next = func() bool { return ctx.Err() == nil }
+	} else {
+		var sent int64
+		next = func() bool { return atomic.AddInt64(&sent, 1) <= int64(opt.Requests) }
+	}
+
+	var tick <-chan time.Time
+	if opt.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opt.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var (
+		mu      sync.Mutex
+		samples []benchSample
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for i := 0; i < opt.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next() {
+				if tick != nil {
+					select {
+					case <-tick:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				s := call(ctx)
+				if ctx.Err() != nil && (s.code == codes.Canceled || s.code == codes.DeadlineExceeded) {
+					// The request is interrupted by the end of the duration or the cancellation.
+					return
+				}
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return newBenchResult(string(rpc.FullName()), rpc.IsStreamingServer(), samples, time.Since(start)), nil
+}
+
+func (m *dependencyManager) benchUnary(ctx context.Context, rpc protoreflect.MethodDescriptor, req *dynamicpb.Message) benchSample {
+	res := dynamicpb.NewMessage(rpc.Output())
+	start := time.Now()
+	_, _, err := m.gRPCClient.Invoke(ctx, string(rpc.FullName()), req, res)
+	return benchSample{latency: time.Since(start), code: benchStatusCode(err)}
+}
+
+// benchServerStreaming sends req and receives all responses. The latency of the returned sample is
+// the time to the first message.
+func (m *dependencyManager) benchServerStreaming(ctx context.Context, rpc protoreflect.MethodDescriptor, req *dynamicpb.Message) benchSample {
+	streamDesc := &gogrpc.StreamDesc{
+		StreamName:    string(rpc.Name()),
+		ServerStreams: true,
+	}
+
+	start := time.Now()
+	var s benchSample
+	finish := func(err error) benchSample {
+		if s.messages == 0 {
+			s.latency = time.Since(start)
+		}
+		s.code = benchStatusCode(err)
+		return s
+	}
+
+	stream, err := m.gRPCClient.NewServerStream(ctx, streamDesc, string(rpc.FullName()))
+	if err != nil {
+		return finish(err)
+	}
+	if err := stream.Send(req); err != nil {
+		return finish(err)
+	}
+	for {
+		err := stream.Receive(dynamicpb.NewMessage(rpc.Output()))
+		if errors.Is(err, io.EOF) {
+			return finish(nil)
+		}
+		if err != nil {
+			return finish(err)
+		}
+		if s.messages == 0 {
+			s.latency = time.Since(start)
+		}
+		s.messages++
+	}
+}
+
+// benchStatusCode returns the status code of err. Errors that aren't gRPC errors are regarded as Unknown.
+func benchStatusCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	stat, err := handleGRPCResponseError(err)
+	if err != nil {
+		return codes.Unknown
+	}
+	return stat.Code()
+}
+
+func newBenchResult(method string, serverStreaming bool, samples []benchSample, elapsed time.Duration) *BenchResult {
+	r := &BenchResult{
+		Method:          method,
+		ServerStreaming: serverStreaming,
+		Requests:        len(samples),
+		Elapsed:         elapsed,
+		StatusCodes:     make(map[string]int),
+	}
+	if len(samples) == 0 {
+		return r
+	}
+
+	latencies := make([]time.Duration, 0, len(samples))
+	var total time.Duration
+	for _, s := range samples {
+		latencies = append(latencies, s.latency)
+		total += s.latency
+		r.StatusCodes[s.code.String()]++
+		r.Messages += s.messages
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	if elapsed > 0 {
+		r.RequestsPerSecond = float64(len(samples)) / elapsed.Seconds()
+		if serverStreaming {
+			r.MessagesPerSecond = float64(r.Messages) / elapsed.Seconds()
+		}
+	}
+
+	percentile := func(p float64) time.Duration {
+		i := int(math.Ceil(p/100*float64(len(latencies)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return latencies[i]
+	}
+	r.Latency = BenchLatency{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(50),
+		P90:  percentile(90),
+		P95:  percentile(95),
+		P99:  percentile(99),
+		Max:  latencies[len(latencies)-1],
+	}
+	r.Histogram = newBenchHistogram(latencies)
+
+	return r
+}
+
+// newBenchHistogram divides the range of sorted latencies into buckets with the same width.
+func newBenchHistogram(latencies []time.Duration) []BenchHistogramBucket {
+	lo, hi := latencies[0], latencies[len(latencies)-1]
+	if lo == hi {
+		return []BenchHistogramBucket{{Upper: hi, Count: len(latencies)}}
+	}
+
+	width := float64(hi-lo) / benchHistogramBuckets
+	buckets := make([]BenchHistogramBucket, benchHistogramBuckets)
+	for i := range buckets {
+		buckets[i].Upper = lo + time.Duration(width*float64(i+1))
+	}
+	buckets[len(buckets)-1].Upper = hi
+
+	var i int
+	for _, l := range latencies {
+		for l > buckets[i].Upper {
+			i++
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc"
+	pb "github.com/ktr0731/evans/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestNewBenchResult(t *testing.T) {
+	var samples []benchSample
+	for i := 1; i <= 100; i++ {
+		code := codes.OK
+		if i%10 == 0 {
+			code = codes.Unavailable
+		}
+		samples = append(samples, benchSample{latency: time.Duration(i) * time.Millisecond, code: code, messages: 2})
+	}
+
+	r := newBenchResult("api.Example.ServerStreaming", true, samples, 2*time.Second)
+
+	if r.Requests != 100 {
+		t.Errorf("expected 100 requests, but got %d", r.Requests)
+	}
+	if r.RequestsPerSecond != 50 {
+		t.Errorf("expected 50 requests/sec, but got %f", r.RequestsPerSecond)
+	}
+	if r.Messages != 200 || r.MessagesPerSecond != 100 {
+		t.Errorf("expected 200 messages and 100 messages/sec, but got %d and %f", r.Messages, r.MessagesPerSecond)
+	}
+
+	expectedLatency := BenchLatency{
+		Min:  1 * time.Millisecond,
+		Mean: 50500 * time.Microsecond,
+		P50:  50 * time.Millisecond,
+		P90:  90 * time.Millisecond,
+		P95:  95 * time.Millisecond,
+		P99:  99 * time.Millisecond,
+		Max:  100 * time.Millisecond,
+	}
+	if diff := cmp.Diff(expectedLatency, r.Latency); diff != "" {
+		t.Errorf("unexpected latency (-want, +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(map[string]int{"OK": 90, "Unavailable": 10}, r.StatusCodes); diff != "" {
+		t.Errorf("unexpected status codes (-want, +got):\n%s", diff)
+	}
+
+	if n := len(r.Histogram); n != benchHistogramBuckets {
+		t.Fatalf("expected %d buckets, but got %d", benchHistogramBuckets, n)
+	}
+	var total int
+	for _, b := range r.Histogram {
+		total += b.Count
+	}
+	if total != 100 {
+		t.Errorf("the sum of bucket counts must be 100, but got %d", total)
+	}
+	if last := r.Histogram[len(r.Histogram)-1]; last.Upper != 100*time.Millisecond {
+		t.Errorf("the upper of the last bucket must be the max latency, but got %s", last.Upper)
+	}
+}
+
+func TestNewBenchResult_sameLatencies(t *testing.T) {
+	samples := []benchSample{{latency: time.Millisecond}, {latency: time.Millisecond}}
+
+	r := newBenchResult("api.Example.Unary", false, samples, time.Second)
+
+	expected := []BenchHistogramBucket{{Upper: time.Millisecond, Count: 2}}
+	if diff := cmp.Diff(expected, r.Histogram); diff != "" {
+		t.Errorf("unexpected histogram (-want, +got):\n%s", diff)
+	}
+	if r.Messages != 0 || r.MessagesPerSecond != 0 {
+		t.Errorf("messages must not be counted for unary RPCs")
+	}
+}
+
+// benchClient responds OK only if the request has the header and the deadline. If block is true,
+// it blocks until the request is canceled.
+type benchClient struct {
+	grpc.Client
+
+	block bool
+}
+
+func (c *benchClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (header, trailer metadata.MD, _ error) {
+	if c.block {
+		<-ctx.Done()
+		return nil, nil, status.FromContextError(ctx.Err()).Err()
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if diff := cmp.Diff([]string{"v"}, md.Get("k")); diff != "" {
+		return nil, nil, status.Error(codes.InvalidArgument, diff)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, nil, status.Error(codes.InvalidArgument, "no deadline")
+	}
+	return nil, nil, nil
+}
+
+func (c *benchClient) Header() grpc.Headers {
+	return grpc.Headers{"k": {"v"}, "grpc-timeout": {"10S"}}
+}
+
+func TestBench(t *testing.T) {
+	svc := compileTestService(t)
+	newDependencyManager := func(client grpc.Client) *dependencyManager {
+		return &dependencyManager{
+			descSource: &pb.DescriptorSourceMock{
+				FindSymbolFunc: func(name string) (protoreflect.Descriptor, error) {
+					return svc.Methods().ByName("Unary"), nil
+				},
+			},
+			filler:     &slowFiller{},
+			gRPCClient: client,
+			state:      state{selectedPackage: "api", selectedService: "Example"},
+		}
+	}
+
+	t.Run("requests", func(t *testing.T) {
+		r, err := newDependencyManager(&benchClient{}).Bench(context.Background(), "Unary", BenchOption{Requests: 5, Concurrency: 2})
+		if err != nil {
+			t.Fatalf("Bench must not return an error, but got '%s'", err)
+		}
+		if diff := cmp.Diff(map[string]int{"OK": 5}, r.StatusCodes); diff != "" {
+			t.Errorf("each request must have the header and the deadline (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		start := time.Now()
+		r, err := newDependencyManager(&benchClient{block: true}).Bench(context.Background(), "Unary", BenchOption{Duration: 50 * time.Millisecond, Concurrency: 2})
+		if err != nil {
+			t.Fatalf("Bench must not return an error, but got '%s'", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("in-flight requests must be canceled when the duration elapses, but it took %s", elapsed)
+		}
+		if r.Requests != 0 {
+			t.Errorf("canceled requests must not be counted, but got %d", r.Requests)
+		}
+	})
+
+	for _, rate := range []float64{-1, 1e-20, 2e9} {
+		_, err := newDependencyManager(&benchClient{}).Bench(context.Background(), "Unary", BenchOption{Requests: 1, Concurrency: 1, Rate: rate})
+		if err == nil {
+			t.Errorf("Bench must return an error for the rate %g", rate)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktr0731/evans/fill"
+	formatjson "github.com/ktr0731/evans/format/json"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/present/json"
+	"github.com/ktr0731/evans/prompt"
+	pb "github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const bidiSessionHelp = `commands:
+  send [JSON]  send a request. if JSON is omitted, the fields are inputted interactively
+  close        close the send direction and wait for the rest of responses (ctrl+d)
+  cancel       cancel the RPC (ctrl+c)
+  help         show this message`
+
+// CallBidiStreamingRPCInSession calls a bidi streaming RPC in the session view. The session reads commands from p
+// to send a request, close the send direction or cancel the RPC. Sent requests and received responses are written to
+// w above the input line with timestamps.
+func CallBidiStreamingRPCInSession(ctx context.Context, w io.Writer, rpcName string, p prompt.Prompt, opts fill.InteractiveFillerOpts) error {
+	return dm.CallBidiStreamingRPCInSession(ctx, w, rpcName, p, opts)
+}
+
+func (m *dependencyManager) CallBidiStreamingRPCInSession(ctx context.Context, w io.Writer, rpcName string, p prompt.Prompt, opts fill.InteractiveFillerOpts) error {
+	fqsn := pb.FullyQualifiedServiceName(m.state.selectedPackage, m.state.selectedService)
+	d, err := m.descSource.FindSymbol(fmt.Sprintf("%s.%s", fqsn, rpcName))
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the RPC descriptor for: %s", rpcName)
+	}
+	rpc, ok := d.(protoreflect.MethodDescriptor)
+	if !ok || !rpc.IsStreamingClient() || !rpc.IsStreamingServer() {
+		return errors.Errorf("'%s' is not a bidi streaming RPC", rpcName)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, startTimeout, stopTimeout := deferTimeout(ctx)
+	defer stopTimeout()
+	ctx, cancelTimeout, err := m.enhanceContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to enhance context with metadata")
+	}
+	defer cancelTimeout()
+
+	streamDesc := &gogrpc.StreamDesc{
+		StreamName:    string(rpc.Name()),
+		ServerStreams: true,
+		ClientStreams: true,
+	}
+	stream, err := m.gRPCClient.NewBidiStream(ctx, streamDesc, string(rpc.FullName()))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create a bidi stream for RPC '%s'", streamDesc.StreamName)
+	}
+
+	s := &bidiSession{
+		rpc:    rpc,
+		stream: stream,
+		prompt: p,
+		fill: func(req *dynamicpb.Message) error {
+			return m.interactiveFiller.Fill(req, opts)
+		},
+		resolver:     pb.NewTypeResolver(m.descSource),
+		w:            &sessionWriter{w: w},
+		now:          time.Now,
+		startTimeout: startTimeout,
+	}
+	return s.run(ctx, cancel)
+}
+
+// bidiSession is an interactive view of a bidi streaming RPC.
+type bidiSession struct {
+	rpc      protoreflect.MethodDescriptor
+	stream   grpc.BidiStream
+	prompt   prompt.Prompt
+	fill     func(req *dynamicpb.Message) error
+	resolver pb.TypeResolver
+	w        *sessionWriter
+	now      func() time.Time
+	// startTimeout starts the timeout of the RPC after the send direction is closed. It may be nil.
+	startTimeout func()
+}
+
+func (s *bidiSession) run(ctx context.Context, cancel context.CancelFunc) error {
+	prefix := fmt.Sprintf("%s> ", s.rpc.Name())
+	s.prompt.SetPrefix(prefix)
+	s.prompt.SetCompleter(&bidiSessionCompleter{})
+	s.w.println(fmt.Sprintf("bidi streaming session of %s. type 'help' to show commands", s.rpc.FullName()))
+
+	var (
+		stat    *status.Status
+		recvErr error
+		done    = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		stat, recvErr = s.receive(ctx)
+	}()
+
+	var canceled bool
+loop:
+	for {
+		s.w.setPrompt(true, prefix)
+		in, err := s.prompt.Input()
+		s.w.setPrompt(false, "")
+
+		select {
+		case <-done:
+			break loop
+		default:
+		}
+
+		cmd, arg := splitCommand(in)
+		switch {
+		case errors.Is(err, io.EOF):
+			cmd = "close"
+		case errors.Is(err, prompt.ErrAbort):
+			cmd = "cancel"
+		case err != nil:
+			cancel()
+			<-done
+			return errors.Wrap(err, "failed to read a command")
+		}
+
+		switch cmd {
+		case "":
+		case "send":
+			if err := s.send(arg); errors.Is(err, io.EOF) {
+				// The server has finished the RPC. The status is received by receive.
+				break loop
+			} else if err != nil {
+				s.w.println(err.Error())
+			}
+		case "close":
+			if err := s.stream.CloseSend(); err != nil {
+				s.w.println(fmt.Sprintf("failed to close the send direction: %s", err))
+				continue
+			}
+			s.w.println(s.event("closed the send direction"))
+			if s.startTimeout != nil {
+				s.startTimeout()
+			}
+			break loop
+		case "cancel":
+			canceled = true
+			cancel()
+			break loop
+		case "help":
+			s.w.println(bidiSessionHelp)
+		default:
+			s.w.println(fmt.Sprintf("unknown command '%s'. type 'help' to show commands", cmd))
+		}
+	}
+
+	<-done
+	if recvErr != nil {
+		return recvErr
+	}
+	// The status CANCELLED is already written.
+	if canceled || (errors.Is(ctx.Err(), context.Canceled) && stat.Code() == codes.Canceled) {
+		return nil
+	}
+	if stat.Code() != codes.OK {
+		return &gRPCError{stat}
+	}
+	return nil
+}
+
+// send sends a request. If in is empty, the fields of the request are inputted interactively.
+func (s *bidiSession) send(in string) error {
+	req := dynamicpb.NewMessage(s.rpc.Input())
+	if in == "" {
+		// Responses received while inputting are written above the input line without the prefix.
+		s.w.setPrompt(true, "")
+		err := s.fill(req)
+		s.w.setPrompt(false, "")
+		if errors.Is(err, io.EOF) || errors.Is(err, prompt.ErrAbort) {
+			return errors.New("inputting canceled")
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to input the request")
+		}
+	} else if err := (protojson.UnmarshalOptions{Resolver: s.resolver}).Unmarshal([]byte(in), req); err != nil {
+		return errors.Wrap(err, "invalid request")
+	}
+
+	if err := s.stream.Send(req); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return errors.Wrapf(err, "failed to send a request")
+	}
+	s.w.println(s.message("sent", req))
+	return nil
+}
+
+// receive receives responses and writes them until the RPC finishes. It returns the status of the RPC.
+func (s *bidiSession) receive(ctx context.Context) (*status.Status, error) {
+	var headerWritten bool
+	for {
+		res := dynamicpb.NewMessage(s.rpc.Output())
+		err := s.stream.Receive(res)
+		if !headerWritten {
+			headerWritten = true
+			if header, err := s.stream.Header(); err == nil && len(header) != 0 {
+				s.w.println(s.event("header\n" + formatMetadata(header)))
+			}
+		}
+
+		var stat *status.Status
+		if errors.Is(err, io.EOF) {
+			stat = status.New(codes.OK, "")
+		} else if err != nil {
+			stat, err = handleGRPCResponseError(err)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to receive a response from the bidi stream '%s'", s.rpc.Name())
+			}
+		}
+		if stat != nil {
+			stat = deadlineStatus(ctx, stat)
+			if trailer := s.stream.Trailer(); len(trailer) != 0 {
+				s.w.println(s.event("trailer\n" + formatMetadata(trailer)))
+			}
+			msg := fmt.Sprintf("finished with code = %s", stat.Code())
+			if stat.Message() != "" {
+				msg += fmt.Sprintf(", message = %q", stat.Message())
+			}
+			s.w.finish(s.event(msg))
+			return stat, nil
+		}
+
+		s.w.println(s.message("received", res))
+	}
+}
+
+func (s *bidiSession) event(msg string) string {
+	return fmt.Sprintf("[%s] %s", s.now().Format("15:04:05.000"), msg)
+}
+
+func (s *bidiSession) message(label string, m proto.Message) string {
+	v, err := formatjson.ConvertMessage(m, false, s.resolver)
+	if err != nil {
+		return s.event(fmt.Sprintf("%s (failed to format the message: %s)", label, err))
+	}
+	body, err := json.NewPresenter("  ").Format(v)
+	if err != nil {
+		return s.event(fmt.Sprintf("%s (failed to format the message: %s)", label, err))
+	}
+	return s.event(label + "\n" + body)
+}
+
+// splitCommand splits in into the command name and its argument.
+func splitCommand(in string) (string, string) {
+	in = strings.TrimSpace(in)
+	i := strings.IndexFunc(in, func(r rune) bool { return r == ' ' || r == '\t' })
+	if i == -1 {
+		return in, ""
+	}
+	return in[:i], strings.TrimSpace(in[i+1:])
+}
+
+func formatMetadata(md metadata.MD) string {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("  %s: %s", k, strings.Join(md[k], ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sessionWriter writes lines above the input line of the session.
+type sessionWriter struct {
+	w io.Writer
+
+	mu        sync.Mutex
+	prompting bool
+	prefix    string
+}
+
+// setPrompt changes whether the input line is shown. prefix is the prefix of the input line.
+func (w *sessionWriter) setPrompt(prompting bool, prefix string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.prompting, w.prefix = prompting, prefix
+}
+
+func (w *sessionWriter) println(s string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.write(s)
+}
+
+// finish writes s as the last line of the session. If the input line is shown, it asks for pressing Enter
+// because the input line is still reading.
+func (w *sessionWriter) finish(s string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.prompting {
+		s += "\npress Enter to finish the session"
+	}
+	w.write(s)
+	w.prompting = false
+}
+
+func (w *sessionWriter) write(s string) {
+	if !w.prompting {
+		io.WriteString(w.w, s+"\n") //nolint:errcheck
+		return
+	}
+	// Clear the input line, write s and redraw the prefix. The input text is redrawn by the next key input.
+	io.WriteString(w.w, "\r\x1b[2K"+s+"\n"+w.prefix) //nolint:errcheck
+}
+
+type bidiSessionCompleter struct{}
+
+func (c *bidiSessionCompleter) Complete(d prompt.Document) []*prompt.Suggest {
+	if strings.Contains(d.TextBeforeCursor(), " ") {
+		return nil
+	}
+	s := []*prompt.Suggest{
+		prompt.NewSuggestion("send", "send a request"),
+		prompt.NewSuggestion("close", "close the send direction"),
+		prompt.NewSuggestion("cancel", "cancel the RPC"),
+		prompt.NewSuggestion("help", "show commands"),
+	}
+	return prompt.FilterHasPrefix(s, d.GetWordBeforeCursor(), true)
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/prompt"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// echoStream echoes requests after the send direction is closed and released is closed.
+type echoStream struct {
+	ctx      context.Context
+	released chan struct{}
+
+	mu     sync.Mutex
+	reqs   []proto.Message
+	closed chan struct{}
+}
+
+func (s *echoStream) Header() (metadata.MD, error) { return metadata.Pairs("k", "v"), nil }
+func (s *echoStream) Trailer() metadata.MD         { return nil }
+
+func (s *echoStream) Send(req interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reqs = append(s.reqs, proto.Clone(req.(proto.Message)))
+	return nil
+}
+
+func (s *echoStream) CloseSend() error {
+	close(s.closed)
+	return nil
+}
+
+func (s *echoStream) Receive(res interface{}) error {
+	select {
+	case <-s.ctx.Done():
+		return status.FromContextError(s.ctx.Err()).Err()
+	case <-s.closed:
+	}
+	<-s.released
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.reqs) == 0 {
+		return io.EOF
+	}
+	proto.Merge(res.(proto.Message), s.reqs[0])
+	s.reqs = s.reqs[1:]
+	return nil
+}
+
+type stubPrompt struct {
+	prompt.Prompt
+
+	inputs []interface{}
+}
+
+func (p *stubPrompt) Input() (string, error) {
+	in := p.inputs[0]
+	p.inputs = p.inputs[1:]
+	if err, ok := in.(error); ok {
+		return "", err
+	}
+	return in.(string), nil
+}
+
+func (p *stubPrompt) SetPrefix(string)              {}
+func (p *stubPrompt) SetCompleter(prompt.Completer) {}
+
+// notifyWriter closes notified when the written content contains s.
+type notifyWriter struct {
+	buf      bytes.Buffer
+	s        string
+	notified chan struct{}
+	once     sync.Once
+}
+
+func (w *notifyWriter) Write(b []byte) (int, error) {
+	n, err := w.buf.Write(b)
+	if strings.Contains(w.buf.String(), w.s) {
+		w.once.Do(func() { close(w.notified) })
+	}
+	return n, err
+}
+
+func (w *notifyWriter) String() string {
+	return w.buf.String()
+}
+
+func TestBidiSession(t *testing.T) {
+	rpc := compileTestService(t).Methods().ByName("Bidi")
+	now := func() time.Time { return time.Date(2026, 10, 17, 12, 34, 56, 789000000, time.UTC) }
+
+	cases := map[string]struct {
+		inputs   []interface{}
+		expected string
+	}{
+		"close": {
+			inputs: []interface{}{"", "help", `send {"name": "oumae"}`, "foo", "send", io.EOF},
+			expected: `bidi streaming session of api.Example.Bidi. type 'help' to show commands
+` + bidiSessionHelp + `
+[12:34:56.789] sent
+{
+  "name": "oumae"
+}
+unknown command 'foo'. type 'help' to show commands
+[12:34:56.789] sent
+{
+  "name": "kousaka"
+}
+[12:34:56.789] closed the send direction
+[12:34:56.789] header
+  k: v
+[12:34:56.789] received
+{
+  "name": "oumae"
+}
+[12:34:56.789] received
+{
+  "name": "kousaka"
+}
+[12:34:56.789] finished with code = OK
+`,
+		},
+		"cancel": {
+			inputs: []interface{}{`send {"name": "oumae"}`, prompt.ErrAbort},
+			expected: `bidi streaming session of api.Example.Bidi. type 'help' to show commands
+[12:34:56.789] sent
+{
+  "name": "oumae"
+}
+[12:34:56.789] header
+  k: v
+[12:34:56.789] finished with code = Canceled, message = "context canceled"
+`,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			w := &notifyWriter{s: "closed the send direction", notified: make(chan struct{})}
+			s := &bidiSession{
+				rpc: rpc,
+				stream: &echoStream{
+					ctx:      ctx,
+					released: w.notified,
+					closed:   make(chan struct{}),
+				},
+				prompt: &stubPrompt{inputs: c.inputs},
+				fill: func(req *dynamicpb.Message) error {
+					req.Set(req.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("kousaka"))
+					return nil
+				},
+				w:   &sessionWriter{w: w},
+				now: now,
+			}
+			if err := s.run(ctx, cancel); err != nil {
+				t.Fatalf("run must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, w.String()); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -111,38 +111,6 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 		return flushDone()
 	}
 
-	parseDuration := func(duration string) (time.Duration, error) {
-		replacer := strings.NewReplacer("n", "ns", "u", "us", "m", "ms", "S", "s", "M", "m", "H", "h")
-		duration = replacer.Replace(duration)
-		timeout, err := time.ParseDuration(duration)
-		if err != nil {
-			return 0, errors.Wrapf(err, "malformed grpc-timeout header")
-		}
-		return timeout, err
-	}
-
-	enhanceContext := func(ctx context.Context) (context.Context, context.CancelFunc, error) {
-		md := metadata.New(nil)
-		for k, v := range m.ListHeaders() {
-			md.Append(k, v...)
-		}
-
-		ctx = metadata.NewOutgoingContext(ctx, md)
-		values := md.Get("grpc-timeout")
-		if len(values) == 0 {
-			return ctx, func() {}, nil
-		}
-
-		timeout, err := parseDuration(values[len(values)-1])
-		if err != nil {
-			return nil, func() {}, err
-		}
-
-		ctx, cancel := context.WithTimeout(ctx, timeout)
-
-		return ctx, cancel, nil
-	}
-
 	streamDesc := &gogrpc.StreamDesc{
 		StreamName:    string(rpc.Name()),
 		ServerStreams: rpc.IsStreamingServer(),
@@ -151,7 +119,9 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 
 	switch {
 	case rpc.IsStreamingClient() && rpc.IsStreamingServer():
-		ctx, cancel, err := enhanceContext(ctx)
+		ctx, startTimeout, stopTimeout := deferTimeout(ctx)
+		defer stopTimeout()
+		ctx, cancel, err := m.enhanceContext(ctx)
 		if err != nil {
 			cancel()
 			return errors.Wrap(err, "failed to enhance context with metadata")
@@ -190,6 +160,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 				}
 
 				if stat != nil {
+					stat = deadlineStatus(ctx, stat)
 					defer func(stat *status.Status) {
 						writeTrailerOnce.Do(func() {
 							if err := flushTrailer(stat, stream.Trailer()); err != nil {
@@ -231,6 +202,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 					if err := stream.CloseSend(); err != nil {
 						return errors.Wrapf(err, "failed to close the stream of RPC '%s'", streamDesc.StreamName)
 					}
+					startTimeout()
 					return nil
 				}
 				if err != nil {
@@ -262,7 +234,9 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 	//   6. Format the response and output it.
 	//
 	case rpc.IsStreamingClient():
-		ctx, cancel, err := enhanceContext(ctx)
+		ctx, startTimeout, stopTimeout := deferTimeout(ctx)
+		defer stopTimeout()
+		ctx, cancel, err := m.enhanceContext(ctx)
 		if err != nil {
 			cancel()
 			return errors.Wrap(err, "failed to enhance context with metadata")
@@ -278,11 +252,13 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 			req, err := newRequest()
 
 			if errors.Is(err, io.EOF) {
+				startTimeout()
 				res := newResponse()
 				stat, err := handleGRPCResponseError(stream.CloseAndReceive(res))
 				if err != nil {
 					return errors.Wrapf(err, "failed to close the stream of RPC '%s'", streamDesc.StreamName)
 				}
+				stat = deadlineStatus(ctx, stat)
 
 				// gRPC error. Treat as a normal response.
 
@@ -328,7 +304,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 			return err
 		}
 
-		ctx, cancel, err := enhanceContext(ctx)
+		ctx, cancel, err := m.enhanceContext(ctx)
 		if err != nil {
 			cancel()
 			return errors.Wrap(err, "failed to enhance context with metadata")
@@ -406,7 +382,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 			return err
 		}
 
-		ctx, cancel, err := enhanceContext(ctx)
+		ctx, cancel, err := m.enhanceContext(ctx)
 		if err != nil {
 			cancel()
 			return errors.Wrap(err, "failed to enhance context with metadata")
@@ -436,6 +412,100 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 	}
 }
 
+// enhanceContext returns a context which has the headers as the outgoing metadata.
+// If the headers have grpc-timeout, the returned context also has the timeout.
+func (m *dependencyManager) enhanceContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	md := metadata.New(nil)
+	for k, v := range m.ListHeaders() {
+		md.Append(k, v...)
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	cancel := func() {}
+	if values := md.Get("grpc-timeout"); len(values) != 0 {
+		timeout, err := parseTimeout(values[len(values)-1])
+		if err != nil {
+			return nil, func() {}, err
+		}
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	// The timeout set by WithTimeout starts here, that is, after the request is inputted.
+	if d, _ := ctx.Value(timeoutKey{}).(time.Duration); d > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, d)
+		cancelHeaderTimeout := cancel
+		cancel = func() {
+			cancelTimeout()
+			cancelHeaderTimeout()
+		}
+	}
+
+	return ctx, cancel, nil
+}
+
+type timeoutKey struct{}
+
+// WithTimeout returns a context which makes the RPC called with it time out after d. Unlike context.WithTimeout,
+// the deadline starts after the request is inputted, so the time taken for inputting is not included. For client and
+// bidi streaming RPCs, it starts after all requests are inputted. Zero means no deadline.
+func WithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// deferTimeout removes the timeout set by WithTimeout from ctx for streaming RPCs which are created before inputting.
+// The returned start function starts the timeout, and the returned context is canceled with the cause
+// context.DeadlineExceeded when it expires. The returned stop function must be called to release the timer.
+func deferTimeout(ctx context.Context) (_ context.Context, start, stop func()) {
+	d, _ := ctx.Value(timeoutKey{}).(time.Duration)
+	ctx = context.WithValue(ctx, timeoutKey{}, time.Duration(0))
+	if d <= 0 {
+		return ctx, func() {}, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	var (
+		mu    sync.Mutex
+		timer *time.Timer
+	)
+	start = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer == nil {
+			timer = time.AfterFunc(d, func() { cancel(context.DeadlineExceeded) })
+		}
+	}
+	stop = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		cancel(nil)
+	}
+	return ctx, start, stop
+}
+
+// deadlineStatus returns the status DeadlineExceeded instead of stat if the RPC is canceled by the timeout which is
+// started by deferTimeout.
+func deadlineStatus(ctx context.Context, stat *status.Status) *status.Status {
+	if stat.Code() == codes.Canceled && errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		return status.FromContextError(context.DeadlineExceeded)
+	}
+	return stat
+}
+
+// parseTimeout parses the value of grpc-timeout header.
+func parseTimeout(duration string) (time.Duration, error) {
+	replacer := strings.NewReplacer("n", "ns", "u", "us", "m", "ms", "S", "s", "M", "m", "H", "h")
+	duration = replacer.Replace(duration)
+	timeout, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0, errors.Wrapf(err, "malformed grpc-timeout header")
+	}
+	return timeout, err
+}
+
 // Gets a request with the body containing the payload of its previous method
 // Only RPCs that are repeatable by definition will have their previous requests returned.
 func (m *dependencyManager) getPreviousRPCRequest(method protoreflect.MethodDescriptor, req proto.Message) error {
@@ -450,7 +520,7 @@ func (m *dependencyManager) getPreviousRPCRequest(method protoreflect.MethodDesc
 	if previousReqBytes == nil {
 		return errors.Errorf("no previous request body exists for method: %s, please issue a normal request", id)
 	}
-	err := proto.Unmarshal(previousReqBytes, req) // TODO: Custom resolver.
+	err := proto.UnmarshalOptions{Resolver: pb.NewTypeResolver(m.descSource)}.Unmarshal(previousReqBytes, req)
 	if err != nil {
 		return errors.Wrapf(err, "error while unmarshalling request for method: %s, please run without the --repeat option", id)
 	}
@@ -500,8 +570,24 @@ func (m *dependencyManager) CallRPCInteractively(ctx context.Context, w io.Write
 	})
 }
 
+// CallRPCWithEditor is the same as CallRPC, but each request is inputted by editing a JSON template of the request
+// with editor. For client streaming RPCs, requests are sent until the saved file has no message.
+func CallRPCWithEditor(ctx context.Context, w io.Writer, rpcName, editor string) error {
+	return dm.CallRPCWithEditor(ctx, w, rpcName, editor)
+}
+
+func (m *dependencyManager) CallRPCWithEditor(ctx context.Context, w io.Writer, rpcName, editor string) error {
+	return m.CallRPC(ctx, w, rpcName, false, fill.NewEditorFiller(editor, pb.NewTypeResolver(m.descSource)))
+}
+
+// handleGRPCResponseError converts err to a gRPC status. Context errors are also converted to the corresponding status
+// such that DeadlineExceeded or Canceled because some gRPC implementations (e.g. gRPC-Web) return them as is.
 func handleGRPCResponseError(err error) (*status.Status, error) {
-	stat, ok := status.FromError(errors.Cause(err))
+	cause := errors.Cause(err)
+	if errors.Is(cause, context.DeadlineExceeded) || errors.Is(cause, context.Canceled) {
+		return status.FromContextError(cause), nil
+	}
+	stat, ok := status.FromError(cause)
 	if !ok {
 		return nil, err
 	}
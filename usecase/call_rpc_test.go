@@ -1,10 +1,25 @@
 package usecase
 
 import (
+	"context"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/bufbuild/protocompile"
+	"github.com/ktr0731/evans/format"
+	formatjson "github.com/ktr0731/evans/format/json"
+	"github.com/ktr0731/evans/grpc"
+	pb "github.com/ktr0731/evans/proto"
+	"github.com/pkg/errors"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 func TestGetPreviousRPCRequest(t *testing.T) {
@@ -60,3 +75,115 @@ func getStubMethod(clientStreaming bool) *stubMethod {
 		isStreamingClient: clientStreaming,
 	}
 }
+
+// compileTestService compiles api.Example which has a unary, a client streaming and a bidi streaming RPC.
+func compileTestService(t *testing.T) protoreflect.ServiceDescriptor {
+	t.Helper()
+
+	c := &protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{
+				"test.proto": `syntax = "proto3";
+package api;
+service Example {
+  rpc Unary(Request) returns (Request);
+  rpc ClientStreaming(stream Request) returns (Request);
+  rpc Bidi(stream Request) returns (stream Request);
+}
+message Request {
+  string name = 1;
+}`,
+			}),
+		},
+	}
+	compiled, err := c.Compile(context.TODO(), "test.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compiled[0].Services().Get(0)
+}
+
+// deadlineClient returns the status of the context once the request is sent.
+// Client streams wait for the context to be done.
+type deadlineClient struct {
+	grpc.Client
+}
+
+func (c *deadlineClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (header, trailer metadata.MD, _ error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, nil, errors.New("the context must have the deadline")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, status.FromContextError(err).Err()
+	}
+	return nil, nil, nil
+}
+
+func (c *deadlineClient) NewClientStream(ctx context.Context, streamDesc *gogrpc.StreamDesc, fqrn string) (grpc.ClientStream, error) {
+	return &waitingClientStream{ctx: ctx}, nil
+}
+
+func (c *deadlineClient) Header() grpc.Headers { return grpc.Headers{} }
+
+type waitingClientStream struct {
+	ctx context.Context
+}
+
+func (s *waitingClientStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *waitingClientStream) Trailer() metadata.MD         { return nil }
+func (s *waitingClientStream) Send(req interface{}) error   { return nil }
+
+func (s *waitingClientStream) CloseAndReceive(res interface{}) error {
+	<-s.ctx.Done()
+	return status.FromContextError(s.ctx.Err()).Err()
+}
+
+// slowFiller takes delay to fill each of n requests.
+type slowFiller struct {
+	n     int
+	delay time.Duration
+}
+
+func (f *slowFiller) Fill(*dynamicpb.Message) error {
+	if f.n == 0 {
+		return io.EOF
+	}
+	f.n--
+	time.Sleep(f.delay)
+	return nil
+}
+
+func TestCallRPC_timeout(t *testing.T) {
+	svc := compileTestService(t)
+	const timeout = 50 * time.Millisecond
+
+	cases := map[string]struct {
+		rpcName  string
+		requests int
+		expected codes.Code
+	}{
+		// Inputting takes longer than the timeout, but the deadline starts after that.
+		"unary":            {rpcName: "Unary", requests: 1, expected: codes.OK},
+		"client streaming": {rpcName: "ClientStreaming", requests: 2, expected: codes.DeadlineExceeded},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			m := &dependencyManager{
+				descSource: &pb.DescriptorSourceMock{
+					FindSymbolFunc: func(name string) (protoreflect.Descriptor, error) {
+						return svc.Methods().ByName(protoreflect.Name(strings.TrimPrefix(name, "api.Example."))), nil
+					},
+				},
+				gRPCClient:        &deadlineClient{},
+				responseFormatter: format.NewResponseFormatter(formatjson.NewResponseFormatter(io.Discard, false, nil), true),
+				state:             state{selectedPackage: "api", selectedService: "Example"},
+			}
+			ctx := WithTimeout(context.Background(), timeout)
+			err := m.CallRPC(ctx, io.Discard, c.rpcName, false, &slowFiller{n: c.requests, delay: 2 * timeout})
+			if code := status.Code(err); code != c.expected {
+				t.Errorf("expected %s, but got '%v'", c.expected, err)
+			}
+		})
+	}
+}
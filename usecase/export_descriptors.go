@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoprint"
+	"github.com/ktr0731/evans/grpc/grpcreflection"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// ExportFormatProto writes reconstructed .proto files.
+	ExportFormatProto = "proto"
+	// ExportFormatProtoset writes a FileDescriptorSet.
+	ExportFormatProtoset = "protoset"
+)
+
+// ExportProtosetName is the file name of the FileDescriptorSet written by ExportDescriptors.
+const ExportProtosetName = "descriptors.protoset"
+
+// ExportDescriptors writes files that define all loaded services and their dependencies to dir.
+// format is one of ExportFormatProto or ExportFormatProtoset.
+// ExportDescriptors returns the written file names relative to dir.
+func ExportDescriptors(dir, format string) ([]string, error) {
+	return dm.ExportDescriptors(dir, format)
+}
+func (m *dependencyManager) ExportDescriptors(dir, format string) ([]string, error) {
+	if format != ExportFormatProto && format != ExportFormatProtoset {
+		return nil, errors.Errorf("unknown export format '%s'", format)
+	}
+
+	fds, err := m.collectFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create directory '%s'", dir)
+	}
+
+	if format == ExportFormatProtoset {
+		set := &descriptorpb.FileDescriptorSet{}
+		for _, fd := range fds {
+			set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+		}
+		b, err := proto.Marshal(set)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal FileDescriptorSet")
+		}
+		if err := os.WriteFile(filepath.Join(dir, ExportProtosetName), b, 0644); err != nil {
+			return nil, errors.Wrap(err, "failed to write the protoset file")
+		}
+		return []string{ExportProtosetName}, nil
+	}
+
+	// Standard imports such as well-known types are provided by Evans, so they are not needed to be written.
+	// Other files under google/protobuf/ are written because they are user files.
+	var (
+		targets []protoreflect.FileDescriptor
+		names   []string
+	)
+	for _, fd := range fds {
+		if isStandardImport(fd.Path()) {
+			continue
+		}
+		targets = append(targets, fd)
+		names = append(names, fd.Path())
+	}
+	jfds, err := desc.WrapFiles(targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert file descriptors")
+	}
+	p := &protoprint.Printer{}
+	if err := p.PrintProtosToFileSystem(jfds, dir); err != nil {
+		return nil, errors.Wrap(err, "failed to write proto files")
+	}
+	return names, nil
+}
+
+// standardImports resolves only the files that Evans provides when it compiles proto files.
+var standardImports = protocompile.WithStandardImports(&protocompile.SourceResolver{
+	Accessor: protocompile.SourceAccessorFromMap(nil),
+})
+
+func isStandardImport(path string) bool {
+	_, err := standardImports.FindFileByPath(path)
+	return err == nil
+}
+
+// collectFiles returns files that define the loaded services and their transitive dependencies.
+// Dependencies precede the files that import them.
+func (m *dependencyManager) collectFiles() ([]protoreflect.FileDescriptor, error) {
+	svcs, err := m.listServices()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list services")
+	}
+
+	var (
+		fds     []protoreflect.FileDescriptor
+		visited = make(map[string]bool)
+		visit   func(fd protoreflect.FileDescriptor)
+	)
+	visit = func(fd protoreflect.FileDescriptor) {
+		if visited[fd.Path()] {
+			return
+		}
+		visited[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			imp := imports.Get(i)
+			if imp.IsPlaceholder() {
+				logger.Printf("skip unresolved dependency %s of %s", imp.Path(), fd.Path())
+				continue
+			}
+			visit(imp.FileDescriptor)
+		}
+		fds = append(fds, fd)
+	}
+
+	for _, svc := range svcs {
+		// gRPC reflection services are provided by the server implicitly.
+		if grpcreflection.IsReflectionService(svc) {
+			continue
+		}
+		d, err := m.descSource.FindSymbol(svc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find service '%s'", svc)
+		}
+		visit(d.ParentFile())
+	}
+
+	return fds, nil
+}
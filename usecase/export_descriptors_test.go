@@ -0,0 +1,17 @@
+package usecase
+
+import "testing"
+
+func TestIsStandardImport(t *testing.T) {
+	cases := map[string]bool{
+		"google/protobuf/timestamp.proto":  true,
+		"google/protobuf/descriptor.proto": true,
+		"google/protobuf/user.proto":       false,
+		"api/api.proto":                    false,
+	}
+	for path, expected := range cases {
+		if actual := isStandardImport(path); actual != expected {
+			t.Errorf("%s: expected %t, but got %t", path, expected, actual)
+		}
+	}
+}
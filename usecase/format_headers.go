@@ -3,6 +3,7 @@ package usecase
 import (
 	"sort"
 
+	"github.com/ktr0731/evans/interpolate"
 	"github.com/pkg/errors"
 )
 
@@ -21,7 +22,8 @@ func (m *dependencyManager) FormatHeaders() (string, error) {
 	headers := m.ListHeaders()
 	for k, v := range headers {
 		for _, vv := range v {
-			s.Headers = append(s.Headers, header{k, vv})
+			// Values expanded from environment variables or commands are secrets.
+			s.Headers = append(s.Headers, header{k, interpolate.Mask(vv)})
 		}
 	}
 	sort.Slice(s.Headers, func(i, j int) bool {
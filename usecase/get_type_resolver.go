@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"github.com/ktr0731/evans/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// GetTypeResolver returns a resolver that resolves message and extension types by the injected DescriptorSource.
+// Because the DescriptorSource is referred at the resolution time, GetTypeResolver can be called before Inject.
+func GetTypeResolver() proto.TypeResolver {
+	return &typeResolver{}
+}
+
+type typeResolver struct{}
+
+func (*typeResolver) resolver() proto.TypeResolver {
+	return proto.NewTypeResolver(dm.descSource)
+}
+
+func (r *typeResolver) FindMessageByName(m protoreflect.FullName) (protoreflect.MessageType, error) {
+	return r.resolver().FindMessageByName(m)
+}
+
+func (r *typeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	return r.resolver().FindMessageByURL(url)
+}
+
+func (r *typeResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	return r.resolver().FindExtensionByName(field)
+}
+
+func (r *typeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return r.resolver().FindExtensionByNumber(message, field)
+}
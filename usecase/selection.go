@@ -0,0 +1,26 @@
+package usecase
+
+// SelectedPackage returns the currently selected package. It returns an empty string if no package is selected.
+func SelectedPackage() string {
+	return dm.SelectedPackage()
+}
+func (m *dependencyManager) SelectedPackage() string {
+	return m.state.selectedPackage
+}
+
+// SelectedService returns the currently selected service. It returns an empty string if no service is selected.
+func SelectedService() string {
+	return dm.SelectedService()
+}
+func (m *dependencyManager) SelectedService() string {
+	return m.state.selectedService
+}
+
+// ClearSelection unselects the currently selected package and service.
+func ClearSelection() {
+	dm.ClearSelection()
+}
+func (m *dependencyManager) ClearSelection() {
+	m.state.selectedPackage = ""
+	m.state.selectedService = ""
+}
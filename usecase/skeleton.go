@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"github.com/ktr0731/evans/fill"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Skeleton returns an example request of the passed symbol in format. The symbol is a fully-qualified method name or
+// message name. If it is a method, the example is the request message of the method.
+func Skeleton(symbol, format string) (string, error) {
+	return dm.Skeleton(symbol, format)
+}
+func (m *dependencyManager) Skeleton(symbol, format string) (string, error) {
+	d, err := m.descSource.FindSymbol(symbol)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve symbol '%s'", symbol)
+	}
+
+	var md protoreflect.MessageDescriptor
+	switch d := d.(type) {
+	case protoreflect.MethodDescriptor:
+		md = d.Input()
+	case protoreflect.MessageDescriptor:
+		md = d
+	default:
+		return "", errors.Errorf("'%s' is neither a method nor a message", symbol)
+	}
+	return fill.Skeleton(md, format)
+}